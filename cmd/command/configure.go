@@ -8,6 +8,10 @@ import (
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/pkg/pythonconfig"
+	"github.com/common-fate/pdk/pkg/secretstore"
 	"github.com/common-fate/provider-registry-sdk-go/pkg/providerregistrysdk"
 	"github.com/joho/godotenv"
 	"github.com/urfave/cli/v2"
@@ -16,8 +20,12 @@ import (
 var Configure = cli.Command{
 	Name:  "configure",
 	Usage: "Update or create .env file with all the required configuration fields",
-	Flags: []cli.Flag{},
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "secret-backend", Usage: "write secrets to a backend ('ssm' or 'secretsmanager') and store references to them in the .env file, rather than the secret values. Defaults to secret_backend in provider.toml"},
+	},
 	Action: func(c *cli.Context) error {
+		ctx := c.Context
+
 		var out bytes.Buffer
 		cmd := exec.Command(".venv/bin/provider", "schema")
 		cmd.Stderr = os.Stderr
@@ -40,6 +48,36 @@ var Configure = cli.Command{
 			return nil
 		}
 
+		var writer *secretstore.Writer
+		var backend secretstore.Backend
+
+		// the --secret-backend flag takes precedence over the secret_backend in provider.toml.
+		backendName := c.String("secret-backend")
+		pconfig, err := pythonconfig.LoadFile("provider.toml")
+		if err != nil {
+			if backendName != "" {
+				return err
+			}
+			clio.Debugf("not reading secret_backend from provider.toml: %s", err)
+		}
+		if backendName == "" {
+			backendName = pconfig.SecretBackend
+		}
+
+		if backendName != "" {
+			backend, err = secretstore.Parse(backendName)
+			if err != nil {
+				return err
+			}
+
+			cfg, err := config.LoadDefaultConfig(ctx)
+			if err != nil {
+				return err
+			}
+
+			writer = secretstore.NewFromConfig(cfg, backend)
+		}
+
 		for k, v := range *schema.Config {
 			// prompt the user for each config value
 			var ans string
@@ -49,6 +87,14 @@ var Configure = cli.Command{
 			}
 
 			if v.Secret != nil && *v.Secret {
+				if writer != nil {
+					secretPath := backend.Path(pconfig.Publisher, pconfig.Name, k)
+					ans, err = writer.Put(ctx, secretPath, ans)
+					if err != nil {
+						return err
+					}
+					clio.Infof("wrote secret %s to %s", k, ans)
+				}
 				values["PROVIDER_SECRET_"+strings.ToUpper(k)] = ans
 			} else {
 				values["PROVIDER_CONFIG_"+strings.ToUpper(k)] = ans
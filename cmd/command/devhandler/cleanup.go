@@ -2,13 +2,17 @@ package devhandler
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/cmd/command/handlertarget"
+	"github.com/common-fate/pdk/pkg/handlerstack"
 	"github.com/urfave/cli/v2"
 )
 
@@ -16,45 +20,63 @@ var cleanup = cli.Command{
 	Name:  "cleanup",
 	Usage: "destroy a development Provider handler deployment",
 	Flags: []cli.Flag{
-		&cli.StringFlag{Name: "id", Required: true, Usage: "the handler ID"},
+		&cli.StringFlag{Name: "id", Usage: "the handler ID"},
+		handlertarget.StackFlag,
 	},
 	Action: func(c *cli.Context) error {
 		ctx := c.Context
 
-		handlerID := "cf-handler-" + c.String("id")
-
 		cfg, err := config.LoadDefaultConfig(ctx)
 		if err != nil {
 			return err
 		}
 
+		var functionName, roleName string
+		switch {
+		case c.IsSet("id") && c.IsSet("stack"):
+			return errors.New("only one of --id or --stack can be provided")
+		case c.IsSet("stack"):
+			h, err := handlerstack.Resolve(ctx, cloudformation.NewFromConfig(cfg), c.String("stack"))
+			if err != nil {
+				return err
+			}
+			functionName = h.FunctionName()
+			// the role name is the last part of the ARN, after the role path.
+			roleName = h.LambdaRoleArn[strings.LastIndex(h.LambdaRoleArn, "/")+1:]
+		case c.IsSet("id"):
+			functionName = "cf-handler-" + c.String("id")
+			roleName = functionName
+		default:
+			return errors.New("either --id or --stack must be provided")
+		}
+
 		lambdaclient := lambda.NewFromConfig(cfg)
 		_, err = lambdaclient.DeleteFunction(ctx, &lambda.DeleteFunctionInput{
-			FunctionName: &handlerID,
+			FunctionName: &functionName,
 		})
 		var rnf *types.ResourceNotFoundException
 		if err != nil && !errors.As(err, &rnf) {
 			clio.Errorw("delete lambda error", "error", err.Error())
 		} else {
-			clio.Infof("deleted lambda %s", handlerID)
+			clio.Infof("deleted lambda %s", functionName)
 		}
 
 		iamclient := iam.NewFromConfig(cfg)
 		_, err = iamclient.DetachRolePolicy(ctx, &iam.DetachRolePolicyInput{
 			PolicyArn: aws.String("arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"),
-			RoleName:  &handlerID,
+			RoleName:  &roleName,
 		})
 		if err != nil {
 			clio.Errorw("detach role policy error", "error", err.Error())
 		}
 
 		_, err = iamclient.DeleteRole(ctx, &iam.DeleteRoleInput{
-			RoleName: &handlerID,
+			RoleName: &roleName,
 		})
 		if err != nil {
 			clio.Errorw("delete role error", "error", err.Error())
 		} else {
-			clio.Infof("deleted role %s", handlerID)
+			clio.Infof("deleted role %s", roleName)
 		}
 
 		return nil
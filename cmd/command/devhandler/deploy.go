@@ -3,19 +3,29 @@ package devhandler
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
 	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/common-fate/clio"
 	"github.com/common-fate/cloudform/deployer"
+	"github.com/common-fate/pdk/pkg/cfngen"
+	"github.com/common-fate/pdk/pkg/cfngen/ref"
+	"github.com/common-fate/pdk/pkg/handlerstack"
 	"github.com/common-fate/pdk/pkg/pythonconfig"
+	"github.com/common-fate/pdk/pkg/secretstore"
 	"github.com/common-fate/provider-registry-sdk-go/pkg/bootstrapper"
 	"github.com/common-fate/provider-registry-sdk-go/pkg/configure"
 	"github.com/common-fate/provider-registry-sdk-go/pkg/providerregistrysdk"
@@ -30,6 +40,13 @@ var deploy = cli.Command{
 		&cli.PathFlag{Name: "path", Value: ".", Usage: "the path to the folder containing your provider code e.g ./cf-provider-example"},
 		&cli.StringFlag{Name: "id", Required: true, Usage: "the handler ID"},
 		&cli.BoolFlag{Name: "confirm", Aliases: []string{"y"}, Usage: "Confirm creation of resources"},
+		&cli.BoolFlag{Name: "delete-existing-log-group", Usage: "delete the handler's log group if Lambda created it outside of the stack, so that the stack can manage it. The existing log events are deleted"},
+		&cli.StringFlag{Name: "secret-backend", Usage: "the backend to write secrets to and read them from ('ssm' or 'secretsmanager'). Defaults to the secret_backend in provider.toml"},
+		&cli.StringFlag{Name: "load-schedule", Usage: "run the Provider's resource loaders on an EventBridge schedule, e.g. 'rate(1 hour)'"},
+		&cli.StringFlag{Name: "load-destination-arn", Usage: "the ARN of an SQS queue, SNS topic, EventBridge event bus or Lambda function to send the results of scheduled resource loads to. S3 buckets aren't supported"},
+		&cli.BoolFlag{Name: "tracing", Usage: "enable X-Ray active tracing of the Lambda function"},
+		&cli.StringFlag{Name: "otel-layer-arn", Usage: "the ARN of an AWS Distro for OpenTelemetry Lambda layer for Python, which instruments the Provider"},
+		&cli.StringSliceFlag{Name: "tag", Usage: "set the value of a tag from the [tags] table in provider.toml, e.g. --tag owner=platform. Tags with an empty value in provider.toml must be set"},
 	},
 	Action: func(c *cli.Context) error {
 		providerPath := c.Path("path")
@@ -114,6 +131,37 @@ var deploy = cli.Command{
 			return err
 		}
 
+		backendName := pconfig.SecretBackend
+		if c.IsSet("secret-backend") {
+			backendName = c.String("secret-backend")
+		}
+		secretBackend, err := secretstore.Parse(backendName)
+		if err != nil {
+			return err
+		}
+
+		// write any secret values from the environment to the secret backend,
+		// so that the handler is deployed with a reference to the secret rather than its value.
+		secretWriter := secretstore.NewFromConfig(cfg, secretBackend)
+		var hasSecrets bool
+		for k, v := range configVals.Values {
+			if !v.Secret {
+				continue
+			}
+			hasSecrets = true
+			if v.Ref == "" || secretstore.IsRef(v.Ref) {
+				continue
+			}
+
+			secretPath := secretBackend.Path(pconfig.Publisher, pconfig.Name, k)
+			v.Ref, err = secretWriter.Put(ctx, secretPath, v.Ref)
+			if err != nil {
+				return err
+			}
+			clio.Infof("wrote secret %s to %s", k, v.Ref)
+			configVals.Values[k] = v
+		}
+
 		stsClient := sts.NewFromConfig(cfg)
 		ci, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 		if err != nil {
@@ -142,14 +190,92 @@ var deploy = cli.Command{
 			ParameterValue: aws.String(handlerID),
 		})
 
+		// the SecretBackend parameter is only present in the template if the Provider has secrets.
+		if hasSecrets {
+			parameters = append(parameters, types.Parameter{
+				ParameterKey:   aws.String("SecretBackend"),
+				ParameterValue: aws.String(string(secretBackend)),
+			})
+		}
+
+		if c.Bool("tracing") {
+			parameters = append(parameters, types.Parameter{
+				ParameterKey:   aws.String(ref.TracingEnabled),
+				ParameterValue: aws.String("true"),
+			})
+		}
+		if c.IsSet("otel-layer-arn") {
+			parameters = append(parameters, types.Parameter{
+				ParameterKey:   aws.String(ref.OtelLayerArn),
+				ParameterValue: aws.String(c.String("otel-layer-arn")),
+			})
+		}
+
+		if c.IsSet("load-schedule") {
+			loaders, err := cfngen.ResourceLoaders(cfngen.FromRegistrySchema(schema))
+			if err != nil {
+				return err
+			}
+			if len(loaders) == 0 {
+				return errors.New("--load-schedule was provided, but the Provider doesn't have any resource loaders")
+			}
+			parameters = append(parameters, types.Parameter{
+				ParameterKey:   aws.String(ref.ResourceLoadSchedule),
+				ParameterValue: aws.String(c.String("load-schedule")),
+			})
+			if c.IsSet("load-destination-arn") {
+				parameters = append(parameters, types.Parameter{
+					ParameterKey:   aws.String(ref.ResourceLoadDestinationArn),
+					ParameterValue: aws.String(c.String("load-destination-arn")),
+				})
+			}
+		} else if c.IsSet("load-destination-arn") {
+			return errors.New("--load-destination-arn can only be used with --load-schedule")
+		}
+
+		tagParams, err := cfngen.TagParameterValues(pconfig.Tags, c.StringSlice("tag"))
+		if err != nil {
+			return err
+		}
+		tagKeys := make([]string, 0, len(tagParams))
+		for k := range tagParams {
+			tagKeys = append(tagKeys, k)
+		}
+		sort.Strings(tagKeys)
+		for _, k := range tagKeys {
+			parameters = append(parameters, types.Parameter{
+				ParameterKey:   aws.String(k),
+				ParameterValue: aws.String(tagParams[k]),
+			})
+		}
+
 		paramsJSON, err := json.Marshal(parameters)
 		if err != nil {
 			return err
 		}
 
+		// handlers deployed before the log group was part of the stack already have one,
+		// which would make the deployment fail.
+		logs := cloudwatchlogs.NewFromConfig(cfg)
+		unmanaged, err := handlerstack.UnmanagedLogGroup(ctx, cloudformation.NewFromConfig(cfg), logs, handlerID, handlerID)
+		if err != nil {
+			return err
+		}
+		if unmanaged {
+			logGroup := handlerstack.LogGroupName(handlerID)
+			if !c.Bool("delete-existing-log-group") {
+				return fmt.Errorf("the log group %s already exists outside of the handler stack, so CloudFormation can't create it. Run again with --delete-existing-log-group to delete it and its log events, or delete it with 'aws logs delete-log-group --log-group-name %s'", logGroup, logGroup)
+			}
+			err = handlerstack.DeleteLogGroup(ctx, logs, handlerID)
+			if err != nil {
+				return err
+			}
+			clio.Infof("deleted the existing log group %s", logGroup)
+		}
+
 		clio.Infow("deploying CloudFormation stack", "name", handlerID, "parameters", string(paramsJSON))
 
-		_, err = d.Deploy(ctx, deployer.DeployOpts{
+		res, err := d.Deploy(ctx, deployer.DeployOpts{
 			Template:  string(template),
 			StackName: handlerID,
 			Confirm:   confirm,
@@ -158,6 +284,19 @@ var deploy = cli.Command{
 		if err != nil {
 			return err
 		}
+		if strings.HasSuffix(res.FinalStatus, "ROLLBACK_COMPLETE") || strings.HasSuffix(res.FinalStatus, "FAILED") {
+			return fmt.Errorf("deploying handler stack %s failed with status %s", handlerID, res.FinalStatus)
+		}
+
+		h, err := handlerstack.Resolve(ctx, cloudformation.NewFromConfig(cfg), handlerID)
+		if err != nil {
+			// stacks deployed before the template exported outputs can't be resolved until they're updated.
+			clio.Warnf("could not read the outputs of the handler stack: %s", err)
+			return nil
+		}
+		clio.Successf("deployed handler %s", h.LambdaFunctionArn)
+		clio.Infof("invoke role: %s", h.InvokeRoleArn)
+		clio.Infof("log group: %s", h.LogGroupName)
 
 		return nil
 	},
@@ -0,0 +1,78 @@
+package devhandler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/cmd/command/handlertarget"
+	"github.com/common-fate/pdk/pkg/handlerstack"
+	"github.com/common-fate/pdk/pkg/logtail"
+	"github.com/urfave/cli/v2"
+)
+
+var logs = cli.Command{
+	Name:  "logs",
+	Usage: "show the CloudWatch logs of a development Provider handler",
+	Flags: []cli.Flag{
+		&cli.StringFlag{Name: "id", Usage: "the handler ID"},
+		handlertarget.StackFlag,
+		&cli.BoolFlag{Name: "follow", Aliases: []string{"f"}, Usage: "keep polling for new log events"},
+		&cli.DurationFlag{Name: "since", Value: 10 * time.Minute, Usage: "show log events which are newer than a relative duration, e.g. 30s or 1h"},
+		&cli.StringFlag{Name: "request-id", Usage: "only show the log events of a single Lambda request"},
+		&cli.StringFlag{Name: "invoke-role-arn", Usage: "an IAM role to assume to read the logs, such as the handler's invoke role"},
+	},
+	Action: func(c *cli.Context) error {
+		// stop following the logs on Ctrl+C.
+		ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+		defer stop()
+
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return err
+		}
+
+		var logGroup string
+		switch {
+		case c.IsSet("id") && c.IsSet("stack"):
+			return errors.New("only one of --id or --stack can be provided")
+		case c.IsSet("stack"):
+			// the stack is read with the default credentials, as the invoke role can only read the logs.
+			h, err := handlerstack.Resolve(ctx, cloudformation.NewFromConfig(cfg), c.String("stack"))
+			if err != nil {
+				return err
+			}
+			logGroup = h.LogGroupName
+		case c.IsSet("id"):
+			logGroup = handlerstack.LogGroupName(c.String("id"))
+		default:
+			return errors.New("either --id or --stack must be provided")
+		}
+
+		if roleARN := c.String("invoke-role-arn"); roleARN != "" {
+			provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN)
+			cfg.Credentials = aws.NewCredentialsCache(provider)
+		}
+
+		clio.Infof("showing logs from %s", logGroup)
+
+		tailer := logtail.NewFromConfig(cfg, logGroup)
+		opts := logtail.Options{
+			Since:     time.Now().Add(-c.Duration("since")),
+			Follow:    c.Bool("follow"),
+			RequestID: c.String("request-id"),
+		}
+		return tailer.Tail(ctx, opts, func(e logtail.Event) error {
+			_, err := fmt.Println(logtail.Format(e))
+			return err
+		})
+	},
+}
@@ -0,0 +1,116 @@
+// Package handlertarget selects a deployed handler from the command line flags,
+// so that commands which call deployed handlers are used in the same way.
+// It also provides the executor for the local Provider, which is used when no deployed handler is selected.
+package handlertarget
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/common-fate/clio"
+	entrypoint "github.com/common-fate/pdk/cmd/run"
+	"github.com/common-fate/pdk/pkg/handlerstack"
+	"github.com/common-fate/pdk/pkg/lambdainvoke"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+	"github.com/joho/godotenv"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	HandlerIDFlag     = &cli.StringFlag{Name: "handler-id", Usage: "the name of the handler's Lambda function"}
+	StackFlag         = &cli.StringFlag{Name: "stack", Usage: "the name of the handler's CloudFormation stack, used to look up the handler from the stack outputs"}
+	InvokeRoleARNFlag = &cli.StringFlag{Name: "invoke-role-arn", Usage: "an IAM role to assume to invoke the handler"}
+)
+
+// Flags are the flags used to select a deployed handler.
+var Flags = []cli.Flag{HandlerIDFlag, StackFlag, InvokeRoleARNFlag}
+
+// IsSet returns true if a deployed handler was selected with --handler-id or --stack.
+func IsSet(c *cli.Context) bool {
+	return c.String("handler-id") != "" || c.String("stack") != ""
+}
+
+// NewExecutor returns an executor which invokes the Lambda function given by
+// --handler-id or --stack, optionally assuming the role given by --invoke-role-arn.
+func NewExecutor(c *cli.Context) (lambdainvoke.Executor, error) {
+	functionName, err := ResolveFunction(c)
+	if err != nil {
+		return lambdainvoke.Executor{}, err
+	}
+
+	cfg, err := config.LoadDefaultConfig(c.Context)
+	if err != nil {
+		return lambdainvoke.Executor{}, err
+	}
+	return lambdainvoke.New(cfg, functionName, c.String("invoke-role-arn")), nil
+}
+
+// CommandFlags returns the flags which were used to select the handler, so that they can be
+// included in suggested commands.
+func CommandFlags(c *cli.Context) string {
+	var flags string
+	if c.IsSet("stack") {
+		flags = "--stack " + c.String("stack")
+	} else {
+		flags = "--handler-id " + c.String("handler-id")
+	}
+	if c.IsSet("invoke-role-arn") {
+		flags += " --invoke-role-arn " + c.String("invoke-role-arn")
+	}
+	return flags
+}
+
+// ResolveFunction returns the Lambda function to invoke, from either
+// --handler-id or the outputs of the handler stack given with --stack.
+func ResolveFunction(c *cli.Context) (string, error) {
+	handlerID := c.String("handler-id")
+	stackName := c.String("stack")
+
+	switch {
+	case handlerID != "" && stackName != "":
+		return "", errors.New("only one of --handler-id or --stack can be provided")
+	case handlerID != "":
+		return handlerID, nil
+	case stackName == "":
+		return "", errors.New("either --handler-id or --stack must be provided")
+	}
+
+	cfg, err := config.LoadDefaultConfig(c.Context)
+	if err != nil {
+		return "", err
+	}
+	h, err := handlerstack.Resolve(c.Context, cloudformation.NewFromConfig(cfg), stackName)
+	if err != nil {
+		return "", err
+	}
+	clio.Debugf("resolved handler %s from stack %s", h.LambdaFunctionArn, stackName)
+	return h.LambdaFunctionArn, nil
+}
+
+// LocalExecutor runs the local Provider with run.RunEntrypointContext. Unlike handlerclient.Local,
+// the Provider config is passed explicitly so that it doesn't depend on the environment, and
+// the Provider is stopped when the context is cancelled.
+type LocalExecutor struct {
+	Env map[string]string
+}
+
+func (e LocalExecutor) Execute(ctx context.Context, request msg.Request) (*msg.Result, error) {
+	return entrypoint.RunEntrypointContext(ctx, request, e.Env)
+}
+
+// NewLocalExecutor returns an executor for the local Provider, using the Provider config in the .env file.
+// A warning is logged if there is no .env file, as the Provider will run without any config.
+func NewLocalExecutor() (LocalExecutor, error) {
+	env, err := godotenv.Read()
+	if errors.Is(err, os.ErrNotExist) {
+		clio.Warn("no .env file was found, so the Provider is running without config. Run 'pdk configure' to create it")
+		return LocalExecutor{}, nil
+	}
+	if err != nil {
+		return LocalExecutor{}, err
+	}
+	return LocalExecutor{Env: env}, nil
+}
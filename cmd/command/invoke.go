@@ -1,176 +1,201 @@
 package command
 
 import (
-	"context"
-	"encoding/base64"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
-	"github.com/aws/aws-sdk-go-v2/service/lambda"
-	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/cmd/command/handlertarget"
+	"github.com/common-fate/pdk/cmd/command/run"
+	"github.com/common-fate/pdk/pkg/lambdainvoke"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
 	"github.com/urfave/cli/v2"
 )
 
-type Payload struct {
-	Type string `json:"type"`
-	Data Data   `json:"data"`
-}
-
-type Data struct {
-	Subject string `json:"subject"`
-	Args    any    `json:"args"`
-}
-
+// Invoke runs requests against a deployed handler. The requests are built from
+// the same flags as 'pdk run', so local and deployed handlers are called in the same way.
 var Invoke = cli.Command{
-	Name: "invoke",
+	Name:  "invoke",
+	Usage: "invoke a deployed Provider handler",
 	Subcommands: []*cli.Command{
 		&invokeGrant,
 		&invokeRevoke,
-		&invokeSchema,
+		&invokeDescribe,
+		&invokeLoad,
+		&invokeReplay,
 	},
 }
 
 var invokeGrant = cli.Command{
-	Name: "grant",
-	Flags: []cli.Flag{
-		&cli.StringFlag{Name: "subject", Required: true},
-		&cli.StringFlag{Name: "args", Required: true},
-		&cli.StringFlag{Name: "handler-id", Required: true},
-		&cli.StringFlag{Name: "invoke-role-arn"},
-	},
+	Name:  "grant",
+	Flags: run.WithFlags(run.GrantFlags, handlertarget.HandlerIDFlag, handlertarget.StackFlag, handlertarget.InvokeRoleARNFlag, outputFlag),
 	Action: func(c *cli.Context) error {
-
-		argsStr := c.String("args")
-		var args any
-
-		err := json.Unmarshal([]byte(argsStr), &args)
+		request, err := run.GrantRequest(c)
 		if err != nil {
 			return err
 		}
-		fmt.Println(args)
-		payload := Payload{
-			Type: "grant",
-			Data: Data{
-				Subject: c.String("subject"),
-				Args:    args,
-			},
-		}
 
-		handlerID := c.String("handler-id")
-		roleARN := c.String("invoke-role-arn")
+		res, err := invokeHandler(c, request)
+		if err != nil {
+			return err
+		}
 
-		return invokeLambda(c.Context, invokeLambdaOpts{
-			Payload:      payload,
-			FunctionName: handlerID,
-			RoleARN:      roleARN,
-		})
+		if c.String("output") == outputPretty {
+			var gr msg.GrantResponse
+			if len(bytes.TrimSpace(res.Response)) > 0 {
+				err = json.Unmarshal(res.Response, &gr)
+				if err != nil {
+					return err
+				}
+			}
+			clio.Infof("revoke access by running:\n%s", run.RevokeCommand("pdk invoke revoke "+handlertarget.CommandFlags(c), request, gr.State))
+		}
+		return nil
 	},
 }
 
 var invokeRevoke = cli.Command{
-	Name: "revoke",
-	Flags: []cli.Flag{
-		&cli.StringFlag{Name: "subject", Required: true},
-		&cli.StringFlag{Name: "args", Required: true},
-		&cli.StringFlag{Name: "handler-id", Required: true},
-		&cli.StringFlag{Name: "invoke-role-arn"},
-	},
+	Name:  "revoke",
+	Flags: run.WithFlags(run.RevokeFlags, handlertarget.HandlerIDFlag, handlertarget.StackFlag, handlertarget.InvokeRoleARNFlag, outputFlag),
 	Action: func(c *cli.Context) error {
-
-		argsStr := c.String("args")
-		var args any
-
-		err := json.Unmarshal([]byte(argsStr), &args)
+		request, err := run.RevokeRequest(c)
 		if err != nil {
 			return err
 		}
 
-		handlerID := c.String("handler-id")
-		roleARN := c.String("invoke-role-arn")
-
-		payload := Payload{
-			Type: "revoke",
-			Data: Data{
-				Subject: c.String("subject"),
-				Args:    args,
-			},
-		}
+		_, err = invokeHandler(c, request)
+		return err
+	},
+}
 
-		return invokeLambda(c.Context, invokeLambdaOpts{
-			Payload:      payload,
-			FunctionName: handlerID,
-			RoleARN:      roleARN,
-		})
+var invokeDescribe = cli.Command{
+	Name:    "describe",
+	Aliases: []string{"schema"},
+	Flags:   []cli.Flag{handlertarget.HandlerIDFlag, handlertarget.StackFlag, handlertarget.InvokeRoleARNFlag, outputFlag},
+	Action: func(c *cli.Context) error {
+		_, err := invokeHandler(c, msg.Describe{})
+		return err
 	},
 }
-var invokeSchema = cli.Command{
-	Name: "schema",
+
+var invokeLoad = cli.Command{
+	Name:  "load",
+	Usage: "run a single resource loader task",
 	Flags: []cli.Flag{
-		&cli.StringFlag{Name: "handler-id", Required: true},
-		&cli.StringFlag{Name: "invoke-role-arn"},
+		&cli.StringFlag{Name: "task", Required: true, Usage: "the name of the resource loader"},
+		&cli.StringFlag{Name: "ctx", Value: "{}", Usage: "the context of the task, as a JSON object"},
+		handlertarget.HandlerIDFlag,
+		handlertarget.StackFlag,
+		handlertarget.InvokeRoleARNFlag,
+		outputFlag,
 	},
 	Action: func(c *cli.Context) error {
-
-		payload := Payload{
-			Type: "schema",
+		request := msg.LoadResources{Task: c.String("task")}
+		err := json.Unmarshal([]byte(c.String("ctx")), &request.Ctx)
+		if err != nil {
+			return fmt.Errorf("parsing --ctx: %w", err)
 		}
 
-		handlerID := c.String("handler-id")
-		roleARN := c.String("invoke-role-arn")
+		_, err = invokeHandler(c, request)
+		return err
+	},
+}
 
-		return invokeLambda(c.Context, invokeLambdaOpts{
-			Payload:      payload,
-			FunctionName: handlerID,
-			RoleARN:      roleARN,
-		})
+var invokeReplay = cli.Command{
+	Name:      "replay",
+	Usage:     "run the requests in a JSON lines file against a deployed handler",
+	ArgsUsage: run.ReplayArgsUsage,
+	Flags:     run.WithFlags(run.ReplayFlags, handlertarget.Flags...),
+	Action: func(c *cli.Context) error {
+		executor, err := handlertarget.NewExecutor(c)
+		if err != nil {
+			return err
+		}
+		return run.Replay(c, executor)
 	},
 }
 
-type invokeLambdaOpts struct {
-	Payload      Payload
-	FunctionName string
-	RoleARN      string
+// The output formats of 'pdk invoke'.
+const (
+	outputPretty = "pretty"
+	outputJSON   = "json"
+)
+
+var outputFlag = &cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: outputPretty, Usage: "the output format, 'pretty' or 'json'"}
+
+// invocationOutput is printed with '--output json', so that invocations can be scripted.
+type invocationOutput struct {
+	Type     msg.RequestType             `json:"type"`
+	OK       bool                        `json:"ok"`
+	Response json.RawMessage             `json:"response,omitempty"`
+	Error    *lambdainvoke.FunctionError `json:"error,omitempty"`
+	Logs     string                      `json:"logs,omitempty"`
 }
 
-func invokeLambda(ctx context.Context, opts invokeLambdaOpts) error {
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		return err
+// invokeHandler invokes the handler and prints the outcome in the format given by --output.
+// An error is returned if the handler fails, so that the command exits with a non-zero status.
+func invokeHandler(c *cli.Context, request msg.Request) (*msg.Result, error) {
+	output := c.String("output")
+	if output != outputPretty && output != outputJSON {
+		return nil, fmt.Errorf("invalid output %q, must be %q or %q", output, outputPretty, outputJSON)
 	}
 
-	payloadbytes, err := json.Marshal(opts.Payload)
+	executor, err := handlertarget.NewExecutor(c)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if opts.RoleARN != "" {
-		stsClient := sts.NewFromConfig(cfg)
-		provider := stscreds.NewAssumeRoleProvider(stsClient, opts.RoleARN)
-		cfg.Credentials = aws.NewCredentialsCache(provider)
+	if output == outputPretty {
+		clio.Infow("invoking handler", "function", executor.FunctionName, "request", request)
 	}
 
-	lambdaclient := lambda.NewFromConfig(cfg)
-	out, err := lambdaclient.Invoke(ctx, &lambda.InvokeInput{
-		FunctionName: &opts.FunctionName,
-		Payload:      payloadbytes,
-		LogType:      types.LogTypeTail,
-	})
+	inv, err := executor.Invoke(c.Context, request)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	clio.Infof(string(out.Payload))
-
-	logs, err := base64.StdEncoding.DecodeString(*out.LogResult)
-	if err != nil {
-		return err
+	if output == outputJSON {
+		out := invocationOutput{
+			Type:  request.Type(),
+			OK:    inv.FunctionError == nil,
+			Error: inv.FunctionError,
+			Logs:  inv.Logs,
+		}
+		if inv.Result != nil {
+			out.Response = inv.Result.Response
+		}
+		outBytes, err := json.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println(string(outBytes))
+	} else {
+		if inv.Logs != "" {
+			clio.Infof("function logs:\n%s", inv.Logs)
+		}
+		if inv.FunctionError != nil {
+			// the traceback is shown separately from the logs, as it's usually what needs fixing.
+			if tb := inv.FunctionError.Traceback(); tb != "" {
+				fmt.Fprintln(os.Stderr, tb)
+			}
+		} else {
+			clio.Successf("%s succeeded", request.Type())
+			// handlers such as revoke may not return a response body.
+			response := bytes.TrimSpace(inv.Result.Response)
+			if len(response) > 0 && !bytes.Equal(response, []byte("null")) {
+				var pretty bytes.Buffer
+				if json.Indent(&pretty, response, "", "  ") == nil {
+					response = pretty.Bytes()
+				}
+				fmt.Println(string(response))
+			}
+		}
 	}
 
-	clio.Infof(string(logs))
-	return nil
+	if inv.FunctionError != nil {
+		return nil, inv.FunctionError
+	}
+	return inv.Result, nil
 }
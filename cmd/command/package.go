@@ -14,10 +14,12 @@ import (
 	"strings"
 
 	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/internal/build"
+	"github.com/common-fate/pdk/pkg/accessrole"
 	"github.com/common-fate/pdk/pkg/cfngen"
-	"github.com/common-fate/pdk/pkg/iamp"
+	"github.com/common-fate/pdk/pkg/iamlint"
 	"github.com/common-fate/pdk/pkg/pythonconfig"
-	"github.com/common-fate/provider-registry-sdk-go/pkg/providerregistrysdk"
+	"github.com/common-fate/pdk/pkg/tfgen"
 	"github.com/pkg/errors"
 	ignore "github.com/sabhiram/go-gitignore"
 	"github.com/urfave/cli/v2"
@@ -56,6 +58,32 @@ func parseLocalDependency(input string) (localDependency, error) {
 
 type PackageFlagOpts struct {
 	LocalDependency []string
+	// Formats are additional deployment template formats
+	// to generate alongside the CloudFormation templates.
+	Formats []string
+	// LintSARIFPath is an optional path to write IAM policy lint findings to in SARIF format.
+	LintSARIFPath string
+}
+
+// Deployment template formats which can be generated by 'pdk package'.
+const (
+	FormatCloudFormation = "cloudformation"
+	FormatTerraform      = "terraform"
+)
+
+// hasFormat returns true if the format has been requested.
+// CloudFormation templates are always generated, as they are required by the registry.
+func (o PackageFlagOpts) hasFormat(format string) (bool, error) {
+	var found bool
+	for _, f := range o.Formats {
+		if f != FormatCloudFormation && f != FormatTerraform {
+			return false, fmt.Errorf("invalid format %s: must be one of %s, %s", f, FormatCloudFormation, FormatTerraform)
+		}
+		if f == format {
+			found = true
+		}
+	}
+	return found, nil
 }
 
 func PackageAndZip(ctx context.Context, providerPath string, flagOpts PackageFlagOpts) error {
@@ -73,12 +101,23 @@ func PackageAndZip(ctx context.Context, providerPath string, flagOpts PackageFla
 
 	fpath := filepath.Join(dist, "handler.zip")
 
+	terraform, err := flagOpts.hasFormat(FormatTerraform)
+	if err != nil {
+		return err
+	}
+
 	configFile := filepath.Join(providerPath, "provider.toml")
 	cfg, err := pythonconfig.LoadFile(configFile)
 	if err != nil {
 		return err
 	}
 
+	// check the lint config before building, so that mistakes are found quickly.
+	lintCfg, err := lintConfig(cfg.Lint)
+	if err != nil {
+		return fmt.Errorf("invalid [lint] config in %s: %w", configFile, err)
+	}
+
 	cmd := exec.Command(".venv/bin/provider", "schema")
 
 	var outb bytes.Buffer
@@ -90,6 +129,26 @@ func PackageAndZip(ctx context.Context, providerPath string, flagOpts PackageFla
 		return err
 	}
 
+	// the schema is unmarshalled again below to add its $id
+	var providerSchema cfngen.Schema
+	err = json.Unmarshal(outb.Bytes(), &providerSchema)
+	if err != nil {
+		return err
+	}
+
+	// create the CloudFormation template for the Provider
+	cloudformationTemplate, err := cfngen.GenerateFromSchema(cfg, providerSchema)
+	if err != nil {
+		return err
+	}
+
+	// lint the role definitions and the template before anything is written to dist,
+	// so that a failed lint doesn't leave artifacts behind which could be published.
+	err = lintPolicies(providerPath, lintCfg, cloudformationTemplate, flagOpts.LintSARIFPath)
+	if err != nil {
+		return err
+	}
+
 	provider := Provider{
 		Publisher: cfg.Publisher,
 		Name:      cfg.Name,
@@ -157,20 +216,7 @@ func PackageAndZip(ctx context.Context, providerPath string, flagOpts PackageFla
 	clio.Successf("zipped provider")
 
 	// generate CloudFormation templates for any roles in the `roles` directory
-	err = generateAccessRoleTemplates(providerPath, cfg)
-	if err != nil {
-		return err
-	}
-
-	// unmarshalling again here is a little messy
-	var providerSchema providerregistrysdk.Schema
-	err = json.Unmarshal(outb.Bytes(), &providerSchema)
-	if err != nil {
-		return err
-	}
-
-	// create the CloudFormation template for the Provider
-	cloudformationTemplate, err := cfngen.Generate(cfg, providerSchema)
+	err = generateAccessRoleTemplates(providerPath, cfg, terraform)
 	if err != nil {
 		return err
 	}
@@ -183,6 +229,26 @@ func PackageAndZip(ctx context.Context, providerPath string, flagOpts PackageFla
 	}
 	clio.Successf("generated cloudformation template: %s", cfnPath)
 
+	if terraform {
+		module, err := tfgen.Generate(cfg, providerSchema)
+		if err != nil {
+			return err
+		}
+
+		tfDir := filepath.Join(dist, "terraform")
+		err = os.MkdirAll(tfDir, 0755)
+		if err != nil {
+			return err
+		}
+
+		tfPath := filepath.Join(tfDir, "main.tf.json")
+		err = os.WriteFile(tfPath, module, 0644)
+		if err != nil {
+			return err
+		}
+		clio.Successf("generated terraform module: %s", tfPath)
+	}
+
 	clio.Successf("packaged %s to %s", provider, fpath)
 
 	return nil
@@ -193,6 +259,8 @@ var Package = cli.Command{
 	Flags: []cli.Flag{
 		&cli.PathFlag{Name: "path", Value: ".", Usage: "The path to the folder containing your provider code e.g ./cf-provider-example"},
 		&cli.StringSliceFlag{Name: "local-dependency", Usage: "(For development use) Add a local python package to the zip archive, e.g. provider=../provider/provider"},
+		&cli.StringSliceFlag{Name: "format", Usage: "Additional deployment template formats to generate, e.g. 'terraform'. CloudFormation templates are always generated"},
+		&cli.PathFlag{Name: "lint-sarif", Usage: "Write IAM policy lint findings to a SARIF file, for use with code scanning tools"},
 	},
 	Action: func(c *cli.Context) error {
 		ctx := c.Context
@@ -202,6 +270,8 @@ var Package = cli.Command{
 
 		err := PackageAndZip(ctx, providerPath, PackageFlagOpts{
 			LocalDependency: localDependency,
+			Formats:         c.StringSlice("format"),
+			LintSARIFPath:   c.Path("lint-sarif"),
 		})
 		if err != nil {
 			return err
@@ -211,7 +281,7 @@ var Package = cli.Command{
 	},
 }
 
-func generateAccessRoleTemplates(dir string, pconfig pythonconfig.Config) error {
+func generateAccessRoleTemplates(dir string, pconfig pythonconfig.Config, terraform bool) error {
 	roleDir := path.Join(dir, "roles")
 	_, err := os.Stat(roleDir)
 	if os.IsNotExist(err) {
@@ -231,32 +301,174 @@ func generateAccessRoleTemplates(dir string, pconfig pythonconfig.Config) error
 	}
 
 	for _, f := range files {
-		roleData, err := os.ReadFile(path.Join(roleDir, f.Name()))
+		if f.IsDir() || !isRoleDefinition(f.Name()) {
+			continue
+		}
+
+		name, role, err := accessrole.LoadFile(path.Join(roleDir, f.Name()))
+		if err != nil {
+			return err
+		}
+
+		template, err := cfngen.GenerateAccessRole(pconfig, name, role)
+		if err != nil {
+			return err
+		}
+		outputPath := path.Join(outdir, name+".json")
+		err = os.WriteFile(outputPath, template, 0644)
 		if err != nil {
 			return err
 		}
 
-		var policy iamp.Policy
+		clio.Infof("generated Access Role CloudFormation template: %s", outputPath)
 
-		err = json.Unmarshal(roleData, &policy)
+		stackSet, err := cfngen.GenerateAccessRoleStackSet(pconfig, name, template)
 		if err != nil {
 			return err
 		}
 
-		name := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+		stackSetDir := path.Join(dir, "dist", "stacksets")
+		err = os.MkdirAll(stackSetDir, 0755)
+		if err != nil {
+			return err
+		}
 
-		template, err := cfngen.GenerateAccessRole(pconfig, name, policy)
+		stackSetPath := path.Join(stackSetDir, name+".json")
+		err = os.WriteFile(stackSetPath, stackSet, 0644)
 		if err != nil {
 			return err
 		}
-		outputPath := path.Join(outdir, f.Name())
-		err = os.WriteFile(outputPath, template, 0644)
+
+		clio.Infof("generated Access Role StackSet template: %s", stackSetPath)
+
+		if terraform {
+			module, err := tfgen.GenerateAccessRole(pconfig, name, role)
+			if err != nil {
+				return err
+			}
+
+			tfDir := path.Join(dir, "dist", "terraform", "roles", name)
+			err = os.MkdirAll(tfDir, 0755)
+			if err != nil {
+				return err
+			}
+
+			tfPath := path.Join(tfDir, "main.tf.json")
+			err = os.WriteFile(tfPath, module, 0644)
+			if err != nil {
+				return err
+			}
+
+			clio.Infof("generated Access Role Terraform module: %s", tfPath)
+		}
+	}
+	return nil
+}
+
+// isRoleDefinition returns true if the file has a supported role definition extension.
+func isRoleDefinition(filename string) bool {
+	ext := filepath.Ext(filename)
+	for _, e := range accessrole.Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// templateLintLocation is the location of the handler template in lint findings.
+const templateLintLocation = "dist/cloudformation.json"
+
+// lintConfig converts the [lint] table of provider.toml to the linter config and validates it.
+// Findings in the generated template which Provider authors can't fix are always suppressed.
+func lintConfig(c pythonconfig.LintConfig) (iamlint.Config, error) {
+	cfg := iamlint.Config{
+		FailOn:   c.FailOn,
+		Disable:  c.Disable,
+		Severity: c.Severity,
+	}
+	for _, s := range c.Suppress {
+		cfg.Suppress = append(cfg.Suppress, iamlint.Suppression{
+			Rule:     s.Rule,
+			Location: s.Location,
+			Sid:      s.Sid,
+			Reason:   s.Reason,
+		})
+	}
+	cfg.Suppress = append(cfg.Suppress, iamlint.TemplateSuppressions(templateLintLocation)...)
+	return cfg, cfg.Validate()
+}
+
+// lintPolicies lints the policies in the Provider's access roles and the generated
+// CloudFormation template, returning an error if any findings are at or above the
+// fail_on severity in the [lint] config.
+func lintPolicies(dir string, lintCfg iamlint.Config, template []byte, sarifPath string) error {
+	var policies []iamlint.Policy
+
+	roleDir := path.Join(dir, "roles")
+	files, err := os.ReadDir(roleDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() || !isRoleDefinition(f.Name()) {
+			continue
+		}
+		rolePath := path.Join("roles", f.Name())
+		_, role, err := accessrole.LoadFile(path.Join(dir, rolePath))
 		if err != nil {
 			return err
 		}
+		for _, p := range role.Policies {
+			policies = append(policies, iamlint.Policy{
+				Location: rolePath + "#" + p.PolicyName,
+				File:     rolePath,
+				Policy:   p.PolicyDocument,
+			})
+		}
+	}
 
-		clio.Infof("generated Access Role CloudFormation template: %s", outputPath)
+	templatePolicies, err := iamlint.TemplatePolicies(templateLintLocation, template)
+	if err != nil {
+		return err
+	}
+	policies = append(policies, templatePolicies...)
+
+	res, err := iamlint.Lint(lintCfg, policies...)
+	if err != nil {
+		return err
 	}
+
+	for _, f := range res.Findings {
+		switch {
+		case f.Suppressed:
+			clio.Debugf("suppressed %s", f)
+		case f.Severity == iamlint.SeverityError:
+			clio.Error(f.String())
+		case f.Severity == iamlint.SeverityWarning:
+			clio.Warn(f.String())
+		default:
+			clio.Info(f.String())
+		}
+	}
+
+	if sarifPath != "" {
+		sarif, err := res.SARIF(build.Version)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(sarifPath, sarif, 0644)
+		if err != nil {
+			return err
+		}
+		clio.Infof("wrote IAM policy lint results to %s", sarifPath)
+	}
+
+	if res.Failed() {
+		return errors.New("IAM policy linting failed: fix the findings above, or add suppressions to the [lint] table in provider.toml")
+	}
+
 	return nil
 }
 
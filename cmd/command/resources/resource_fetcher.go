@@ -6,9 +6,8 @@ import (
 	"encoding/json"
 
 	"github.com/common-fate/clio"
-	"github.com/common-fate/pdk/cmd/run"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/handlerclient"
 	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
-	"github.com/joho/godotenv"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -16,6 +15,8 @@ import (
 // provider schema's "loadResources" object.
 type ResourceFetcher struct {
 	eg *errgroup.Group
+	// executor runs the loaders, either locally or in a deployed handler.
+	executor handlerclient.Executor
 }
 
 // LoadResources invokes the deployment
@@ -24,7 +25,7 @@ func (rf *ResourceFetcher) LoadResources(ctx context.Context, tasks []string) er
 		// copy the loop variable
 		tc := task
 
-		err := runTasksRecursive(tc, map[string]any{})
+		err := runTasksRecursive(ctx, rf.executor, tc, map[string]any{})
 		if err != nil {
 			return err
 		}
@@ -32,9 +33,9 @@ func (rf *ResourceFetcher) LoadResources(ctx context.Context, tasks []string) er
 	return nil
 }
 
-func runTasksRecursive(name string, ctx map[string]any) error {
+func runTasksRecursive(goctx context.Context, executor handlerclient.Executor, name string, ctx map[string]any) error {
 	clio.Debugw("running task", "name", name, "ctx", ctx)
-	res, err := runTask(name, ctx)
+	res, err := runTask(goctx, executor, name, ctx)
 	if err != nil {
 		return err
 	}
@@ -46,7 +47,7 @@ func runTasksRecursive(name string, ctx map[string]any) error {
 	}
 
 	for _, subtask := range res.Tasks {
-		err = runTasksRecursive(subtask.Task, subtask.Ctx)
+		err = runTasksRecursive(goctx, executor, subtask.Task, subtask.Ctx)
 		if err != nil {
 			return err
 		}
@@ -57,12 +58,8 @@ func runTasksRecursive(name string, ctx map[string]any) error {
 // runTask calls a resource loading function in the provider.
 // Note that the provided ctx variable is NOT a context.Context Go context, but is
 // rather a dict of arguments to be provided to the loader function in Python.
-func runTask(name string, ctx map[string]any) (*msg.LoadResponse, error) {
-	env, err := godotenv.Read()
-	if err != nil {
-		return nil, err
-	}
-	out, err := run.RunEntrypoint(msg.LoadResources{Task: name, Ctx: ctx}, env)
+func runTask(goctx context.Context, executor handlerclient.Executor, name string, ctx map[string]any) (*msg.LoadResponse, error) {
+	out, err := executor.Execute(goctx, msg.LoadResources{Task: name, Ctx: ctx})
 	if err != nil {
 		return nil, err
 	}
@@ -4,10 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 
-	"github.com/common-fate/pdk/cmd/run"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/cmd/command/handlertarget"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/handlerclient"
 	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
 	"github.com/common-fate/provider-registry-sdk-go/pkg/providerregistrysdk"
-	"github.com/joho/godotenv"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/sync/errgroup"
 )
@@ -32,14 +33,17 @@ var runTaskCommand = cli.Command{
 		&cli.StringFlag{Name: "ctx", Value: "{}"},
 	},
 	Action: func(c *cli.Context) error {
-		env, _ := godotenv.Read()
+		executor, err := handlertarget.NewLocalExecutor()
+		if err != nil {
+			return err
+		}
 		var contx map[string]any
-		err := json.Unmarshal([]byte(c.String("ctx")), &contx)
+		err = json.Unmarshal([]byte(c.String("ctx")), &contx)
 		if err != nil {
 			return err
 		}
 		task := c.String("task")
-		out, err := run.RunEntrypoint(msg.LoadResources{Task: task, Ctx: contx}, env)
+		out, err := executor.Execute(c.Context, msg.LoadResources{Task: task, Ctx: contx})
 		if err != nil {
 			return err
 		}
@@ -49,11 +53,16 @@ var runTaskCommand = cli.Command{
 }
 
 var loadCommand = cli.Command{
-	Name: "load",
+	Name:  "load",
+	Usage: "run all of the resource loaders, either locally or in a deployed handler with --handler-id or --stack",
+	Flags: handlertarget.Flags,
 	Action: func(c *cli.Context) error {
-		env, _ := godotenv.Read()
+		executor, err := loadExecutor(c)
+		if err != nil {
+			return err
+		}
 
-		out, err := run.RunEntrypoint(msg.Describe{}, env)
+		out, err := executor.Execute(c.Context, msg.Describe{})
 		if err != nil {
 			return err
 		}
@@ -64,7 +73,7 @@ var loadCommand = cli.Command{
 			return err
 		}
 		//then call the local version of fetch resources
-		rf := ResourceFetcher{eg: &errgroup.Group{}}
+		rf := ResourceFetcher{eg: &errgroup.Group{}, executor: executor}
 
 		var tasks []string
 		for key := range describe.Schema.Resources.Loaders {
@@ -74,3 +83,18 @@ var loadCommand = cli.Command{
 		return rf.LoadResources(c.Context, tasks)
 	},
 }
+
+// loadExecutor returns the executor which runs the resource loaders. The loaders run
+// locally with the config in the .env file, unless a deployed handler is selected.
+func loadExecutor(c *cli.Context) (handlerclient.Executor, error) {
+	if !handlertarget.IsSet(c) {
+		return handlertarget.NewLocalExecutor()
+	}
+
+	executor, err := handlertarget.NewExecutor(c)
+	if err != nil {
+		return nil, err
+	}
+	clio.Infof("loading resources from %s", executor.FunctionName)
+	return executor, nil
+}
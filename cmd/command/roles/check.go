@@ -0,0 +1,149 @@
+package roles
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/pkg/accessrole"
+	"github.com/common-fate/pdk/pkg/iamp"
+	"github.com/urfave/cli/v2"
+)
+
+var checkFlags = []cli.Flag{
+	&cli.StringSliceFlag{Name: "action", Usage: "the action to check, e.g. s3:GetObject. Can be specified multiple times"},
+	&cli.StringSliceFlag{Name: "resource", Usage: "the ARN of the resource to check. Can be specified multiple times. Defaults to '*'"},
+	&cli.StringSliceFlag{Name: "context", Usage: "a condition key in the request context, e.g. aws:PrincipalTag/team=platform. Can be specified multiple times, including for the same key"},
+	&cli.StringFlag{Name: "principal", Usage: "the ARN of the principal making the request, used by statements with a Principal"},
+	&cli.StringFlag{Name: "expect", Value: "allow", Usage: "'allow' or 'deny'. The command exits with an error if any request has a different result"},
+}
+
+var check = cli.Command{
+	Name:      "check",
+	Usage:     "Check whether an access role allows an action, without calling AWS",
+	ArgsUsage: "<role file>",
+	Description: "Evaluates the inline policies of an access role definition against each combination of --action and --resource, " +
+		"e.g. 'pdk roles check roles/admin.json --action s3:GetObject --resource arn:aws:s3:::bucket/key'. " +
+		"Managed policies and permissions boundaries are not evaluated.",
+	Flags: checkFlags,
+	// flags are parsed by the action so that they can be given after the role file.
+	SkipFlagParsing: true,
+	Action: func(c *cli.Context) error {
+		set := flag.NewFlagSet(c.Command.Name, flag.ContinueOnError)
+		set.SetOutput(io.Discard)
+		for _, f := range checkFlags {
+			err := f.Apply(set)
+			if err != nil {
+				return err
+			}
+		}
+		args, err := parseInterspersed(set, c.Args().Slice())
+		if errors.Is(err, flag.ErrHelp) {
+			return cli.ShowCommandHelp(c.Lineage()[1], c.Command.Name)
+		}
+		if err != nil {
+			return err
+		}
+		c = cli.NewContext(c.App, set, c)
+
+		if len(args) != 1 {
+			return errors.New("usage: pdk roles check <role file> --action <action> [--resource <arn>]")
+		}
+		actions := c.StringSlice("action")
+		if len(actions) == 0 {
+			return errors.New("at least one --action must be provided")
+		}
+		resources := c.StringSlice("resource")
+		if len(resources) == 0 {
+			resources = []string{"*"}
+		}
+		expect := c.String("expect")
+		if expect != "allow" && expect != "deny" {
+			return fmt.Errorf("invalid --expect %s: must be 'allow' or 'deny'", expect)
+		}
+
+		context := map[string]iamp.Value{}
+		for _, kv := range c.StringSlice("context") {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok || k == "" {
+				return fmt.Errorf("invalid --context %s: must be in the format key=value", kv)
+			}
+			context[k] = append(context[k], v)
+		}
+
+		name, role, err := accessrole.LoadFile(args[0])
+		if err != nil {
+			return err
+		}
+		if len(role.ManagedPolicyArns) > 0 {
+			clio.Warnf("role %s has managed policies which are not evaluated: %s", name, strings.Join(role.ManagedPolicyArns, ", "))
+		}
+		if role.PermissionsBoundary != "" {
+			clio.Warnf("role %s has a permissions boundary which is not evaluated: %s", name, role.PermissionsBoundary)
+		}
+
+		var policies []iamp.Policy
+		for _, p := range role.Policies {
+			policies = append(policies, p.PolicyDocument)
+		}
+
+		var unexpected int
+		for _, action := range actions {
+			for _, resource := range resources {
+				res, err := iamp.Evaluate(iamp.Request{
+					Principal: c.String("principal"),
+					Action:    action,
+					Resource:  resource,
+					Context:   context,
+				}, policies...)
+				if err != nil {
+					return fmt.Errorf("evaluating role %s: %w", name, err)
+				}
+
+				fmt.Printf("%s %s: %s\n", action, resource, res.Decision)
+				for _, m := range res.Matched {
+					fmt.Printf("  %s %s\n", role.Policies[m.Policy].PolicyName, statementName(m))
+				}
+
+				allowed := res.Decision == iamp.DecisionAllow
+				if allowed != (expect == "allow") {
+					unexpected++
+				}
+			}
+		}
+
+		if unexpected > 0 {
+			want := map[string]string{"allow": "allowed", "deny": "denied"}[expect]
+			return fmt.Errorf("%d of %d requests were not %s", unexpected, len(actions)*len(resources), want)
+		}
+		return nil
+	},
+}
+
+func statementName(m iamp.MatchedStatement) string {
+	if m.Statement.Sid != "" {
+		return m.Statement.Sid
+	}
+	return fmt.Sprintf("Statement[%d]", m.Index)
+}
+
+// parseInterspersed parses flags which are given before or after positional arguments,
+// and returns the positional arguments. urfave/cli stops parsing flags at the first argument.
+func parseInterspersed(set *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		err := set.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		args = set.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
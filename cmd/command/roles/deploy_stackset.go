@@ -0,0 +1,110 @@
+package roles
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/pkg/cfngen"
+	"github.com/common-fate/pdk/pkg/cfngen/ref"
+	"github.com/common-fate/pdk/pkg/pythonconfig"
+	"github.com/common-fate/pdk/pkg/stackset"
+	"github.com/urfave/cli/v2"
+)
+
+var deployStackSet = cli.Command{
+	Name:  "deploy-stackset",
+	Usage: "Deploy an access role to every account in one or more AWS Organizations OUs using a service-managed StackSet",
+	Description: "Deploys the access role template generated by 'pdk package' (dist/roles/<role>.json) as a StackSet. " +
+		"Run this from the organisation management account or a delegated administrator account (with --call-as DELEGATED_ADMIN).",
+	Flags: []cli.Flag{
+		&cli.PathFlag{Name: "path", Value: ".", Usage: "the path to the folder containing your provider code e.g ./cf-provider-example"},
+		&cli.StringFlag{Name: "role", Required: true, Usage: "the name of the access role to deploy, e.g. 'read' for roles/read.json"},
+		&cli.StringFlag{Name: "handler-account-id", Required: true, Usage: "the ID of the AWS account that the Provider handler is deployed to"},
+		&cli.StringFlag{Name: "handler-id", Usage: "the handler ID. Defaults to cf-handler-<publisher>-<name>"},
+		&cli.StringSliceFlag{Name: "ou", Required: true, Usage: "the ID of an OU to deploy the role to, e.g. ou-abcd-12345678. Can be specified multiple times"},
+		&cli.StringSliceFlag{Name: "region", Usage: "the region to deploy the stack instances to. Can be specified multiple times. Defaults to the current region"},
+		&cli.StringFlag{Name: "stackset-name", Usage: "the name of the StackSet. Defaults to <handler-id>-access-<role>"},
+		&cli.StringFlag{Name: "call-as", Value: string(types.CallAsSelf), Usage: "'SELF' or 'DELEGATED_ADMIN'"},
+		&cli.StringFlag{Name: "handler-role-path", Value: "/", Usage: "the path of the IAM role used by the Provider handler"},
+		&cli.StringFlag{Name: "permissions-boundary-arn", Usage: "the ARN of an IAM managed policy to use as a permissions boundary for the access role"},
+		&cli.StringFlag{Name: "role-path", Value: "/", Usage: "the path to create the access role under"},
+		&cli.StringSliceFlag{Name: "tag", Usage: "set the value of a tag from the [tags] table in provider.toml, e.g. --tag owner=platform. Tags with an empty value in provider.toml must be set"},
+	},
+	Action: func(c *cli.Context) error {
+		ctx := c.Context
+		providerPath := c.Path("path")
+		role := c.String("role")
+
+		pconfig, err := pythonconfig.LoadFile(filepath.Join(providerPath, "provider.toml"))
+		if err != nil {
+			return err
+		}
+
+		templatePath := filepath.Join(providerPath, "dist", "roles", role+".json")
+		template, err := os.ReadFile(templatePath)
+		if err != nil {
+			return fmt.Errorf("reading access role template (run 'pdk package' to generate it): %w", err)
+		}
+
+		callAs := types.CallAs(c.String("call-as"))
+		if callAs != types.CallAsSelf && callAs != types.CallAsDelegatedAdmin {
+			return fmt.Errorf("invalid --call-as %s: must be one of %v", callAs, callAs.Values())
+		}
+
+		handlerID := c.String("handler-id")
+		if handlerID == "" {
+			handlerID = fmt.Sprintf("cf-handler-%s-%s", pconfig.Publisher, pconfig.Name)
+		}
+
+		stackSetName := c.String("stackset-name")
+		if stackSetName == "" {
+			stackSetName = handlerID + "-access-" + role
+		}
+
+		cfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			return err
+		}
+
+		regions := c.StringSlice("region")
+		if len(regions) == 0 {
+			regions = []string{cfg.Region}
+		}
+
+		// tags with an empty value in provider.toml are required parameters of the template.
+		params, err := cfngen.TagParameterValues(pconfig.Tags, c.StringSlice("tag"))
+		if err != nil {
+			return err
+		}
+		params[ref.HandlerAccountID] = c.String("handler-account-id")
+		params[ref.HandlerID] = handlerID
+		params[ref.HandlerRolePath] = c.String("handler-role-path")
+		params[ref.RolePath] = c.String("role-path")
+		// only override the permissions boundary if it's provided, so that
+		// the default from the role definition is used otherwise.
+		if c.IsSet("permissions-boundary-arn") {
+			params[ref.PermissionsBoundaryArn] = c.String("permissions-boundary-arn")
+		}
+
+		d := stackset.NewFromConfig(cfg)
+		err = d.Deploy(ctx, stackset.DeployOpts{
+			StackSetName:          stackSetName,
+			Description:           fmt.Sprintf("Common Fate %s/%s Access Role - %s", pconfig.Publisher, pconfig.Name, role),
+			TemplateBody:          string(template),
+			Parameters:            params,
+			OrganizationalUnitIDs: c.StringSlice("ou"),
+			Regions:               regions,
+			CallAs:                callAs,
+		})
+		if err != nil {
+			return err
+		}
+
+		clio.Successf("deployed access role %s to %v using StackSet %s", role, c.StringSlice("ou"), stackSetName)
+		return nil
+	},
+}
@@ -0,0 +1,13 @@
+package roles
+
+import "github.com/urfave/cli/v2"
+
+var Command = cli.Command{
+	Name:  "roles",
+	Usage: "Work with the access roles used by a Provider",
+	Subcommands: []*cli.Command{
+		&check,
+		&suggest,
+		&deployStackSet,
+	},
+}
@@ -0,0 +1,138 @@
+package roles
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/pkg/accessrole"
+	"github.com/common-fate/pdk/pkg/iamsuggest"
+	"github.com/common-fate/pdk/pkg/pythonconfig"
+	"github.com/urfave/cli/v2"
+)
+
+var suggest = cli.Command{
+	Name:  "suggest",
+	Usage: "Suggest a least-privilege policy from the boto3 calls in the Provider code",
+	Description: "Statically scans the Provider's Python package for boto3 client calls, maps them to IAM actions and prints a draft policy. " +
+		"The draft is compared with the inline policies in the roles folder, showing actions which are used but not granted, and actions which are granted but never used.",
+	Flags: []cli.Flag{
+		&cli.PathFlag{Name: "path", Value: ".", Usage: "the path to the folder containing your provider code e.g ./cf-provider-example"},
+		&cli.StringFlag{Name: "package", Usage: "the Python package to scan. Defaults to provider_<name>"},
+		&cli.PathFlag{Name: "output", Aliases: []string{"o"}, Usage: "write the draft policy to a file, e.g. roles/suggested.json"},
+	},
+	Action: func(c *cli.Context) error {
+		providerPath := c.Path("path")
+
+		pkg := c.String("package")
+		if pkg == "" {
+			pconfig, err := pythonconfig.LoadFile(filepath.Join(providerPath, "provider.toml"))
+			if err != nil {
+				return err
+			}
+			// the name of the provider package is 'provider_<snake_case_name>'
+			pkg = "provider_" + strings.ReplaceAll(pconfig.Name, "-", "_")
+		}
+
+		scan, err := iamsuggest.ScanDir(filepath.Join(providerPath, pkg))
+		if err != nil {
+			return err
+		}
+		for _, w := range scan.Warnings {
+			clio.Warn(w)
+		}
+
+		actions := iamsuggest.Actions(scan.Calls)
+		for _, a := range actions {
+			if a.Guessed {
+				call := a.Calls[0]
+				clio.Warnf("the %s service isn't in the action catalog, check that %s is the correct action for %s (%s:%d)", call.Service, a.Name, call.Method, call.File, call.Line)
+			}
+		}
+
+		policy := iamsuggest.DraftPolicy(actions)
+		draft, err := json.MarshalIndent(policy, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if output := c.Path("output"); output != "" {
+			err = os.WriteFile(output, append(draft, '\n'), 0644)
+			if err != nil {
+				return err
+			}
+			clio.Successf("wrote draft policy with %d actions to %s", len(actions), output)
+		} else {
+			fmt.Println(string(draft))
+		}
+
+		roles, err := loadRoles(providerPath)
+		if err != nil {
+			return err
+		}
+		if len(roles) == 0 {
+			clio.Info("the provider doesn't have any access roles to compare the draft policy with")
+			return nil
+		}
+
+		diff := iamsuggest.Compare(actions, roles...)
+		if len(diff.Missing) == 0 && len(diff.Unused) == 0 {
+			clio.Successf("the access roles grant all of the %d actions used by the provider, and no others", len(actions))
+			return nil
+		}
+
+		if len(diff.Missing) > 0 {
+			fmt.Println("\nActions used by the provider which no access role grants:")
+			for _, a := range diff.Missing {
+				call := a.Calls[0]
+				fmt.Printf("  + %s (%s.%s at %s:%d)\n", a.Name, call.Service, call.Method, call.File, call.Line)
+			}
+		}
+		if len(diff.Unused) > 0 {
+			fmt.Println("\nActions granted by access roles which the provider doesn't use:")
+			for _, u := range diff.Unused {
+				fmt.Printf("  - %s (%s#%s)\n", u.Action, u.File, u.Policy)
+			}
+		}
+		return nil
+	},
+}
+
+// loadRoles loads the access role definitions in the Provider's roles folder.
+func loadRoles(providerPath string) ([]iamsuggest.Role, error) {
+	files, err := os.ReadDir(filepath.Join(providerPath, "roles"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var roles []iamsuggest.Role
+	for _, f := range files {
+		if f.IsDir() || !isRoleDefinition(f.Name()) {
+			continue
+		}
+		rolePath := filepath.Join("roles", f.Name())
+		_, def, err := accessrole.LoadFile(filepath.Join(providerPath, rolePath))
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, iamsuggest.Role{File: filepath.ToSlash(rolePath), Definition: def})
+	}
+	return roles, nil
+}
+
+// isRoleDefinition returns true if the file has a supported role definition extension.
+func isRoleDefinition(filename string) bool {
+	ext := filepath.Ext(filename)
+	for _, e := range accessrole.Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
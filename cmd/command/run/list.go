@@ -0,0 +1,43 @@
+package run
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/pkg/grantledger"
+	"github.com/urfave/cli/v2"
+)
+
+var listCommand = cli.Command{
+	Name:  "list",
+	Usage: "list the active grants made with 'pdk run grant'",
+	Action: func(c *cli.Context) error {
+		grants, err := grantledger.Open(grantledger.DefaultDir).Active()
+		if err != nil {
+			return err
+		}
+		if len(grants) == 0 {
+			clio.Infof("there are no active grants in the ledger")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REQUEST ID\tSUBJECT\tKIND\tARGS\tGRANTED")
+		for _, g := range grants {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", g.RequestID, g.Subject, g.Kind, formatArgs(g.Args), g.GrantedAt.Local().Format(time.RFC3339))
+		}
+		return w.Flush()
+	},
+}
+
+func formatArgs(args map[string]string) string {
+	parts := make([]string, 0, len(args))
+	for _, k := range sortedKeys(args) {
+		parts = append(parts, k+"="+args[k])
+	}
+	return strings.Join(parts, ",")
+}
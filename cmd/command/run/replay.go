@@ -0,0 +1,89 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/cmd/command/handlertarget"
+	"github.com/common-fate/pdk/pkg/replay"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/handlerclient"
+	"github.com/urfave/cli/v2"
+)
+
+// ReplayFlags are the flags of the replay commands. They are shared with 'pdk invoke replay'.
+var ReplayFlags = []cli.Flag{
+	&cli.IntFlag{Name: "concurrency", Value: 1, Usage: "the number of requests to run at the same time. With the default of 1, requests run in the order of the file"},
+	&cli.PathFlag{Name: "results", Usage: "write the results to a JSON lines file (if not provided, the results are written to stdout)"},
+}
+
+// ReplayArgsUsage describes the argument of the replay commands.
+const ReplayArgsUsage = "<requests.jsonl>"
+
+var replayCommand = cli.Command{
+	Name:      "replay",
+	Usage:     "run the requests in a JSON lines file against the local Provider",
+	ArgsUsage: ReplayArgsUsage,
+	Flags:     ReplayFlags,
+	Action: func(c *cli.Context) error {
+		executor, err := handlertarget.NewLocalExecutor()
+		if err != nil {
+			return err
+		}
+		return Replay(c, executor)
+	},
+}
+
+// Replay reads the requests file given as the first argument, runs the requests
+// with the executor and writes the results. An error is returned if any of the requests failed.
+func Replay(c *cli.Context, executor handlerclient.Executor) error {
+	path := c.Args().First()
+	if path == "" {
+		return errors.New("usage: " + c.Command.HelpName + " " + ReplayArgsUsage)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	requests, err := replay.Read(f)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var w io.Writer = os.Stdout
+	if resultsPath := c.Path("results"); resultsPath != "" {
+		out, err := os.Create(resultsPath)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		w = out
+	}
+
+	clio.Infof("replaying %d requests from %s", len(requests), path)
+
+	// stop starting requests on Ctrl-C; the results of the requests which ran are kept.
+	ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+	defer stop()
+
+	summary, err := replay.Run(ctx, executor, requests, c.Int("concurrency"), w)
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("replay was interrupted after %d of %d requests (%d failed)", summary.Succeeded+summary.Failed, len(requests), summary.Failed)
+	}
+	if err != nil {
+		return err
+	}
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d requests failed", summary.Failed, len(requests))
+	}
+	clio.Successf("%d requests succeeded", summary.Succeeded)
+	return nil
+}
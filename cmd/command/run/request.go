@@ -0,0 +1,134 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+	"github.com/segmentio/ksuid"
+	"github.com/urfave/cli/v2"
+)
+
+// GrantFlags are the flags used to build a grant request. They are shared
+// with 'pdk invoke', so that local and deployed handlers are called in the same way.
+var GrantFlags = []cli.Flag{
+	&cli.StringFlag{Name: "subject", Required: true},
+	&cli.StringFlag{Name: "kind", Required: true},
+	&cli.StringSliceFlag{Name: "arg", Aliases: []string{"a"}},
+	&cli.StringFlag{Name: "request-id", Usage: "supply a particular request ID (if not provided, an auto-generated ID will be used)"},
+}
+
+// RevokeFlags are the flags used to build a revoke request. The subject and kind are
+// checked by RevokeRequest rather than being required, as 'pdk run revoke' can read them from the grant ledger.
+var RevokeFlags = []cli.Flag{
+	&cli.StringFlag{Name: "subject"},
+	&cli.StringFlag{Name: "kind"},
+	&cli.StringSliceFlag{Name: "arg", Aliases: []string{"a"}},
+	&cli.StringSliceFlag{Name: "state", Aliases: []string{"s"}},
+	&cli.StringFlag{Name: "request-id", Usage: "supply a particular request ID (if not provided, an auto-generated ID will be used)"},
+}
+
+// WithFlags returns the shared flags followed by the extra flags of a command.
+func WithFlags(shared []cli.Flag, extra ...cli.Flag) []cli.Flag {
+	flags := make([]cli.Flag, 0, len(shared)+len(extra))
+	flags = append(flags, shared...)
+	return append(flags, extra...)
+}
+
+// GrantRequest builds a grant request from the GrantFlags.
+func GrantRequest(c *cli.Context) (msg.Grant, error) {
+	args, err := parseKeyValues(c.StringSlice("arg"))
+	if err != nil {
+		return msg.Grant{}, err
+	}
+
+	return msg.Grant{
+		Subject: c.String("subject"),
+		Target: msg.Target{
+			Kind:      c.String("kind"),
+			Arguments: args,
+		},
+		Request: msg.AccessRequest{ID: requestID(c)},
+	}, nil
+}
+
+// RevokeRequest builds a revoke request from the RevokeFlags.
+func RevokeRequest(c *cli.Context) (msg.Revoke, error) {
+	if c.String("subject") == "" || c.String("kind") == "" {
+		return msg.Revoke{}, errors.New("--subject and --kind are required")
+	}
+	args, err := parseKeyValues(c.StringSlice("arg"))
+	if err != nil {
+		return msg.Revoke{}, err
+	}
+	state, err := parseKeyValues(c.StringSlice("state"))
+	if err != nil {
+		return msg.Revoke{}, err
+	}
+
+	request := msg.Revoke{
+		Subject: c.String("subject"),
+		Target: msg.Target{
+			Kind:      c.String("kind"),
+			Arguments: args,
+		},
+		State:   map[string]any{},
+		Request: msg.AccessRequest{ID: requestID(c)},
+	}
+	for k, v := range state {
+		request.State[k] = v
+	}
+	return request, nil
+}
+
+// RevokeCommand returns the command which revokes a grant, e.g. 'pdk run revoke --request-id ...'.
+func RevokeCommand(command string, grant msg.Grant, state map[string]any) string {
+	parts := []string{command, "--request-id", grant.Request.ID, "--subject", grant.Subject, "--kind", grant.Target.Kind}
+	for _, k := range sortedKeys(grant.Target.Arguments) {
+		parts = append(parts, "-a", k+"="+grant.Target.Arguments[k])
+	}
+	stateKeys := make([]string, 0, len(state))
+	for k := range state {
+		stateKeys = append(stateKeys, k)
+	}
+	sort.Strings(stateKeys)
+	for _, k := range stateKeys {
+		parts = append(parts, "-s", fmt.Sprintf("%s=%v", k, state[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func requestID(c *cli.Context) string {
+	id := c.String("request-id")
+	if id == "" {
+		// use 'pdk_' prefix to denote generated requests which came from the PDK CLI.
+		id = "pdk_" + ksuid.New().String()
+		clio.Infof("generated a unique Access Request ID: %s", id)
+	}
+	return id
+}
+
+// parseKeyValues parses flags in key=value format.
+func parseKeyValues(flags []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, f := range flags {
+		key, val, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid value %q: values must be in key=value format", f)
+		}
+		values[key] = val
+	}
+	return values, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
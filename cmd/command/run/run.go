@@ -2,14 +2,14 @@ package run
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strings"
+	"time"
 
 	"github.com/common-fate/clio"
-	"github.com/common-fate/provider-registry-sdk-go/pkg/handlerclient"
+	"github.com/common-fate/pdk/pkg/grantledger"
 	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
 	"github.com/joho/godotenv"
-	"github.com/segmentio/ksuid"
 	"github.com/urfave/cli/v2"
 )
 
@@ -19,58 +19,30 @@ var Command = cli.Command{
 		&grantCommand,
 		&revokeCommand,
 		&describeCommand,
+		&replayCommand,
+		&serveCommand,
+		&listCommand,
 	},
 }
 
 var grantCommand = cli.Command{
-	Name: "grant",
-	Flags: []cli.Flag{
-		&cli.StringFlag{Name: "subject", Required: true},
-		&cli.StringFlag{Name: "kind", Required: true},
-		&cli.StringSliceFlag{Name: "arg", Aliases: []string{"a"}},
-		&cli.StringFlag{Name: "request-id", Usage: "supply a particular request ID (if not provided, an auto-generated ID will be used)"},
-	},
+	Name:  "grant",
+	Flags: WithFlags(GrantFlags, traceFileFlag),
 	Action: func(c *cli.Context) error {
 		ctx := c.Context
 		// expects that the config exists in the dotenv file
 		_ = godotenv.Load()
 
-		requestID := c.String("request-id")
-		if requestID == "" {
-			// use 'pdk_' prefix to denote generated requests which came from the PDK CLI.
-			requestID = "pdk_" + ksuid.New().String()
-			clio.Infof("generated a unique Access Request ID: %s", requestID)
-		}
-
-		target := msg.Target{
-			Kind:      c.String("kind"),
-			Arguments: map[string]string{},
-		}
-
-		var argFlags []string
-
-		for _, arg := range c.StringSlice("arg") {
-			argFlags = append(argFlags, "-a "+arg)
-
-			parts := strings.SplitN(arg, "=", 2) // args are in key=value format
-			key := parts[0]
-			val := parts[1]
-			target.Arguments[key] = val
-		}
-
-		subject := c.String("subject")
-
-		rt := handlerclient.Client{
-			Executor: handlerclient.Local{},
+		request, err := GrantRequest(c)
+		if err != nil {
+			return err
 		}
 
-		request := msg.Grant{
-			Subject: subject,
-			Target:  target,
-			Request: msg.AccessRequest{
-				ID: requestID,
-			},
+		rt, closeClient, err := newClient(c)
+		if err != nil {
+			return err
 		}
+		defer closeClient()
 
 		clio.Infow("granting access", "request", request)
 
@@ -86,93 +58,112 @@ var grantCommand = cli.Command{
 
 		clio.Successf("granted access: %s", string(resJSON))
 
-		var stateFlags []string
-		for key, val := range res.State {
-			flag := fmt.Sprintf("-s %s=%s", key, val)
-			stateFlags = append(stateFlags, flag)
+		err = grantledger.Open(grantledger.DefaultDir).Record(grantledger.FromRequest(request, res.State, time.Now()))
+		if err != nil {
+			clio.Warnf("couldn't record the grant in the ledger: %s", err)
+			clio.Infof("revoke access by running:\n%s", RevokeCommand("pdk run revoke", request, res.State))
+			return nil
 		}
 
-		revoke := fmt.Sprintf("pdk run revoke --request-id %s --subject %s --kind %s %s %s", requestID, subject, target.Kind, strings.Join(argFlags, " "), strings.Join(stateFlags, " "))
-
-		clio.Infof("revoke access by running:\n%s", revoke)
+		clio.Infof("revoke access by running:\npdk run revoke --request-id %s", request.Request.ID)
 
 		return nil
 	},
 }
 
 var revokeCommand = cli.Command{
-	Name: "revoke",
-	Flags: []cli.Flag{
-		&cli.StringFlag{Name: "subject", Required: true},
-		&cli.StringFlag{Name: "kind", Required: true},
-		&cli.StringSliceFlag{Name: "arg", Aliases: []string{"a"}},
-		&cli.StringSliceFlag{Name: "state", Aliases: []string{"s"}},
-		&cli.StringFlag{Name: "request-id", Usage: "supply a particular request ID (if not provided, an auto-generated ID will be used)"},
-	},
+	Name:  "revoke",
+	Usage: "revoke access. Grants made with 'pdk run grant' can be revoked with only --request-id, or all at once with --all",
+	Flags: WithFlags(RevokeFlags, traceFileFlag, &cli.BoolFlag{Name: "all", Usage: "revoke all of the active grants in the ledger"}),
 	Action: func(c *cli.Context) error {
 		ctx := c.Context
 
 		// expects that the config exists in the dotenv file
 		_ = godotenv.Load()
 
-		requestID := c.String("request-id")
-		if requestID == "" {
-			// use 'pdk_' prefix to denote generated requests which came from the PDK CLI.
-			requestID = "pdk_" + ksuid.New().String()
-			clio.Infof("generated a unique Access Request ID: %s", requestID)
-		}
-
-		request := msg.Revoke{
-			Subject: c.String("subject"),
-			Target: msg.Target{
-				Kind:      c.String("kind"),
-				Arguments: map[string]string{},
-			},
-			State: map[string]any{},
-			Request: msg.AccessRequest{
-				ID: requestID,
-			},
+		ledger := grantledger.Open(grantledger.DefaultDir)
+
+		var requests []msg.Revoke
+		switch {
+		case c.Bool("all"):
+			grants, err := ledger.Active()
+			if err != nil {
+				return err
+			}
+			if len(grants) == 0 {
+				clio.Infof("there are no active grants in the ledger")
+				return nil
+			}
+			for _, g := range grants {
+				requests = append(requests, g.RevokeRequest())
+			}
+		case c.IsSet("request-id") && !c.IsSet("subject") && !c.IsSet("kind"):
+			g, err := ledger.Get(c.String("request-id"))
+			if errors.Is(err, grantledger.ErrNotFound) {
+				return fmt.Errorf("%w, provide --subject, --kind, --arg and --state to revoke it", err)
+			}
+			if err != nil {
+				return err
+			}
+			if !g.Active() {
+				clio.Warnf("the grant was already revoked at %s", g.RevokedAt.Local().Format(time.RFC3339))
+			}
+			requests = append(requests, g.RevokeRequest())
+		default:
+			request, err := RevokeRequest(c)
+			if err != nil {
+				return err
+			}
+			requests = append(requests, request)
+		}
+
+		rt, closeClient, err := newClient(c)
+		if err != nil {
+			return err
 		}
+		defer closeClient()
 
-		for _, arg := range c.StringSlice("arg") {
-			parts := strings.SplitN(arg, "=", 2) // args are in key=value format
-			key := parts[0]
-			val := parts[1]
-			request.Target.Arguments[key] = val
-		}
+		var failed int
+		for _, request := range requests {
+			clio.Infow("revoking access", "request", request)
 
-		for _, arg := range c.StringSlice("state") {
-			parts := strings.SplitN(arg, "=", 2) // args are in key=value format
-			key := parts[0]
-			val := parts[1]
-			request.State[key] = val
-		}
+			err = rt.Revoke(ctx, request)
+			if err != nil {
+				if len(requests) == 1 {
+					return err
+				}
+				// keep going, so that one broken grant doesn't stop the others being cleaned up.
+				clio.Errorf("error revoking %s: %s", request.Request.ID, err)
+				failed++
+				continue
+			}
 
-		rt := handlerclient.Client{
-			Executor: handlerclient.Local{},
+			err = ledger.MarkRevoked(request.Request.ID, time.Now())
+			if err != nil && !errors.Is(err, grantledger.ErrNotFound) {
+				clio.Warnf("couldn't update the grant ledger: %s", err)
+			}
+			clio.Successf("revoked access for %s", request.Request.ID)
 		}
 
-		clio.Infow("revoking access", "request", request)
-
-		err := rt.Revoke(ctx, request)
-		if err != nil {
-			return err
+		if failed > 0 {
+			return fmt.Errorf("%d of %d grants couldn't be revoked", failed, len(requests))
 		}
-
-		clio.Successf("revoked access")
 		return nil
 	},
 }
 
 var describeCommand = cli.Command{
-	Name: "describe",
+	Name:  "describe",
+	Flags: []cli.Flag{traceFileFlag},
 	Action: func(c *cli.Context) error {
 		// expects that the config exists in the dotenv file
 		_ = godotenv.Load()
 
-		rt := handlerclient.Client{
-			Executor: handlerclient.Local{},
+		rt, closeClient, err := newClient(c)
+		if err != nil {
+			return err
 		}
+		defer closeClient()
 		out, err := rt.Describe(c.Context)
 		if err != nil {
 			return err
@@ -0,0 +1,67 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/cmd/command/handlertarget"
+	"github.com/common-fate/pdk/pkg/handlerserver"
+	"github.com/urfave/cli/v2"
+)
+
+var serveCommand = cli.Command{
+	Name:  "serve",
+	Usage: "serve the local Provider over HTTP, accepting the same payloads as the deployed handler",
+	Flags: []cli.Flag{
+		&cli.IntFlag{Name: "port", Value: 9000, Usage: "the port to listen on"},
+		&cli.StringFlag{Name: "host", Value: "127.0.0.1", Usage: "the address to listen on"},
+	},
+	Action: func(c *cli.Context) error {
+		executor, err := handlertarget.NewLocalExecutor()
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(c.Context, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		addr := net.JoinHostPort(c.String("host"), strconv.Itoa(c.Int("port")))
+		srv := &http.Server{
+			Addr:              addr,
+			Handler:           &handlerserver.Server{Executor: executor},
+			ReadHeaderTimeout: 10 * time.Second,
+			// cancel running requests on shutdown, which stops the Provider processes.
+			BaseContext: func(net.Listener) context.Context { return ctx },
+		}
+
+		shutdown := make(chan struct{})
+		go func() {
+			defer close(shutdown)
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			_ = srv.Shutdown(shutdownCtx)
+		}()
+
+		url := fmt.Sprintf("http://%s", addr)
+		clio.Infof("serving the Provider on %s", url)
+		clio.Infof("POST Lambda payloads to %s, or use lambda.Invoke from the AWS SDK with the Lambda endpoint set to %s", url, url)
+
+		err = srv.ListenAndServe()
+		if errors.Is(err, http.ErrServerClosed) {
+			// wait for the running requests to stop, so that no Provider processes are left behind.
+			<-shutdown
+			return nil
+		}
+		return err
+	},
+}
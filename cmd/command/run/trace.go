@@ -0,0 +1,94 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/pkg/pythonconfig"
+	"github.com/common-fate/pdk/pkg/tracefile"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/handlerclient"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+	"github.com/urfave/cli/v2"
+)
+
+// otelInstrument is installed into the virtual environment by the opentelemetry-distro package.
+const otelInstrument = ".venv/bin/opentelemetry-instrument"
+
+var traceFileFlag = &cli.PathFlag{
+	Name:  "trace-file",
+	Usage: "trace the Provider with OpenTelemetry and append the spans to a JSON lines file. Requires the opentelemetry-distro and opentelemetry-exporter-otlp-proto-http packages",
+}
+
+// newClient returns a handler client which runs the Provider locally. If a trace file
+// is provided, the Provider is traced and cleanup must be called to finish writing the spans.
+func newClient(c *cli.Context) (client *handlerclient.Client, cleanup func(), err error) {
+	path := c.Path(traceFileFlag.Name)
+	if path == "" {
+		return &handlerclient.Client{Executor: handlerclient.Local{}}, func() {}, nil
+	}
+
+	if _, err := os.Stat(otelInstrument); err != nil {
+		return nil, nil, fmt.Errorf("%s wasn't found, install it by running 'pip install opentelemetry-distro opentelemetry-exporter-otlp-proto-http' and 'opentelemetry-bootstrap -a install': %w", otelInstrument, err)
+	}
+
+	receiver, err := tracefile.Listen(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	serviceName := "provider"
+	if pconfig, err := pythonconfig.LoadFile("provider.toml"); err == nil {
+		serviceName = pconfig.Publisher + "/" + pconfig.Name
+	}
+
+	cleanup = func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err := receiver.Close(ctx)
+		if err != nil {
+			clio.Errorf("error closing trace file: %s", err)
+		}
+		clio.Infof("wrote %d spans to %s", receiver.Count(), path)
+	}
+
+	executor := tracedLocal{Env: append(os.Environ(), receiver.Env(serviceName)...)}
+	return &handlerclient.Client{Executor: executor}, cleanup, nil
+}
+
+// tracedLocal runs the Provider locally with OpenTelemetry auto-instrumentation,
+// in the same way that the OpenTelemetry Lambda layer wraps the Provider when it's deployed.
+type tracedLocal struct {
+	Env []string
+}
+
+func (t tracedLocal) Execute(ctx context.Context, request msg.Request) (*msg.Result, error) {
+	payload := struct {
+		Type msg.RequestType `json:"type"`
+		Data msg.Request     `json:"data"`
+	}{Type: request.Type(), Data: request}
+
+	payloadbytes, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, otelInstrument, ".venv/bin/provider", "run", string(payloadbytes))
+	cmd.Env = t.Env
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var res msg.Result
+	err = json.Unmarshal(out, &res)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't decode the Provider response, check that the OpenTelemetry exporters aren't writing to stdout: %w", err)
+	}
+	return &res, nil
+}
@@ -0,0 +1,11 @@
+package template
+
+import "github.com/urfave/cli/v2"
+
+var Command = cli.Command{
+	Name:  "template",
+	Usage: "Work with the deployment templates generated for a Provider",
+	Subcommands: []*cli.Command{
+		&validate,
+	},
+}
@@ -0,0 +1,46 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/pkg/cfngen"
+	"github.com/urfave/cli/v2"
+)
+
+var validate = cli.Command{
+	Name:      "validate",
+	Usage:     "Check a CloudFormation template for problems which would cause it to fail when it's deployed",
+	ArgsUsage: "<file>",
+	Description: "Validates a JSON CloudFormation template offline, such as dist/cloudformation.json. " +
+		"References to parameters, resources and conditions, parameter names and defaults, name lengths, template size limits and resource property types are checked. " +
+		"Templates generated by 'pdk package' are validated automatically.",
+	Action: func(c *cli.Context) error {
+		file := c.Args().First()
+		if file == "" {
+			return errors.New("usage: pdk template validate <file>")
+		}
+
+		template, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		err = cfngen.Validate(template)
+		var verrs cfngen.ValidationErrors
+		if errors.As(err, &verrs) {
+			for _, e := range verrs {
+				clio.Error(e.Error())
+			}
+			return fmt.Errorf("found %d problems in %s", len(verrs), file)
+		}
+		if err != nil {
+			return err
+		}
+
+		clio.Successf("%s is valid", file)
+		return nil
+	},
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/common-fate/pdk/cmd/command"
 	"github.com/common-fate/pdk/cmd/command/devhandler"
 	"github.com/common-fate/pdk/cmd/command/resources"
+	"github.com/common-fate/pdk/cmd/command/roles"
 	"github.com/common-fate/pdk/cmd/command/run"
+	"github.com/common-fate/pdk/cmd/command/template"
 
 	"github.com/common-fate/pdk/internal/build"
 	"github.com/joho/godotenv"
@@ -29,7 +31,9 @@ func main() {
 			&devhandler.Command,
 			&command.SchemaCommand,
 			&resources.Command,
+			&roles.Command,
 			&run.Command,
+			&template.Command,
 			&command.Configure,
 			&command.Login,
 			&command.Logout,
@@ -2,7 +2,9 @@ package run
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"os"
 	"os/exec"
 
@@ -16,6 +18,16 @@ type payload struct {
 }
 
 func RunEntrypoint(event msg.Request, env map[string]string) (*msg.Result, error) {
+	return runEntrypoint(context.Background(), event, env, os.Stdin)
+}
+
+// RunEntrypointContext runs the Provider in the background: it's killed when ctx
+// is cancelled, and it doesn't share stdin with pdk.
+func RunEntrypointContext(ctx context.Context, event msg.Request, env map[string]string) (*msg.Result, error) {
+	return runEntrypoint(ctx, event, env, nil)
+}
+
+func runEntrypoint(ctx context.Context, event msg.Request, env map[string]string, stdin io.Reader) (*msg.Result, error) {
 	payload := payload{
 		Type: event.Type(),
 		Data: event,
@@ -32,10 +44,10 @@ func RunEntrypoint(event msg.Request, env map[string]string) (*msg.Result, error
 
 	var b bytes.Buffer
 
-	cmd := exec.Command(".venv/bin/provider", "run", eventString)
+	cmd := exec.CommandContext(ctx, ".venv/bin/provider", "run", eventString)
 	cmd.Stdout = &b
 	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+	cmd.Stdin = stdin
 	// forward the env of the caller to the script
 	// this means AWS creds in the env will be available to the python code etc
 	cmd.Env = append(cmd.Env, os.Environ()...)
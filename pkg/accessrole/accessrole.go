@@ -0,0 +1,219 @@
+// Package accessrole parses the access role definitions in a Provider's roles/ folder.
+//
+// A role definition can either be a plain IAM policy document, which becomes the
+// single inline policy of the role, or an extended definition in JSON or YAML:
+//
+//	Description: Read access to CloudWatch Logs
+//	Policies:
+//	  - PolicyName: logs-read
+//	    PolicyDocument:
+//	      Version: "2012-10-17"
+//	      Statement:
+//	        - Effect: Allow
+//	          Action: logs:GetLogEvents
+//	          Resource: "*"
+//	ManagedPolicyArns:
+//	  - arn:aws:iam::aws:policy/ReadOnlyAccess
+//	MaxSessionDuration: 3600
+//	PermissionsBoundary: arn:aws:iam::123456789012:policy/boundary
+//	Tags:
+//	  team: platform
+//	Trust:
+//	  ExternalId: example
+//	  Condition:
+//	    StringEquals:
+//	      aws:PrincipalOrgID: o-abcd1234
+package accessrole
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/common-fate/pdk/pkg/iamp"
+	"github.com/invopop/yaml"
+)
+
+// DefaultPolicyName is the name of the inline policy created from a plain IAM policy document.
+const DefaultPolicyName = "access-policy"
+
+// ReservedTagPrefix is the prefix of tags which are set by Common Fate and can't be used in role definitions.
+const ReservedTagPrefix = "common-fate-"
+
+// Definition is an access role definition.
+type Definition struct {
+	Description string `json:"Description,omitempty"`
+	// Policies are inline policies attached to the role.
+	Policies []Policy `json:"Policies,omitempty"`
+	// ManagedPolicyArns are the ARNs of AWS or customer managed policies attached to the role.
+	ManagedPolicyArns []string `json:"ManagedPolicyArns,omitempty"`
+	// MaxSessionDuration is the maximum session duration in seconds, from 3600 to 43200.
+	MaxSessionDuration *int `json:"MaxSessionDuration,omitempty"`
+	// PermissionsBoundary is the default permissions boundary for the role. It can be
+	// overridden by the PermissionsBoundaryArn parameter when the role is deployed.
+	PermissionsBoundary string            `json:"PermissionsBoundary,omitempty"`
+	Tags                map[string]string `json:"Tags,omitempty"`
+	Trust               Trust             `json:"Trust,omitempty"`
+}
+
+// Policy is an inline policy attached to the role.
+type Policy struct {
+	PolicyName     string      `json:"PolicyName"`
+	PolicyDocument iamp.Policy `json:"PolicyDocument"`
+}
+
+// Trust contains additional conditions for the trust policy of the role,
+// which are required when the Provider handler assumes the role.
+type Trust struct {
+	// ExternalId requires the sts:ExternalId to match when assuming the role.
+	ExternalId string `json:"ExternalId,omitempty"`
+	// Condition is an IAM condition block, e.g. {"StringEquals": {"aws:PrincipalOrgID": "o-abcd1234"}}.
+	Condition iamp.Condition `json:"Condition,omitempty"`
+}
+
+// TrustCondition returns the condition block for the trust policy, or nil if there are no conditions.
+func (t Trust) TrustCondition() iamp.Condition {
+	if t.ExternalId == "" && len(t.Condition) == 0 {
+		return nil
+	}
+	cond := iamp.Condition{}
+	for op, values := range t.Condition {
+		cond[op] = map[string]iamp.Value{}
+		for k, v := range values {
+			cond[op][k] = v
+		}
+	}
+	if t.ExternalId != "" {
+		if _, ok := cond["StringEquals"]; !ok {
+			cond["StringEquals"] = map[string]iamp.Value{}
+		}
+		cond["StringEquals"]["sts:ExternalId"] = iamp.Value{t.ExternalId}
+	}
+	return cond
+}
+
+// SortedTags returns the keys of the role tags in sorted order.
+func (d Definition) SortedTags() []string {
+	var keys []string
+	for k := range d.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FromPolicy creates a role definition from a plain IAM policy document.
+func FromPolicy(policy iamp.Policy) Definition {
+	return Definition{
+		Policies: []Policy{{PolicyName: DefaultPolicyName, PolicyDocument: policy}},
+	}
+}
+
+// Validate checks that the role definition can be deployed.
+func (d Definition) Validate() error {
+	if len(d.Policies) == 0 && len(d.ManagedPolicyArns) == 0 {
+		return fmt.Errorf("the role must have at least one policy or managed policy ARN")
+	}
+
+	names := map[string]bool{}
+	for _, p := range d.Policies {
+		if p.PolicyName == "" {
+			return fmt.Errorf("policies must have a PolicyName")
+		}
+		if names[p.PolicyName] {
+			return fmt.Errorf("duplicate policy name %s", p.PolicyName)
+		}
+		names[p.PolicyName] = true
+		if len(p.PolicyDocument.Statements) == 0 {
+			return fmt.Errorf("policy %s must have at least one statement", p.PolicyName)
+		}
+	}
+
+	for _, arn := range d.ManagedPolicyArns {
+		if !strings.HasPrefix(arn, "arn:") {
+			return fmt.Errorf("invalid managed policy ARN %s", arn)
+		}
+	}
+
+	if d.PermissionsBoundary != "" && !strings.HasPrefix(d.PermissionsBoundary, "arn:") {
+		return fmt.Errorf("invalid permissions boundary ARN %s", d.PermissionsBoundary)
+	}
+
+	err := d.Trust.Condition.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid trust condition: %w", err)
+	}
+
+	if d.MaxSessionDuration != nil && (*d.MaxSessionDuration < 3600 || *d.MaxSessionDuration > 43200) {
+		return fmt.Errorf("MaxSessionDuration must be between 3600 and 43200 seconds, got %d", *d.MaxSessionDuration)
+	}
+
+	for k := range d.Tags {
+		if strings.HasPrefix(k, ReservedTagPrefix) {
+			return fmt.Errorf("tag %s is reserved: tags must not begin with %s", k, ReservedTagPrefix)
+		}
+	}
+
+	return nil
+}
+
+// Parse parses a role definition in JSON or YAML. Plain IAM policy documents are
+// converted to a role definition with a single inline policy.
+func Parse(data []byte) (Definition, error) {
+	// YAML is a superset of JSON, so converting handles both formats.
+	j, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return Definition{}, err
+	}
+
+	var keys map[string]json.RawMessage
+	err = json.Unmarshal(j, &keys)
+	if err != nil {
+		return Definition{}, err
+	}
+
+	// plain policy documents always contain a 'Statement' field.
+	if _, ok := keys["Statement"]; ok {
+		var policy iamp.Policy
+		err = json.Unmarshal(j, &policy)
+		if err != nil {
+			return Definition{}, err
+		}
+		d := FromPolicy(policy)
+		return d, d.Validate()
+	}
+
+	dec := json.NewDecoder(strings.NewReader(string(j)))
+	dec.DisallowUnknownFields()
+
+	var d Definition
+	err = dec.Decode(&d)
+	if err != nil {
+		return Definition{}, err
+	}
+	return d, d.Validate()
+}
+
+// Extensions are the file extensions supported for role definitions.
+var Extensions = []string{".json", ".yaml", ".yml"}
+
+// LoadFile loads a role definition from a file. The name of the role is the file name
+// without the extension, e.g. 'read' for 'roles/read.yaml'.
+func LoadFile(path string) (name string, d Definition, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", Definition{}, err
+	}
+
+	d, err = Parse(data)
+	if err != nil {
+		return "", Definition{}, fmt.Errorf("parsing role definition %s: %w", path, err)
+	}
+
+	base := filepath.Base(path)
+	name = strings.TrimSuffix(base, filepath.Ext(base))
+	return name, d, nil
+}
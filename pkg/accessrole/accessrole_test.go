@@ -0,0 +1,140 @@
+package accessrole
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/common-fate/pdk/pkg/iamp"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    string
+		want    Definition
+		wantErr bool
+	}{
+		{
+			name: "plain policy",
+			give: `{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["s3:ListBucket"], "Resource": ["*"]}]}`,
+			want: FromPolicy(iamp.NewPolicy(iamp.Statement{
+				Effect:   iamp.Allow,
+				Action:   iamp.Value{"s3:ListBucket"},
+				Resource: iamp.Value{"*"},
+			})),
+		},
+		{
+			name: "yaml definition",
+			give: `
+Description: Read logs
+Policies:
+  - PolicyName: logs-read
+    PolicyDocument:
+      Version: "2012-10-17"
+      Statement:
+        - Effect: Allow
+          Action: [logs:GetLogEvents]
+          Resource: ["*"]
+ManagedPolicyArns:
+  - arn:aws:iam::aws:policy/ReadOnlyAccess
+MaxSessionDuration: 3600
+Tags:
+  team: platform
+Trust:
+  ExternalId: example
+`,
+			want: Definition{
+				Description: "Read logs",
+				Policies: []Policy{
+					{
+						PolicyName: "logs-read",
+						PolicyDocument: iamp.NewPolicy(iamp.Statement{
+							Effect:   iamp.Allow,
+							Action:   iamp.Value{"logs:GetLogEvents"},
+							Resource: iamp.Value{"*"},
+						}),
+					},
+				},
+				ManagedPolicyArns:  []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+				MaxSessionDuration: intPtr(3600),
+				Tags:               map[string]string{"team": "platform"},
+				Trust:              Trust{ExternalId: "example"},
+			},
+		},
+		{
+			name: "json definition with managed policies only",
+			give: `{"ManagedPolicyArns": ["arn:aws:iam::aws:policy/ReadOnlyAccess"]}`,
+			want: Definition{
+				ManagedPolicyArns: []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+			},
+		},
+		{
+			name:    "unknown field",
+			give:    `{"ManagedPolicies": ["arn:aws:iam::aws:policy/ReadOnlyAccess"]}`,
+			wantErr: true,
+		},
+		{
+			name:    "no policies",
+			give:    `{"Description": "empty"}`,
+			wantErr: true,
+		},
+		{
+			name:    "reserved tag",
+			give:    `{"ManagedPolicyArns": ["arn:aws:iam::aws:policy/ReadOnlyAccess"], "Tags": {"common-fate-abac-role": "admin"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "session duration out of range",
+			give:    `{"ManagedPolicyArns": ["arn:aws:iam::aws:policy/ReadOnlyAccess"], "MaxSessionDuration": 60}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.give))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrustCondition(t *testing.T) {
+	trust := Trust{
+		ExternalId: "example",
+		Condition: iamp.Condition{
+			"StringEquals": {"aws:PrincipalOrgID": {"o-abcd1234"}},
+		},
+	}
+
+	want := iamp.Condition{
+		"StringEquals": {
+			"aws:PrincipalOrgID": {"o-abcd1234"},
+			"sts:ExternalId":     {"example"},
+		},
+	}
+
+	got := trust.TrustCondition()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TrustCondition() = %v, want %v", got, want)
+	}
+
+	// the condition in the definition must not be modified.
+	if _, ok := trust.Condition["StringEquals"]["sts:ExternalId"]; ok {
+		t.Error("TrustCondition() modified the role definition")
+	}
+
+	if (Trust{}).TrustCondition() != nil {
+		t.Error("want nil condition for an empty trust")
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
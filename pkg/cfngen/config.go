@@ -0,0 +1,157 @@
+package cfngen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	cfn "github.com/awslabs/goformation/v7/cloudformation"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/providerregistrysdk"
+)
+
+// The config types which a Provider schema can use.
+const (
+	ConfigTypeString  = "string"
+	ConfigTypeNumber  = "number"
+	ConfigTypeBoolean = "boolean"
+	ConfigTypeList    = "list"
+)
+
+// OptionalNumberPattern matches an empty string or a number. It's used for optional number
+// config, because CloudFormation Number parameters can't have an empty default.
+const OptionalNumberPattern = `^$|^-?[0-9]+(\.[0-9]+)?$`
+
+// ConfigField is a config field in the Provider schema. The registry schema type only has
+// the description, type and secret flag of a field, so the schema is decoded into a Schema
+// to read the constraints which the Provider declares.
+type ConfigField struct {
+	Type        string  `json:"type"`
+	Description *string `json:"description,omitempty"`
+	Secret      bool    `json:"secret,omitempty"`
+	// Optional fields can be left empty when the Provider is deployed.
+	Optional bool `json:"optional,omitempty"`
+	Default  any  `json:"default,omitempty"`
+	// Enum lists the allowed values of the field.
+	Enum []any `json:"enum,omitempty"`
+	// Pattern is a regular expression which values of the field must match.
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// Schema is a Provider schema with the config decoded as ConfigFields.
+type Schema struct {
+	providerregistrysdk.Schema
+	Config map[string]ConfigField `json:"config,omitempty"`
+}
+
+// FromRegistrySchema converts a registry schema, which doesn't include any config constraints.
+func FromRegistrySchema(s providerregistrysdk.Schema) Schema {
+	schema := Schema{Schema: s, Config: map[string]ConfigField{}}
+	if s.Config == nil {
+		return schema
+	}
+	for k, v := range *s.Config {
+		schema.Config[k] = ConfigField{
+			Type:        string(v.Type),
+			Description: v.Description,
+			Secret:      v.Secret != nil && *v.Secret,
+		}
+	}
+	return schema
+}
+
+// IsRequired returns true if a value must be provided for the field when deploying.
+func (f ConfigField) IsRequired() bool {
+	return !f.Optional && f.Default == nil
+}
+
+// DefaultString returns the default value of the field, formatted as a parameter value.
+// Lists are formatted as comma-separated values.
+func (f ConfigField) DefaultString() string {
+	return formatConfigValue(f.Default)
+}
+
+func formatConfigValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case []any:
+		items := make([]string, len(val))
+		for i, item := range val {
+			items[i] = formatConfigValue(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v)
+}
+
+// configParameter returns the CloudFormation parameter for a config field.
+func configParameter(f ConfigField) (cfn.Parameter, error) {
+	p := cfn.Parameter{
+		Type:        "String",
+		Description: f.Description,
+	}
+
+	switch f.Type {
+	case ConfigTypeString, "":
+	case ConfigTypeNumber:
+		p.Type = "Number"
+	case ConfigTypeBoolean:
+		p.AllowedValues = []any{"true", "false"}
+	case ConfigTypeList:
+		p.Type = "CommaDelimitedList"
+	default:
+		return p, fmt.Errorf("unsupported config type %q", f.Type)
+	}
+
+	if f.Pattern != "" && p.Type != "String" {
+		return p, fmt.Errorf("a pattern can only be used with string config, but the type is %s", f.Type)
+	}
+
+	// secrets are references to a secret backend rather than the secret value,
+	// but they shouldn't be shown in the console either way.
+	if f.Secret {
+		p.NoEcho = cfn.Bool(true)
+	}
+
+	// optional fields without a default are empty if they aren't provided.
+	emptyAllowed := f.Optional && f.Default == nil
+
+	switch {
+	case f.Default != nil:
+		p.Default = f.DefaultString()
+	case emptyAllowed:
+		p.Default = ""
+		if p.Type == "Number" {
+			p.Type = "String"
+			p.AllowedPattern = cfn.String(OptionalNumberPattern)
+			p.ConstraintDescription = cfn.String("must be a number")
+		}
+	case p.Type == "String":
+		p.MinLength = cfn.Int(1)
+	}
+
+	if len(f.Enum) > 0 {
+		p.AllowedValues = nil
+		for _, v := range f.Enum {
+			p.AllowedValues = append(p.AllowedValues, formatConfigValue(v))
+		}
+	}
+	if emptyAllowed && len(p.AllowedValues) > 0 {
+		p.AllowedValues = append([]any{""}, p.AllowedValues...)
+	}
+
+	if f.Pattern != "" {
+		pattern := f.Pattern
+		if emptyAllowed {
+			pattern = "^$|" + pattern
+		}
+		p.AllowedPattern = cfn.String(pattern)
+		p.ConstraintDescription = cfn.String("must match the pattern " + f.Pattern)
+	}
+
+	return p, nil
+}
@@ -7,12 +7,20 @@ import (
 	cfn "github.com/awslabs/goformation/v7/cloudformation"
 	"github.com/awslabs/goformation/v7/cloudformation/iam"
 	"github.com/awslabs/goformation/v7/cloudformation/tags"
+	"github.com/common-fate/pdk/pkg/accessrole"
 	"github.com/common-fate/pdk/pkg/cfngen/ref"
 	"github.com/common-fate/pdk/pkg/iamp"
 	"github.com/common-fate/pdk/pkg/pythonconfig"
 )
 
-func GenerateAccessRole(pconfig pythonconfig.Config, roleName string, policy iamp.Policy) ([]byte, error) {
+// GenerateAccessRole creates a CloudFormation template for an access role. Plain IAM
+// policies can be converted to a role definition using accessrole.FromPolicy.
+func GenerateAccessRole(pconfig pythonconfig.Config, roleName string, role accessrole.Definition) ([]byte, error) {
+	err := role.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid access role %s: %w", roleName, err)
+	}
+
 	template := cfn.NewTemplate()
 
 	template.Metadata["CommonFate::AccessRoleTemplate::Version"] = "v1"
@@ -33,18 +41,59 @@ func GenerateAccessRole(pconfig pythonconfig.Config, roleName string, policy iam
 		Default:     fmt.Sprintf("cf-handler-%s-%s", pconfig.Publisher, pconfig.Name),
 	}
 
+	template.Parameters[ref.HandlerRolePath] = cfn.Parameter{
+		Type:                  "String",
+		Default:               "/",
+		AllowedPattern:        aws.String(rolePathPattern),
+		ConstraintDescription: aws.String("must begin and end with a '/'"),
+		Description:           aws.String("The path of the IAM role used by the Lambda function deployed for the provider"),
+	}
+
+	addRoleParameters(template)
+
+	providerTags, err := addTagParameters(template, pconfig.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if role.PermissionsBoundary != "" {
+		// use the permissions boundary from the role definition unless it's overridden when deploying.
+		p := template.Parameters[ref.PermissionsBoundaryArn]
+		p.Default = role.PermissionsBoundary
+		template.Parameters[ref.PermissionsBoundaryArn] = p
+	}
+
 	arpd := iamp.NewPolicy(
 		iamp.Statement{
 			Effect: iamp.Allow,
 			Action: iamp.Value{"sts:AssumeRole"},
-			Principal: map[string]iamp.Value{
+			Principal: iamp.Principal{
 				// only allow the handler function to assume the role
-				"AWS": {cfn.Join("", []string{"arn:", ref.AWSPartitionRef, ":iam::", cfn.Ref(ref.HandlerAccountID), ":role/", cfn.Ref(ref.HandlerID)})},
+				"AWS": {cfn.Join("", []string{"arn:", ref.AWSPartitionRef, ":iam::", cfn.Ref(ref.HandlerAccountID), ":role", cfn.Ref(ref.HandlerRolePath), cfn.Ref(ref.HandlerID)})},
 			},
+			Condition: role.Trust.TrustCondition(),
 		},
 	)
 
+	var policies []iam.Role_Policy
+	for _, p := range role.Policies {
+		policies = append(policies, iam.Role_Policy{
+			PolicyName:     p.PolicyName,
+			PolicyDocument: p.PolicyDocument,
+		})
+	}
+
+	roleTags := []tags.Tag{{Key: "common-fate-abac-role", Value: "access-provider-permissions-role"}}
+	for _, k := range role.SortedTags() {
+		roleTags = append(roleTags, tags.Tag{Key: k, Value: role.Tags[k]})
+	}
+	// tags in the role definition take precedence over the tags from provider.toml.
+	roleTags = withTags(roleTags, providerTags)
+
 	roleDesc := fmt.Sprintf("Common Fate %s/%s Access Role - %s", pconfig.Publisher, pconfig.Name, roleName)
+	if role.Description != "" {
+		roleDesc += ": " + role.Description
+	}
 
 	cfnRoleName := cfn.Join("", []string{cfn.Ref(ref.HandlerID), "-access-" + roleName})
 
@@ -52,18 +101,17 @@ func GenerateAccessRole(pconfig pythonconfig.Config, roleName string, policy iam
 		AssumeRolePolicyDocument: arpd,
 		RoleName:                 &cfnRoleName,
 		Description:              &roleDesc,
-		Policies: []iam.Role_Policy{
-			{
-				PolicyName:     "access-policy",
-				PolicyDocument: policy,
-			},
-		},
-		Tags: []tags.Tag{{Key: "common-fate-abac-role", Value: "access-provider-permissions-role"}},
+		Path:                     cfn.RefPtr(ref.RolePath),
+		PermissionsBoundary:      rolePermissionsBoundary(),
+		Policies:                 policies,
+		ManagedPolicyArns:        role.ManagedPolicyArns,
+		MaxSessionDuration:       role.MaxSessionDuration,
+		Tags:                     roleTags,
 	}
 
 	template.Outputs["Role"] = cfn.Output{
 		Value: cfn.GetAtt("Role", "Arn"),
 	}
 
-	return template.JSON()
+	return marshal(template)
 }
@@ -3,7 +3,9 @@ package cfngen
 import (
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/bradleyjkemp/cupaloy"
+	"github.com/common-fate/pdk/pkg/accessrole"
 	"github.com/common-fate/pdk/pkg/iamp"
 	"github.com/common-fate/pdk/pkg/pythonconfig"
 )
@@ -12,7 +14,7 @@ func TestGenerateAccessRole(t *testing.T) {
 	type args struct {
 		pconfig  pythonconfig.Config
 		roleName string
-		policy   iamp.Policy
+		role     accessrole.Definition
 	}
 	tests := []struct {
 		name string
@@ -26,17 +28,50 @@ func TestGenerateAccessRole(t *testing.T) {
 					Name:      "test-provider",
 				},
 				roleName: "cloudwatch-read",
-				policy: iamp.NewPolicy(iamp.Statement{
+				role: accessrole.FromPolicy(iamp.NewPolicy(iamp.Statement{
 					Effect:   iamp.Allow,
 					Action:   iamp.Value{"s3:ListBucket"},
 					Resource: iamp.Value{"*"},
-				}),
+				})),
+			},
+		},
+		{
+			name: "role definition",
+			args: args{
+				pconfig: pythonconfig.Config{
+					Publisher: "common-fate",
+					Name:      "test-provider",
+				},
+				roleName: "cloudwatch-read",
+				role: accessrole.Definition{
+					Description: "Read CloudWatch Logs",
+					Policies: []accessrole.Policy{
+						{
+							PolicyName: "logs-read",
+							PolicyDocument: iamp.NewPolicy(iamp.Statement{
+								Effect:   iamp.Allow,
+								Action:   iamp.Value{"logs:GetLogEvents"},
+								Resource: iamp.Value{"*"},
+							}),
+						},
+					},
+					ManagedPolicyArns:   []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+					MaxSessionDuration:  aws.Int(7200),
+					PermissionsBoundary: "arn:aws:iam::123456789012:policy/boundary",
+					Tags:                map[string]string{"team": "platform"},
+					Trust: accessrole.Trust{
+						ExternalId: "example",
+						Condition: iamp.Condition{
+							"StringEquals": {"aws:PrincipalOrgID": {"o-abcd1234"}},
+						},
+					},
+				},
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := GenerateAccessRole(tt.args.pconfig, tt.args.roleName, tt.args.policy)
+			got, err := GenerateAccessRole(tt.args.pconfig, tt.args.roleName, tt.args.role)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -45,3 +80,26 @@ func TestGenerateAccessRole(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateAccessRoleStackSet(t *testing.T) {
+	pconfig := pythonconfig.Config{
+		Publisher: "common-fate",
+		Name:      "test-provider",
+	}
+
+	roleTemplate, err := GenerateAccessRole(pconfig, "cloudwatch-read", accessrole.FromPolicy(iamp.NewPolicy(iamp.Statement{
+		Effect:   iamp.Allow,
+		Action:   iamp.Value{"s3:ListBucket"},
+		Resource: iamp.Value{"*"},
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := GenerateAccessRoleStackSet(pconfig, "cloudwatch-read", roleTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cupaloy.SnapshotT(t, got)
+}
@@ -0,0 +1,166 @@
+package cfngen
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cfn "github.com/awslabs/goformation/v7/cloudformation"
+	"github.com/common-fate/pdk/pkg/cfngen/ref"
+	"github.com/common-fate/pdk/pkg/pythonconfig"
+)
+
+// resource is a CloudFormation resource with untyped properties. It's used where
+// the goformation types can't express a property, such as passing a list parameter
+// where the type expects a []string.
+type resource struct {
+	Type       string
+	Properties map[string]any
+	Condition  string
+}
+
+func (r *resource) AWSCloudFormationType() string {
+	return r.Type
+}
+
+func (r resource) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Type       string
+		Properties map[string]any
+		Condition  string `json:",omitempty"`
+	}{
+		Type:       r.Type,
+		Properties: r.Properties,
+		Condition:  r.Condition,
+	})
+}
+
+// GenerateAccessRoleStackSet wraps an access role template generated by GenerateAccessRole
+// in a service-managed StackSet, so that the role can be deployed to every account in
+// one or more AWS Organizations OUs. The template should be deployed to the
+// organisation management account or a delegated administrator account.
+func GenerateAccessRoleStackSet(pconfig pythonconfig.Config, roleName string, roleTemplate []byte) ([]byte, error) {
+	template := cfn.NewTemplate()
+
+	template.Metadata["CommonFate::AccessRoleStackSetTemplate::Version"] = "v1"
+	template.Metadata["CommonFate::Provider::Publisher"] = pconfig.Publisher
+	template.Metadata["CommonFate::Provider::Name"] = pconfig.Name
+
+	template.Parameters[ref.OrganizationalUnitIDs] = cfn.Parameter{
+		Type:        "CommaDelimitedList",
+		Description: aws.String("The IDs of the AWS Organizations OUs to deploy the access role to, e.g. ou-abcd-12345678"),
+	}
+
+	template.Parameters[ref.Regions] = cfn.Parameter{
+		Type:        "CommaDelimitedList",
+		Description: aws.String("The regions to deploy the access role to. IAM roles are global, so this is usually a single region"),
+	}
+
+	template.Parameters[ref.CallAs] = cfn.Parameter{
+		Type:          "String",
+		Default:       "SELF",
+		AllowedValues: []any{"SELF", "DELEGATED_ADMIN"},
+		Description:   aws.String("Use DELEGATED_ADMIN if this stack is deployed to a delegated administrator account rather than the organisation management account"),
+	}
+
+	handlerAccountIDDesc := fmt.Sprintf("The ID of the AWS account that the %s/%s Provider will be deployed to", pconfig.Publisher, pconfig.Name)
+	template.Parameters[ref.HandlerAccountID] = cfn.Parameter{
+		Type:        "String",
+		MinLength:   aws.Int(1),
+		Description: &handlerAccountIDDesc,
+	}
+
+	template.Parameters[ref.HandlerID] = cfn.Parameter{
+		Type:        "String",
+		MinLength:   aws.Int(1),
+		Description: aws.String("The name of the Lambda function deployed for the provider"),
+		Default:     fmt.Sprintf("cf-handler-%s-%s", pconfig.Publisher, pconfig.Name),
+	}
+
+	template.Parameters[ref.HandlerRolePath] = cfn.Parameter{
+		Type:                  "String",
+		Default:               "/",
+		AllowedPattern:        aws.String(rolePathPattern),
+		ConstraintDescription: aws.String("must begin and end with a '/'"),
+		Description:           aws.String("The path of the IAM role used by the Lambda function deployed for the provider"),
+	}
+
+	addRoleParameters(template)
+
+	providerTags, err := addTagParameters(template, pconfig.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	var role struct {
+		Parameters map[string]cfn.Parameter
+	}
+	err = json.Unmarshal(roleTemplate, &role)
+	if err != nil {
+		return nil, fmt.Errorf("parsing access role template: %w", err)
+	}
+
+	// pass the parameters through to each stack instance.
+	var params []map[string]any
+	passthrough := []string{ref.HandlerAccountID, ref.HandlerID, ref.HandlerRolePath, ref.PermissionsBoundaryArn, ref.RolePath}
+	for _, k := range sortedKeys(pconfig.Tags) {
+		passthrough = append(passthrough, TagParameter(k))
+	}
+	for _, p := range passthrough {
+		// keep the defaults from the role template, such as a permissions boundary from the role definition.
+		if rp, ok := role.Parameters[p]; ok && rp.Default != nil {
+			wp := template.Parameters[p]
+			wp.Default = rp.Default
+			template.Parameters[p] = wp
+		}
+		params = append(params, map[string]any{
+			"ParameterKey":   p,
+			"ParameterValue": cfn.Ref(p),
+		})
+	}
+
+	roleDesc := fmt.Sprintf("Common Fate %s/%s Access Role - %s", pconfig.Publisher, pconfig.Name, roleName)
+
+	stackSet := &resource{
+		Type: "AWS::CloudFormation::StackSet",
+		Properties: map[string]any{
+			"StackSetName":    cfn.Join("", []string{cfn.Ref(ref.HandlerID), "-access-" + roleName}),
+			"Description":     roleDesc,
+			"PermissionModel": "SERVICE_MANAGED",
+			"CallAs":          cfn.Ref(ref.CallAs),
+			"Capabilities":    []string{"CAPABILITY_NAMED_IAM"},
+			"AutoDeployment": map[string]any{
+				// deploy the role to accounts which are added to the OUs in future.
+				"Enabled":                      true,
+				"RetainStacksOnAccountRemoval": false,
+			},
+			"ManagedExecution": map[string]any{
+				"Active": true,
+			},
+			"OperationPreferences": map[string]any{
+				"FailureTolerancePercentage": 10,
+				"MaxConcurrentPercentage":    25,
+				"RegionConcurrencyType":      "PARALLEL",
+			},
+			"Parameters":   params,
+			"TemplateBody": string(roleTemplate),
+			"StackInstancesGroup": []map[string]any{
+				{
+					"DeploymentTargets": map[string]any{
+						"OrganizationalUnitIds": cfn.Ref(ref.OrganizationalUnitIDs),
+					},
+					"Regions": cfn.Ref(ref.Regions),
+				},
+			},
+		},
+	}
+
+	addTagsProperty(stackSet, providerTags)
+	template.Resources["StackSet"] = stackSet
+
+	template.Outputs["StackSetId"] = cfn.Output{
+		Value: cfn.Ref("StackSet"),
+	}
+
+	return marshal(template)
+}
@@ -6,12 +6,14 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	cfn "github.com/awslabs/goformation/v7/cloudformation"
+	"github.com/awslabs/goformation/v7/cloudformation/cloudformation"
 	"github.com/awslabs/goformation/v7/cloudformation/iam"
 	"github.com/awslabs/goformation/v7/cloudformation/lambda"
 	"github.com/awslabs/goformation/v7/cloudformation/tags"
 	"github.com/common-fate/pdk/pkg/cfngen/ref"
 	"github.com/common-fate/pdk/pkg/iamp"
 	"github.com/common-fate/pdk/pkg/pythonconfig"
+	"github.com/common-fate/pdk/pkg/secretstore"
 	"github.com/common-fate/provider-registry-sdk-go/pkg/providerregistrysdk"
 )
 
@@ -43,13 +45,43 @@ func ConvertToPascalCase(s string) string {
 }
 
 var reservedParameters = map[string]bool{
-	"AssetPath":              true,
-	"BootstrapBucketName":    true,
-	"CommonFateAWSAccountID": true,
-	"HandlerID":              true,
+	"AssetPath":                  true,
+	"BootstrapBucketName":        true,
+	"CommonFateAWSAccountID":     true,
+	"HandlerID":                  true,
+	"KmsKeyArn":                  true,
+	"SecretBackend":              true,
+	"PermissionsBoundaryArn":     true,
+	"RolePath":                   true,
+	"LogRetentionDays":           true,
+	"LogKmsKeyArn":               true,
+	"AlarmTopicArn":              true,
+	"ResourceLoadSchedule":       true,
+	"TracingEnabled":             true,
+	"OtelLayerArn":               true,
+	"ResourceLoadDestinationArn": true,
 }
 
+// isReservedParameter returns true if the parameter name is used by the template. Names are
+// compared case-insensitively, as config keys such as 'handler_id' become 'HandlerId'.
+func isReservedParameter(name string) bool {
+	for p := range reservedParameters {
+		if strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate creates the CloudFormation template for a Provider's handler from a registry schema.
+// Registry schemas don't include config constraints, so use GenerateFromSchema where they are available.
 func Generate(pconfig pythonconfig.Config, schema providerregistrysdk.Schema) ([]byte, error) {
+	return GenerateFromSchema(pconfig, FromRegistrySchema(schema))
+}
+
+// GenerateFromSchema creates the CloudFormation template for a Provider's handler,
+// including constraints on the config parameters.
+func GenerateFromSchema(pconfig pythonconfig.Config, schema Schema) ([]byte, error) {
 	template := cfn.NewTemplate()
 
 	template.Metadata["CommonFate::HandlerTemplate::Version"] = "v1"
@@ -78,15 +110,35 @@ func Generate(pconfig pythonconfig.Config, schema providerregistrysdk.Schema) ([
 		Description: cfn.String("The name of invoke handler lambda function"),
 	}
 
+	template.Parameters[ref.KmsKeyArn] = cfn.Parameter{
+		Type:        "String",
+		Default:     "",
+		Description: cfn.String("(Optional) The ARN of a customer-managed KMS key used to encrypt the Lambda environment variables and to decrypt SecureString SSM parameters"),
+	}
+
+	addRoleParameters(template)
+
+	providerTags, err := addTagParameters(template, pconfig.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	addLogGroup(template, providerTags)
+	addAlarms(template, providerTags)
+
+	// the KMS key is optional - if it isn't provided we fall back to the AWS-managed key.
+	template.Conditions[ref.HasKmsKeyArn] = cfn.Not([]string{cfn.Equals(cfn.Ref(ref.KmsKeyArn), "")})
+
 	lambdaFunction := &lambda.Function{
 		Runtime:      cfn.String("python3.9"),
 		FunctionName: cfn.RefPtr("HandlerID"),
-		Timeout:      cfn.Int(600),
+		Timeout:      cfn.Int(LambdaTimeout),
 		Role:         cfn.GetAtt(ref.LambdaRole, "Arn"),
 		Handler:      cfn.String("provider.runtime.aws_lambda_entrypoint.lambda_handler"),
-		Tags: []tags.Tag{
+		KmsKeyArn:    cfn.IfPtr(ref.HasKmsKeyArn, cfn.Ref(ref.KmsKeyArn), ref.AWSNoValueRef),
+		Tags: withTags([]tags.Tag{
 			{Key: "common-fate-abac-role", Value: "access-provider"},
-		},
+		}, providerTags),
 		Environment: &lambda.Function_Environment{
 			Variables: map[string]string{},
 		},
@@ -96,79 +148,132 @@ func Generate(pconfig pythonconfig.Config, schema providerregistrysdk.Schema) ([
 		},
 		AWSCloudFormationDependsOn: []string{
 			ref.LambdaRole,
+			// create the log group first, otherwise Lambda creates it without a retention period.
+			ref.LogGroup,
 		},
 	}
 
+	addTracing(template, lambdaFunction)
+
 	lambdaArn := cfn.GetAtt(ref.LambdaFunction, "Arn")
 
-	arpd := map[string]any{
-		"Version": "2012-10-17",
-		"Statement": []map[string]any{
-			{
-				"Action": "sts:AssumeRole",
-				"Effect": "Allow",
-				"Principal": map[string]any{
-					"Service": "lambda.amazonaws.com",
-				},
-			},
+	arpd := iamp.NewPolicy(
+		iamp.Statement{
+			Effect:    iamp.Allow,
+			Action:    iamp.Value{"sts:AssumeRole"},
+			Principal: iamp.Principal{"Service": {"lambda.amazonaws.com"}},
 		},
-	}
-
-	// scope down the SSM policy, so that the provider is only allowed to read from
-	// paths which include the publisher and name of the provider.
+	)
 
-	ssmPath := fmt.Sprintf("/common-fate/provider/%s/%s/*", pconfig.Publisher, pconfig.Name)
+	secretBackend, err := secretstore.Parse(pconfig.SecretBackend)
+	if err != nil {
+		return nil, err
+	}
 
 	hasSecrets := false
-	if schema.Config != nil {
-		for k, v := range *schema.Config {
-			// prevent users from submitting providers that overwrite our built-in
-			// parameter names as it could cause unexpected behaviour.
-			if reserved, ok := reservedParameters[k]; ok && reserved {
-				return nil, fmt.Errorf("%s is a reserved parameter name", k)
-			}
-
-			cfnKey := ConvertToPascalCase(k)
-			envPrefix := "PROVIDER_CONFIG_"
+	for k, v := range schema.Config {
+		cfnKey := ConvertToPascalCase(k)
+		envPrefix := "PROVIDER_CONFIG_"
+
+		if v.Secret {
+			cfnKey += "Secret"
+			envPrefix = "PROVIDER_SECRET_"
+			hasSecrets = true
+		}
 
-			if v.Secret != nil && *v.Secret {
-				cfnKey += "Secret"
-				envPrefix = "PROVIDER_SECRET_"
-				hasSecrets = true
-			}
+		// prevent users from submitting providers that overwrite our built-in
+		// parameter names as it could cause unexpected behaviour.
+		if isReservedParameter(cfnKey) {
+			return nil, fmt.Errorf("config %s uses the reserved parameter name %s", k, cfnKey)
+		}
+		if _, ok := template.Parameters[cfnKey]; ok {
+			return nil, fmt.Errorf("config %s would overwrite the %s parameter", k, cfnKey)
+		}
 
-			template.Parameters[cfnKey] = cfn.Parameter{
-				Type:        "String",
-				Description: v.Description,
-				MinLength:   cfn.Int(1),
-			}
+		param, err := configParameter(v)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: %w", k, err)
+		}
+		template.Parameters[cfnKey] = param
 
-			envVar := envPrefix + strings.ToUpper(k)
+		envVar := envPrefix + strings.ToUpper(k)
 
-			lambdaFunction.Environment.Variables[envVar] = cfn.Ref(cfnKey)
+		value := cfn.Ref(cfnKey)
+		if param.Type == "CommaDelimitedList" {
+			// environment variables are strings, so lists are passed as comma-separated values.
+			value = cfn.Join(",", cfn.Ref(cfnKey))
 		}
+		lambdaFunction.Environment.Variables[envVar] = value
 	}
 
-	policyStatements := []map[string]any{
-		{
-			"Action":    "sts:AssumeRole",
-			"Effect":    "Allow",
-			"Resource":  "*",
-			"Condition": map[string]any{"StringEquals": map[string]string{"iam:ResourceTag/common-fate-abac-role": "access-provider-permissions-role"}},
-		}}
+	inlinePolicy := iamp.NewPolicy(
+		iamp.Statement{
+			Effect:    iamp.Allow,
+			Action:    iamp.Value{"sts:AssumeRole"},
+			Resource:  iamp.Value{"*"},
+			Condition: iamp.Condition{"StringEquals": {"iam:ResourceTag/common-fate-abac-role": {"access-provider-permissions-role"}}},
+		},
+	)
 
-	// only give SSM permissions if the Provider actually needs to read secrets.
+	// only give secret permissions if the Provider actually needs to read secrets.
 	if hasSecrets {
-		policyStatements = append(policyStatements, map[string]any{
-			"Action":   "ssm:GetParameter",
-			"Effect":   "Allow",
-			"Resource": cfn.Join("", []string{"arn:", ref.AWSPartitionRef, ":ssm:", ref.AWSRegionRef, ":", ref.AWSAccountIDRef, ":parameter", ssmPath}),
-		})
+		var allowedValues []any
+		for _, b := range secretstore.Backends {
+			allowedValues = append(allowedValues, string(b))
+		}
+
+		// the default backend comes from provider.toml, but can be overridden per deployment.
+		template.Parameters[ref.SecretBackend] = cfn.Parameter{
+			Type:          "String",
+			Default:       string(secretBackend),
+			AllowedValues: allowedValues,
+			Description:   cfn.String("The backend that the Provider reads secrets from"),
+		}
+
+		template.Conditions[ref.UseSecretsManager] = cfn.Equals(cfn.Ref(ref.SecretBackend), string(secretstore.SecretsManager))
+		template.Conditions[ref.UseSSM] = cfn.Equals(cfn.Ref(ref.SecretBackend), string(secretstore.SSM))
+
+		lambdaFunction.Environment.Variables["PROVIDER_SECRET_BACKEND"] = cfn.Ref(ref.SecretBackend)
+
+		// scope down the secret policies, so that the provider is only allowed to read from
+		// paths which include the publisher and name of the provider.
+		ssmPath := secretstore.SSM.Prefix(pconfig.Publisher, pconfig.Name) + "*"
+		secretsManagerPath := secretstore.SecretsManager.Prefix(pconfig.Publisher, pconfig.Name) + "*"
+
+		template.Resources[ref.LambdaSSMPolicy] = &iam.Policy{
+			PolicyName: "handler-ssm-policy",
+			Roles:      []string{cfn.Ref(ref.LambdaRole)},
+			PolicyDocument: iamp.NewPolicy(iamp.Statement{
+				Effect:   iamp.Allow,
+				Action:   iamp.Value{"ssm:GetParameter"},
+				Resource: iamp.Value{cfn.Join("", []string{"arn:", ref.AWSPartitionRef, ":ssm:", ref.AWSRegionRef, ":", ref.AWSAccountIDRef, ":parameter", ssmPath})},
+			}),
+			AWSCloudFormationCondition: ref.UseSSM,
+		}
+
+		template.Resources[ref.LambdaSecretsManagerPolicy] = &iam.Policy{
+			PolicyName: "handler-secretsmanager-policy",
+			Roles:      []string{cfn.Ref(ref.LambdaRole)},
+			PolicyDocument: iamp.NewPolicy(iamp.Statement{
+				Effect:   iamp.Allow,
+				Action:   iamp.Value{"secretsmanager:GetSecretValue"},
+				Resource: iamp.Value{cfn.Join("", []string{"arn:", ref.AWSPartitionRef, ":secretsmanager:", ref.AWSRegionRef, ":", ref.AWSAccountIDRef, ":secret:", secretsManagerPath})},
+			}),
+			AWSCloudFormationCondition: ref.UseSecretsManager,
+		}
 	}
 
-	inlinePolicy := map[string]any{
-		"Version":   "2012-10-17",
-		"Statement": policyStatements,
+	// allow the customer-managed KMS key to be used to decrypt the environment
+	// variables and any SecureString SSM parameters, if it has been provided.
+	template.Resources[ref.LambdaKmsPolicy] = &iam.Policy{
+		PolicyName: "handler-kms-policy",
+		Roles:      []string{cfn.Ref(ref.LambdaRole)},
+		PolicyDocument: iamp.NewPolicy(iamp.Statement{
+			Effect:   iamp.Allow,
+			Action:   iamp.Value{"kms:Decrypt"},
+			Resource: iamp.Value{cfn.Ref(ref.KmsKeyArn)},
+		}),
+		AWSCloudFormationCondition: ref.HasKmsKeyArn,
 	}
 
 	template.Resources[ref.LambdaRole] = &iam.Role{
@@ -180,7 +285,10 @@ func Generate(pconfig pythonconfig.Config, schema providerregistrysdk.Schema) ([
 				PolicyDocument: inlinePolicy,
 			},
 		},
-		RoleName: cfn.RefPtr("HandlerID"),
+		RoleName:            cfn.RefPtr("HandlerID"),
+		Path:                cfn.RefPtr(ref.RolePath),
+		PermissionsBoundary: rolePermissionsBoundary(),
+		Tags:                providerTags,
 	}
 
 	template.Resources[ref.LambdaFunction] = lambdaFunction
@@ -189,7 +297,7 @@ func Generate(pconfig pythonconfig.Config, schema providerregistrysdk.Schema) ([
 		iamp.Statement{
 			Effect: iamp.Allow,
 			Action: iamp.Value{"sts:AssumeRole"},
-			Principal: map[string]iamp.Value{
+			Principal: iamp.Principal{
 				"AWS": {cfn.Join("", []string{"arn:", ref.AWSPartitionRef, ":iam::", cfn.Ref(ref.CommonFateAWSAccountID), ":root"})},
 			},
 			// TODO: add external ID condition
@@ -237,14 +345,66 @@ func Generate(pconfig pythonconfig.Config, schema providerregistrysdk.Schema) ([
 		AssumeRolePolicyDocument: invokeRoleARPD,
 		RoleName:                 &invokeRoleName,
 		Description:              &invokeRoleDescription,
+		Path:                     cfn.RefPtr(ref.RolePath),
+		PermissionsBoundary:      rolePermissionsBoundary(),
 		Policies: []iam.Role_Policy{
 			{
 				PolicyName:     "invoke-policy",
 				PolicyDocument: invokePolicy,
 			},
 		},
-		Tags: []tags.Tag{{Key: "common-fate-abac-role", Value: "handler-invoke"}},
+		Tags: withTags([]tags.Tag{{Key: "common-fate-abac-role", Value: "handler-invoke"}}, providerTags),
+	}
+
+	err = addResourceLoading(template, schema)
+	if err != nil {
+		return nil, err
 	}
 
-	return template.JSON()
+	addPolicyDependencies(template, lambdaFunction)
+
+	addInterface(template, schema, pconfig.Tags)
+
+	addOutput(template, ref.LambdaFunctionArnOutput, lambdaArn, "The ARN of the Lambda function")
+	addOutput(template, ref.LambdaRoleArnOutput, cfn.GetAtt(ref.LambdaRole, "Arn"), "The ARN of the IAM role used by the Lambda function")
+	addOutput(template, ref.LambdaInvocationRoleArnOutput, cfn.GetAtt(ref.LambdaInvocationRole, "Arn"), "The ARN of the IAM role which Common Fate assumes to invoke the Lambda function")
+	addOutput(template, ref.LogGroupNameOutput, cfn.Ref(ref.LogGroup), "The name of the log group of the Lambda function")
+
+	return marshal(template)
+}
+
+// addPolicyDependencies makes the Lambda function depend on the conditional policies of its role,
+// so that the policies have been attached before the function is first invoked.
+//
+// DependsOn can't refer to a resource which may not be created, so the function depends on a
+// WaitConditionHandle instead. The handle refers to each policy with Fn::If in its metadata, which
+// adds a dependency on the policy only when its condition is true.
+func addPolicyDependencies(template *cfn.Template, lambdaFunction *lambda.Function) {
+	metadata := map[string]any{}
+	for name, r := range template.Resources {
+		policy, ok := r.(*iam.Policy)
+		if !ok || policy.AWSCloudFormationCondition == "" {
+			continue
+		}
+		for _, role := range policy.Roles {
+			if role == cfn.Ref(ref.LambdaRole) {
+				metadata[name] = cfn.If(policy.AWSCloudFormationCondition, cfn.Ref(name), "")
+			}
+		}
+	}
+
+	template.Resources[ref.LambdaPolicies] = &cloudformation.WaitConditionHandle{
+		AWSCloudFormationMetadata: metadata,
+	}
+	lambdaFunction.AWSCloudFormationDependsOn = append(lambdaFunction.AWSCloudFormationDependsOn, ref.LambdaPolicies)
+}
+
+// addOutput adds an output which is exported as '<stack name>-<output name>',
+// so that it can be imported by other stacks.
+func addOutput(template *cfn.Template, name string, value string, description string) {
+	template.Outputs[name] = cfn.Output{
+		Value:       value,
+		Description: cfn.String(description),
+		Export:      &cfn.Export{Name: cfn.Join("", []string{ref.AWSStackNameRef, "-" + name})},
+	}
 }
@@ -2,6 +2,8 @@ package cfngen
 
 import (
 	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/awslabs/goformation/v7/cloudformation"
@@ -29,7 +31,7 @@ func TestConvertToPascalCase(t *testing.T) {
 func TestGenerate(t *testing.T) {
 	testcases := []struct {
 		name         string
-		give         providerregistrysdk.Schema
+		give         Schema
 		giveProvider pythonconfig.Config
 	}{
 		{
@@ -38,8 +40,8 @@ func TestGenerate(t *testing.T) {
 				Name:      "test",
 				Publisher: "example-org",
 			},
-			give: providerregistrysdk.Schema{
-				Config: &map[string]providerregistrysdk.Config{
+			give: Schema{
+				Config: map[string]ConfigField{
 					"api_url": {
 						Type:        "string",
 						Description: cloudformation.String("some usage"),
@@ -47,7 +49,24 @@ func TestGenerate(t *testing.T) {
 					"api_key": {
 						Type:        "string",
 						Description: cloudformation.String("API key"),
-						Secret:      cloudformation.Bool(true),
+						Secret:      true,
+					},
+				},
+			},
+		},
+		{
+			name: "secrets manager",
+			giveProvider: pythonconfig.Config{
+				Name:          "test",
+				Publisher:     "example-org",
+				SecretBackend: "secretsmanager",
+			},
+			give: Schema{
+				Config: map[string]ConfigField{
+					"api_key": {
+						Type:        "string",
+						Description: cloudformation.String("API key"),
+						Secret:      true,
 					},
 				},
 			},
@@ -58,19 +77,89 @@ func TestGenerate(t *testing.T) {
 				Name:      "test",
 				Publisher: "example-org",
 			},
-			give: providerregistrysdk.Schema{
-				Config: &map[string]providerregistrysdk.Config{
+			give: Schema{
+				Config: map[string]ConfigField{
 					"config_value": {
 						Type: "string",
 					},
 				},
 			},
 		},
+		{
+			name: "tags",
+			giveProvider: pythonconfig.Config{
+				Name:      "test",
+				Publisher: "example-org",
+				Tags:      map[string]string{"cost-centre": "1234", "owner": ""},
+			},
+			give: Schema{
+				Config: map[string]ConfigField{
+					"api_url": {Type: "string"},
+				},
+			},
+		},
+		{
+			name: "config constraints",
+			giveProvider: pythonconfig.Config{
+				Name:      "test",
+				Publisher: "example-org",
+			},
+			give: Schema{
+				Config: map[string]ConfigField{
+					"region": {
+						Type:    "string",
+						Default: "us-east-1",
+						Enum:    []any{"us-east-1", "ap-southeast-2"},
+					},
+					"org_id": {
+						Type:     "string",
+						Optional: true,
+						Pattern:  "^o-[a-z0-9]+$",
+					},
+					"page_size": {
+						Type:    "number",
+						Default: float64(100),
+					},
+					"max_retries": {
+						Type:     "number",
+						Optional: true,
+					},
+					"dry_run": {
+						Type:    "boolean",
+						Default: false,
+					},
+					"group_ids": {
+						Type:     "list",
+						Optional: true,
+					},
+				},
+			},
+		},
+		{
+			name: "resource loaders",
+			giveProvider: pythonconfig.Config{
+				Name:      "test",
+				Publisher: "example-org",
+			},
+			give: Schema{
+				Schema: providerregistrysdk.Schema{
+					Resources: &providerregistrysdk.Resources{
+						Loaders: map[string]providerregistrysdk.Loader{
+							"fetch_groups":      {Title: "Groups"},
+							"fetch-permissions": {},
+						},
+					},
+				},
+				Config: map[string]ConfigField{
+					"api_url": {Type: "string"},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
-			got, err := Generate(tc.giveProvider, tc.give)
+			got, err := GenerateFromSchema(tc.giveProvider, tc.give)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -90,3 +179,199 @@ func TestGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateRegistrySchema(t *testing.T) {
+	pconfig := pythonconfig.Config{Name: "test", Publisher: "example-org"}
+	schema := providerregistrysdk.Schema{
+		Config: &map[string]providerregistrysdk.Config{
+			"api_key": {Type: "string", Secret: cloudformation.Bool(true)},
+		},
+	}
+	got, err := Generate(pconfig, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := GenerateFromSchema(pconfig, FromRegistrySchema(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("want template\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestGenerateKmsKeyArn(t *testing.T) {
+	got, err := GenerateFromSchema(pythonconfig.Config{Name: "test", Publisher: "example-org"}, Schema{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tmpl struct {
+		Conditions map[string]any `json:"Conditions"`
+		Parameters map[string]struct {
+			Default *string `json:"Default"`
+		} `json:"Parameters"`
+		Resources map[string]struct {
+			Properties map[string]any `json:"Properties"`
+		} `json:"Resources"`
+	}
+	err = json.Unmarshal(got, &tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	param, ok := tmpl.Parameters["KmsKeyArn"]
+	if !ok {
+		t.Fatal("KmsKeyArn parameter not found")
+	}
+	if param.Default == nil || *param.Default != "" {
+		t.Errorf("KmsKeyArn parameter should default to an empty string")
+	}
+
+	if _, ok := tmpl.Conditions["HasKmsKeyArn"]; !ok {
+		t.Errorf("HasKmsKeyArn condition not found")
+	}
+
+	if _, ok := tmpl.Resources["LambdaFunction"].Properties["KmsKeyArn"]; !ok {
+		t.Errorf("LambdaFunction should set KmsKeyArn")
+	}
+}
+
+func TestGeneratePolicyDependencies(t *testing.T) {
+	got, err := GenerateFromSchema(pythonconfig.Config{Name: "test", Publisher: "example-org"}, Schema{Config: map[string]ConfigField{"api_key": {Type: "string", Secret: true}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tmpl struct {
+		Resources map[string]struct {
+			Type      string         `json:"Type"`
+			Condition string         `json:"Condition"`
+			DependsOn []string       `json:"DependsOn"`
+			Metadata  map[string]any `json:"Metadata"`
+		} `json:"Resources"`
+	}
+	err = json.Unmarshal(got, &tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(tmpl.Resources["LambdaFunction"].DependsOn, []string{"LambdaRole", "LogGroup", "LambdaPolicies"}) {
+		t.Errorf("unexpected LambdaFunction DependsOn %v", tmpl.Resources["LambdaFunction"].DependsOn)
+	}
+
+	// every conditional policy must be referred to by the wait condition handle, with its condition.
+	handle := tmpl.Resources["LambdaPolicies"].Metadata
+	for name, r := range tmpl.Resources {
+		if r.Type != "AWS::IAM::Policy" || r.Condition == "" {
+			continue
+		}
+		want := map[string]any{"Fn::If": []any{r.Condition, map[string]any{"Ref": name}, ""}}
+		if !reflect.DeepEqual(handle[name], want) {
+			t.Errorf("LambdaPolicies should refer to %s with %v, got %v", name, want, handle[name])
+		}
+	}
+	if len(handle) != 4 {
+		t.Errorf("expected LambdaPolicies to refer to 4 policies, got %v", handle)
+	}
+}
+
+func TestGenerateReservedParameters(t *testing.T) {
+	pconfig := pythonconfig.Config{Name: "test", Publisher: "example-org", Tags: map[string]string{"owner": ""}}
+
+	tests := []struct {
+		name   string
+		config map[string]ConfigField
+	}{
+		{name: "kms key", config: map[string]ConfigField{"kms_key_arn": {Type: "string"}}},
+		{name: "log retention", config: map[string]ConfigField{"log_retention_days": {Type: "string"}}},
+		{name: "tracing", config: map[string]ConfigField{"tracing_enabled": {Type: "string"}}},
+		{name: "different case", config: map[string]ConfigField{"handler_id": {Type: "string"}}},
+		{name: "tag", config: map[string]ConfigField{"tag_owner": {Type: "string"}}},
+		{name: "secret", config: map[string]ConfigField{"api_key": {Type: "string", Secret: true}, "api_key_secret": {Type: "string"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GenerateFromSchema(pconfig, Schema{Config: tt.config})
+			if err == nil {
+				t.Error("expected an error for a config key which collides with a template parameter")
+			}
+		})
+	}
+
+	// every parameter of the template must be reserved, so that config can't overwrite it.
+	got, err := GenerateFromSchema(pconfig, Schema{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tmpl struct {
+		Parameters map[string]any `json:"Parameters"`
+	}
+	err = json.Unmarshal(got, &tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for p := range tmpl.Parameters {
+		if !reservedParameters[p] && !strings.HasPrefix(p, "Tag") {
+			t.Errorf("parameter %s should be in reservedParameters", p)
+		}
+	}
+}
+
+func TestGenerateParameterGroups(t *testing.T) {
+	schema := Schema{
+		Config: map[string]ConfigField{
+			"api_url": {Type: "string", Description: cloudformation.String("The API URL")},
+			"api_key": {Type: "string", Secret: true},
+			"org_id":  {Type: "string"},
+		},
+	}
+	got, err := GenerateFromSchema(pythonconfig.Config{Name: "test", Publisher: "example-org"}, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tmpl struct {
+		Metadata struct {
+			Interface struct {
+				ParameterGroups []struct {
+					Label      map[string]string
+					Parameters []string
+				}
+				ParameterLabels map[string]map[string]string
+			} `json:"AWS::CloudFormation::Interface"`
+		}
+		Parameters map[string]any
+	}
+	err = json.Unmarshal(got, &tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups := tmpl.Metadata.Interface.ParameterGroups
+	if len(groups) < 2 {
+		t.Fatalf("want at least 2 parameter groups, got %+v", groups)
+	}
+	if want := []string{"ApiUrl", "OrgId"}; !reflect.DeepEqual(groups[0].Parameters, want) {
+		t.Errorf("want config parameters %v first, got %v", want, groups[0].Parameters)
+	}
+	if want := []string{"ApiKeySecret"}; !reflect.DeepEqual(groups[1].Parameters, want) {
+		t.Errorf("want secret parameters %v second, got %v", want, groups[1].Parameters)
+	}
+
+	// every template parameter should be in a named group, rather than falling through to 'Other'.
+	grouped := 0
+	for _, g := range groups {
+		if g.Label["default"] == "Other" {
+			t.Errorf("parameters %v aren't in a group", g.Parameters)
+		}
+		grouped += len(g.Parameters)
+	}
+	if grouped != len(tmpl.Parameters) {
+		t.Errorf("want %d grouped parameters, got %d", len(tmpl.Parameters), grouped)
+	}
+
+	if got := tmpl.Metadata.Interface.ParameterLabels["ApiUrl"]["default"]; got != "The API URL" {
+		t.Errorf("want the schema description as the label, got %q", got)
+	}
+}
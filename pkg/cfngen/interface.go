@@ -0,0 +1,108 @@
+package cfngen
+
+import (
+	"sort"
+
+	cfn "github.com/awslabs/goformation/v7/cloudformation"
+	"github.com/common-fate/pdk/pkg/cfngen/ref"
+)
+
+// parameterGroup is a group of parameters shown together in the CloudFormation console.
+type parameterGroup struct {
+	Label      string
+	Parameters []string
+}
+
+// infrastructureGroups group the parameters which Common Fate and the template define,
+// in the order they're shown in the console after the Provider's config.
+var infrastructureGroups = []parameterGroup{
+	{
+		Label:      "Common Fate",
+		Parameters: []string{ref.CommonFateAWSAccountID, ref.HandlerID, ref.BootstrapBucketName, ref.AssetPath},
+	},
+	{
+		Label:      "Security",
+		Parameters: []string{ref.SecretBackend, ref.KmsKeyArn, ref.PermissionsBoundaryArn, ref.RolePath},
+	},
+	{
+		Label:      "Logging and monitoring",
+		Parameters: []string{ref.LogRetentionDays, ref.LogKmsKeyArn, ref.AlarmTopicArn, ref.TracingEnabled, ref.OtelLayerArn},
+	},
+	{
+		Label:      "Resource loading",
+		Parameters: []string{ref.ResourceLoadSchedule, ref.ResourceLoadDestinationArn},
+	},
+}
+
+// addInterface adds AWS::CloudFormation::Interface metadata, which groups and labels the
+// parameters in the CloudFormation console. The Provider's config and secrets are shown first,
+// using the schema descriptions as labels. It should be called after all parameters are added.
+func addInterface(template *cfn.Template, schema Schema, providerTags map[string]string) {
+	var config, secrets []string
+	labels := map[string]any{}
+	for k, v := range schema.Config {
+		name := ConvertToPascalCase(k)
+		if v.Secret {
+			name += "Secret"
+			secrets = append(secrets, name)
+		} else {
+			config = append(config, name)
+		}
+		if v.Description != nil && *v.Description != "" {
+			labels[name] = map[string]string{"default": *v.Description}
+		}
+	}
+	sort.Strings(config)
+	sort.Strings(secrets)
+
+	var tagParams []string
+	for _, k := range sortedKeys(providerTags) {
+		tagParams = append(tagParams, TagParameter(k))
+	}
+
+	groups := []parameterGroup{
+		{Label: "Provider configuration", Parameters: config},
+		{Label: "Provider secrets", Parameters: secrets},
+	}
+	groups = append(groups, infrastructureGroups...)
+	groups = append(groups, parameterGroup{Label: "Tags", Parameters: tagParams})
+
+	grouped := map[string]bool{}
+	var parameterGroups []any
+	for _, g := range groups {
+		var params []string
+		for _, p := range g.Parameters {
+			// some parameters, such as the secret backend, are only added to some templates.
+			if _, ok := template.Parameters[p]; ok {
+				params = append(params, p)
+				grouped[p] = true
+			}
+		}
+		if len(params) == 0 {
+			continue
+		}
+		parameterGroups = append(parameterGroups, map[string]any{
+			"Label":      map[string]string{"default": g.Label},
+			"Parameters": params,
+		})
+	}
+
+	var other []string
+	for p := range template.Parameters {
+		if !grouped[p] {
+			other = append(other, p)
+		}
+	}
+	if len(other) > 0 {
+		sort.Strings(other)
+		parameterGroups = append(parameterGroups, map[string]any{
+			"Label":      map[string]string{"default": "Other"},
+			"Parameters": other,
+		})
+	}
+
+	template.Metadata["AWS::CloudFormation::Interface"] = map[string]any{
+		"ParameterGroups": parameterGroups,
+		"ParameterLabels": labels,
+	}
+}
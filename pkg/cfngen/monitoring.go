@@ -0,0 +1,131 @@
+package cfngen
+
+import (
+	"strconv"
+
+	cfn "github.com/awslabs/goformation/v7/cloudformation"
+	"github.com/awslabs/goformation/v7/cloudformation/tags"
+	"github.com/common-fate/pdk/pkg/cfngen/ref"
+)
+
+// LambdaTimeout is the timeout of the Provider's Lambda function, in seconds.
+const LambdaTimeout = 600
+
+// DefaultLogRetentionDays is the default number of days to keep the Lambda function logs for.
+const DefaultLogRetentionDays = 30
+
+// LogRetentionDays are the retention periods supported by CloudWatch Logs.
+var LogRetentionDays = []int{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1096, 1827, 2192, 2557, 2922, 3288, 3653}
+
+// DurationAlarmThreshold is the Lambda duration, in milliseconds, above which the
+// duration alarm is triggered. It's 80% of the timeout so that the alarm fires before
+// invocations start timing out.
+const DurationAlarmThreshold = LambdaTimeout * 1000 * 8 / 10
+
+// Alarm is a CloudWatch alarm on a Lambda function metric.
+type Alarm struct {
+	// MetricName is the Lambda metric, which is also used as the suffix of the alarm name.
+	MetricName  string
+	Statistic   string
+	Threshold   float64
+	Description string
+}
+
+// Alarms are created on the Lambda function if an alarm SNS topic is provided.
+var Alarms = []Alarm{
+	{
+		MetricName:  "Errors",
+		Statistic:   "Sum",
+		Threshold:   1,
+		Description: "The Provider handler returned an error, so a grant, revoke or resource load may have failed",
+	},
+	{
+		MetricName:  "Throttles",
+		Statistic:   "Sum",
+		Threshold:   1,
+		Description: "Invocations of the Provider handler were throttled",
+	},
+	{
+		MetricName:  "Duration",
+		Statistic:   "Maximum",
+		Threshold:   DurationAlarmThreshold,
+		Description: "The Provider handler ran for more than 80% of its timeout",
+	},
+}
+
+// addLogGroup adds the log group for the Lambda function, so that the retention
+// and encryption of the logs can be configured.
+func addLogGroup(template *cfn.Template, providerTags []tags.Tag) {
+	var retention []any
+	for _, d := range LogRetentionDays {
+		retention = append(retention, d)
+	}
+
+	template.Parameters[ref.LogRetentionDays] = cfn.Parameter{
+		Type:          "Number",
+		Default:       strconv.Itoa(DefaultLogRetentionDays),
+		AllowedValues: retention,
+		Description:   cfn.String("The number of days to keep the Lambda function logs for"),
+	}
+
+	template.Parameters[ref.LogKmsKeyArn] = cfn.Parameter{
+		Type:        "String",
+		Default:     "",
+		Description: cfn.String("(Optional) The ARN of a customer-managed KMS key used to encrypt the Lambda function logs. The key policy must allow CloudWatch Logs to use the key"),
+	}
+
+	template.Conditions[ref.HasLogKmsKeyArn] = cfn.Not([]string{cfn.Equals(cfn.Ref(ref.LogKmsKeyArn), "")})
+
+	// goformation types RetentionInDays as an int, so a parameter can't be referenced.
+	//
+	// Lambda creates this log group when the function is first invoked, so stacks deployed before it was
+	// part of the template can't be updated until the existing log group is deleted or imported into the stack.
+	// 'pdk devhandler deploy' checks for this with handlerstack.UnmanagedLogGroup.
+	logGroup := &resource{
+		Type: "AWS::Logs::LogGroup",
+		Properties: map[string]any{
+			"LogGroupName":    cfn.Join("", []string{"/aws/lambda/", cfn.Ref(ref.HandlerID)}),
+			"RetentionInDays": cfn.Ref(ref.LogRetentionDays),
+			"KmsKeyId":        cfn.If(ref.HasLogKmsKeyArn, cfn.Ref(ref.LogKmsKeyArn), ref.AWSNoValueRef),
+		},
+	}
+	addTagsProperty(logGroup, providerTags)
+	template.Resources[ref.LogGroup] = logGroup
+}
+
+// addAlarms adds CloudWatch alarms on the Lambda function which notify an SNS topic,
+// if one is provided.
+func addAlarms(template *cfn.Template, providerTags []tags.Tag) {
+	template.Parameters[ref.AlarmTopicArn] = cfn.Parameter{
+		Type:        "String",
+		Default:     "",
+		Description: cfn.String("(Optional) The ARN of an SNS topic to notify when the Lambda function errors, is throttled or is close to timing out. Alarms are only created if this is provided"),
+	}
+
+	template.Conditions[ref.HasAlarmTopicArn] = cfn.Not([]string{cfn.Equals(cfn.Ref(ref.AlarmTopicArn), "")})
+
+	for _, a := range Alarms {
+		// goformation doesn't support tags on alarms, so the properties are untyped.
+		alarm := &resource{
+			Type: "AWS::CloudWatch::Alarm",
+			Properties: map[string]any{
+				"AlarmName":          cfn.Join("", []string{cfn.Ref(ref.HandlerID), "-", a.MetricName}),
+				"AlarmDescription":   a.Description,
+				"Namespace":          "AWS/Lambda",
+				"MetricName":         a.MetricName,
+				"Statistic":          a.Statistic,
+				"Dimensions":         []map[string]string{{"Name": "FunctionName", "Value": cfn.Ref(ref.LambdaFunction)}},
+				"Period":             300,
+				"EvaluationPeriods":  1,
+				"Threshold":          a.Threshold,
+				"ComparisonOperator": "GreaterThanOrEqualToThreshold",
+				"TreatMissingData":   "notBreaching",
+				"AlarmActions":       []string{cfn.Ref(ref.AlarmTopicArn)},
+				"OKActions":          []string{cfn.Ref(ref.AlarmTopicArn)},
+			},
+			Condition: ref.HasAlarmTopicArn,
+		}
+		addTagsProperty(alarm, providerTags)
+		template.Resources[a.MetricName+"Alarm"] = alarm
+	}
+}
@@ -6,11 +6,40 @@ import "github.com/awslabs/goformation/v7/cloudformation"
 
 // CloudFormation parameters
 const (
-	BootstrapBucketName    = "BootstrapBucketName"
-	AssetPath              = "AssetPath"
-	HandlerID              = "HandlerID"
-	CommonFateAWSAccountID = "CommonFateAWSAccountID"
-	HandlerAccountID       = "HandlerAccountID"
+	BootstrapBucketName        = "BootstrapBucketName"
+	AssetPath                  = "AssetPath"
+	HandlerID                  = "HandlerID"
+	CommonFateAWSAccountID     = "CommonFateAWSAccountID"
+	HandlerAccountID           = "HandlerAccountID"
+	KmsKeyArn                  = "KmsKeyArn"
+	SecretBackend              = "SecretBackend"
+	PermissionsBoundaryArn     = "PermissionsBoundaryArn"
+	RolePath                   = "RolePath"
+	HandlerRolePath            = "HandlerRolePath"
+	OrganizationalUnitIDs      = "OrganizationalUnitIds"
+	Regions                    = "Regions"
+	CallAs                     = "CallAs"
+	LogRetentionDays           = "LogRetentionDays"
+	LogKmsKeyArn               = "LogKmsKeyArn"
+	AlarmTopicArn              = "AlarmTopicArn"
+	ResourceLoadSchedule       = "ResourceLoadSchedule"
+	TracingEnabled             = "TracingEnabled"
+	OtelLayerArn               = "OtelLayerArn"
+	ResourceLoadDestinationArn = "ResourceLoadDestinationArn"
+)
+
+// CloudFormation conditions
+const (
+	HasKmsKeyArn               = "HasKmsKeyArn"
+	UseSecretsManager          = "UseSecretsManager"
+	UseSSM                     = "UseSSM"
+	HasPermissionsBoundary     = "HasPermissionsBoundary"
+	HasLogKmsKeyArn            = "HasLogKmsKeyArn"
+	HasAlarmTopicArn           = "HasAlarmTopicArn"
+	HasResourceLoadSchedule    = "HasResourceLoadSchedule"
+	HasTracing                 = "HasTracing"
+	HasOtelLayer               = "HasOtelLayer"
+	HasResourceLoadDestination = "HasResourceLoadDestination"
 )
 
 // CloudFormation Logical IDs
@@ -18,9 +47,31 @@ const (
 	LambdaFunction       = "LambdaFunction"
 	LambdaRole           = "LambdaRole"
 	LambdaInvocationRole = "LambdaInvocationRole"
+	LogGroup             = "LogGroup"
+	// policies which are only attached to the LambdaRole under some conditions.
+	LambdaSSMPolicy            = "LambdaSSMPolicy"
+	LambdaSecretsManagerPolicy = "LambdaSecretsManagerPolicy"
+	LambdaKmsPolicy            = "LambdaKmsPolicy"
+	LambdaTracingPolicy        = "LambdaTracingPolicy"
+	// a wait condition handle which depends on the conditional policies, as DependsOn can't be conditional.
+	LambdaPolicies = "LambdaPolicies"
+	// resources which are only created if resources are loaded on a schedule.
+	ResourceLoadDestinationPolicy = "ResourceLoadDestinationPolicy"
+	ResourceLoadDestination       = "ResourceLoadDestination"
+)
+
+// CloudFormation outputs of the handler template
+const (
+	LambdaFunctionArnOutput       = "LambdaFunctionArn"
+	LambdaRoleArnOutput           = "LambdaRoleArn"
+	LambdaInvocationRoleArnOutput = "LambdaInvocationRoleArn"
+	LogGroupNameOutput            = "LogGroupName"
 )
 
 var (
+	// { "Ref": "AWS::StackName" }
+	AWSStackNameRef = cloudformation.Ref("AWS::StackName")
+
 	// { "Ref": "AWS::Partition" }
 	AWSPartitionRef = cloudformation.Ref("AWS::Partition")
 
@@ -29,4 +80,7 @@ var (
 
 	// { "Ref": "AWS::AccountId" }
 	AWSAccountIDRef = cloudformation.Ref("AWS::AccountId")
+
+	// { "Ref": "AWS::NoValue" }
+	AWSNoValueRef = cloudformation.Ref("AWS::NoValue")
 )
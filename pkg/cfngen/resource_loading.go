@@ -0,0 +1,202 @@
+package cfngen
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	cfn "github.com/awslabs/goformation/v7/cloudformation"
+	"github.com/awslabs/goformation/v7/cloudformation/events"
+	"github.com/awslabs/goformation/v7/cloudformation/iam"
+	"github.com/awslabs/goformation/v7/cloudformation/lambda"
+	"github.com/common-fate/pdk/pkg/cfngen/ref"
+	"github.com/common-fate/pdk/pkg/iamp"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+)
+
+// ScheduleExpressionPattern matches an empty string, which disables scheduled resource loading,
+// or an EventBridge schedule expression such as 'rate(1 hour)'.
+const ScheduleExpressionPattern = `^$|^(rate|cron)\(.+\)$`
+
+// ResultDestinationPattern matches an empty string or the ARN of a destination for asynchronous
+// Lambda invocations: an SQS queue, SNS topic, EventBridge event bus or Lambda function.
+// S3 buckets aren't supported.
+const ResultDestinationPattern = `^$|^arn:aws[a-z-]*:(sqs|sns|events|lambda):.+$`
+
+// ResultDestinationActions maps the service in a destination ARN to the action which allows
+// the Lambda function to send the results of asynchronous invocations to the destination.
+var ResultDestinationActions = map[string]string{
+	"events": "events:PutEvents",
+	"lambda": "lambda:InvokeFunction",
+	"sns":    "sns:Publish",
+	"sqs":    "sqs:SendMessage",
+}
+
+// ResultDestinationServices returns the services in ResultDestinationActions, sorted.
+func ResultDestinationServices() []string {
+	var services []string
+	for s := range ResultDestinationActions {
+		services = append(services, s)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// ResourceLoader is a top-level resource loader which is invoked on a schedule.
+type ResourceLoader struct {
+	// Task is the name of the loader in the Provider schema.
+	Task string
+	// Name is the loader name in PascalCase, used in logical IDs.
+	Name  string
+	Title string
+}
+
+var nonAlphanumeric = regexp.MustCompile("[^a-zA-Z0-9]+")
+
+// ResourceLoaders returns the top-level resource loaders in the schema, sorted by task name.
+func ResourceLoaders(schema Schema) ([]ResourceLoader, error) {
+	if schema.Resources == nil {
+		return nil, nil
+	}
+
+	names := map[string]string{}
+	var loaders []ResourceLoader
+	for task, l := range schema.Resources.Loaders {
+		name := ConvertToPascalCase(nonAlphanumeric.ReplaceAllString(task, "_"))
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("the resource loaders %s and %s have the same name when converted to PascalCase", task, other)
+		}
+		names[name] = task
+		title := l.Title
+		if title == "" {
+			title = task
+		}
+		loaders = append(loaders, ResourceLoader{Task: task, Name: name, Title: title})
+	}
+	sort.Slice(loaders, func(i, j int) bool { return loaders[i].Task < loaders[j].Task })
+	return loaders, nil
+}
+
+// LoadResourcesInput returns the Lambda payload which runs a resource loader.
+func LoadResourcesInput(task string) (string, error) {
+	payload := struct {
+		Type msg.RequestType   `json:"type"`
+		Data msg.LoadResources `json:"data"`
+	}{
+		Type: msg.RequestTypeLoadResources,
+		Data: msg.LoadResources{Task: task, Ctx: map[string]any{}},
+	}
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// addResourceLoading adds an optional EventBridge schedule for each top-level resource loader,
+// which invokes the Lambda function asynchronously. The results of the invocations can be sent
+// to a destination such as an SQS queue. Nothing is added if the Provider has no loaders.
+func addResourceLoading(template *cfn.Template, schema Schema) error {
+	loaders, err := ResourceLoaders(schema)
+	if err != nil {
+		return err
+	}
+	if len(loaders) == 0 {
+		return nil
+	}
+
+	template.Parameters[ref.ResourceLoadSchedule] = cfn.Parameter{
+		Type:                  "String",
+		Default:               "",
+		AllowedPattern:        cfn.String(ScheduleExpressionPattern),
+		ConstraintDescription: cfn.String("must be an EventBridge schedule expression, such as rate(1 hour)"),
+		Description:           cfn.String("(Optional) An EventBridge schedule expression, such as rate(1 hour), to run the Provider's resource loaders on. Resources are only loaded on a schedule if this is provided"),
+	}
+
+	template.Parameters[ref.ResourceLoadDestinationArn] = cfn.Parameter{
+		Type:                  "String",
+		Default:               "",
+		AllowedPattern:        cfn.String(ResultDestinationPattern),
+		ConstraintDescription: cfn.String("must be the ARN of an SQS queue, SNS topic, EventBridge event bus or Lambda function"),
+		Description:           cfn.String("(Optional) The ARN of an SQS queue, SNS topic, EventBridge event bus or Lambda function to send the results of scheduled resource loads to. S3 buckets aren't supported"),
+	}
+
+	template.Conditions[ref.HasResourceLoadSchedule] = cfn.Not([]string{cfn.Equals(cfn.Ref(ref.ResourceLoadSchedule), "")})
+	// the destination is only used if there is a schedule, as nothing else invokes the function asynchronously.
+	template.Conditions[ref.HasResourceLoadDestination] = cfn.And([]string{
+		cfn.Not([]string{cfn.Equals(cfn.Ref(ref.ResourceLoadSchedule), "")}),
+		cfn.Not([]string{cfn.Equals(cfn.Ref(ref.ResourceLoadDestinationArn), "")}),
+	})
+
+	// only the action for the service in the destination ARN is granted. The pattern on the
+	// parameter ensures that it is one of the supported services, so the last is the default.
+	services := ResultDestinationServices()
+	destinationAction := ResultDestinationActions[services[len(services)-1]]
+	for i := len(services) - 2; i >= 0; i-- {
+		condition := resultDestinationCondition(services[i])
+		template.Conditions[condition] = cfn.Equals(cfn.Select(2, cfn.Split(":", cfn.Ref(ref.ResourceLoadDestinationArn))), services[i])
+		destinationAction = cfn.If(condition, ResultDestinationActions[services[i]], destinationAction)
+	}
+
+	for _, l := range loaders {
+		input, err := LoadResourcesInput(l.Task)
+		if err != nil {
+			return err
+		}
+
+		ruleID := "ResourceLoad" + l.Name + "Schedule"
+		template.Resources[ruleID] = &events.Rule{
+			Description:        cfn.JoinPtr("", []string{"Loads " + l.Title + " resources for the ", cfn.Ref(ref.HandlerID), " Handler"}),
+			ScheduleExpression: cfn.RefPtr(ref.ResourceLoadSchedule),
+			State:              cfn.String("ENABLED"),
+			Targets: []events.Rule_Target{
+				{
+					Arn:   cfn.GetAtt(ref.LambdaFunction, "Arn"),
+					Id:    "handler",
+					Input: cfn.String(input),
+				},
+			},
+			AWSCloudFormationCondition: ref.HasResourceLoadSchedule,
+		}
+
+		template.Resources["ResourceLoad"+l.Name+"Permission"] = &lambda.Permission{
+			Action:                     "lambda:InvokeFunction",
+			FunctionName:               cfn.Ref(ref.LambdaFunction),
+			Principal:                  "events.amazonaws.com",
+			SourceArn:                  cfn.GetAttPtr(ruleID, "Arn"),
+			AWSCloudFormationCondition: ref.HasResourceLoadSchedule,
+		}
+	}
+
+	template.Resources[ref.ResourceLoadDestinationPolicy] = &iam.Policy{
+		PolicyName: "handler-resource-load-destination-policy",
+		Roles:      []string{cfn.Ref(ref.LambdaRole)},
+		PolicyDocument: iamp.NewPolicy(iamp.Statement{
+			Effect:   iamp.Allow,
+			Action:   iamp.Value{destinationAction},
+			Resource: iamp.Value{cfn.Ref(ref.ResourceLoadDestinationArn)},
+		}),
+		AWSCloudFormationCondition: ref.HasResourceLoadDestination,
+	}
+
+	// the destination applies to all asynchronous invocations of the function.
+	template.Resources[ref.ResourceLoadDestination] = &lambda.EventInvokeConfig{
+		FunctionName: cfn.Ref(ref.LambdaFunction),
+		Qualifier:    "$LATEST",
+		DestinationConfig: &lambda.EventInvokeConfig_DestinationConfig{
+			OnSuccess: &lambda.EventInvokeConfig_OnSuccess{Destination: cfn.Ref(ref.ResourceLoadDestinationArn)},
+			OnFailure: &lambda.EventInvokeConfig_OnFailure{Destination: cfn.Ref(ref.ResourceLoadDestinationArn)},
+		},
+		// Lambda checks that it can send to the destination when the config is created.
+		AWSCloudFormationDependsOn: []string{ref.ResourceLoadDestinationPolicy},
+		AWSCloudFormationCondition: ref.HasResourceLoadDestination,
+	}
+
+	return nil
+}
+
+// resultDestinationCondition returns the name of the condition which is true if the destination is in the service.
+func resultDestinationCondition(service string) string {
+	return "ResourceLoadDestinationIs" + ConvertToPascalCase(service)
+}
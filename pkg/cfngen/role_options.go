@@ -0,0 +1,36 @@
+package cfngen
+
+import (
+	cfn "github.com/awslabs/goformation/v7/cloudformation"
+	"github.com/common-fate/pdk/pkg/cfngen/ref"
+)
+
+// rolePathPattern matches valid IAM role paths, which must begin and end with a '/'.
+const rolePathPattern = `^\/([\x21-\x7E]*\/)?$`
+
+// addRoleParameters adds the optional permissions boundary and role path
+// parameters to a template. Organisations commonly require these to be set on
+// every IAM role, so all roles in our generated templates should use them.
+func addRoleParameters(template *cfn.Template) {
+	template.Parameters[ref.PermissionsBoundaryArn] = cfn.Parameter{
+		Type:        "String",
+		Default:     "",
+		Description: cfn.String("(Optional) The ARN of an IAM managed policy to use as a permissions boundary for the IAM roles"),
+	}
+
+	template.Parameters[ref.RolePath] = cfn.Parameter{
+		Type:                  "String",
+		Default:               "/",
+		AllowedPattern:        cfn.String(rolePathPattern),
+		ConstraintDescription: cfn.String("must begin and end with a '/'"),
+		Description:           cfn.String("(Optional) The path to create the IAM roles under, e.g. /common-fate/"),
+	}
+
+	template.Conditions[ref.HasPermissionsBoundary] = cfn.Not([]string{cfn.Equals(cfn.Ref(ref.PermissionsBoundaryArn), "")})
+}
+
+// rolePermissionsBoundary returns the permissions boundary to apply to IAM roles,
+// if one has been provided.
+func rolePermissionsBoundary() *string {
+	return cfn.IfPtr(ref.HasPermissionsBoundary, cfn.Ref(ref.PermissionsBoundaryArn), ref.AWSNoValueRef)
+}
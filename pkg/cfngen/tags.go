@@ -0,0 +1,145 @@
+package cfngen
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	cfn "github.com/awslabs/goformation/v7/cloudformation"
+	"github.com/awslabs/goformation/v7/cloudformation/tags"
+	"github.com/common-fate/pdk/pkg/accessrole"
+)
+
+// tagKeyPattern matches the characters allowed in AWS tag keys.
+var tagKeyPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]{1,128}$`)
+
+// TagParameter returns the name of the parameter which sets the value of a tag
+// from provider.toml, e.g. 'cost-centre' -> 'TagCostCentre'.
+func TagParameter(key string) string {
+	var b strings.Builder
+	b.WriteString("Tag")
+	for _, part := range strings.FieldsFunc(key, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// TagParameterValues returns the tag parameters to deploy with, from tags in key=value format such
+// as the --tag flags of the deploy commands. Tags with an empty value in provider.toml must be provided,
+// as their parameters have no default.
+func TagParameterValues(configTags map[string]string, values []string) (map[string]string, error) {
+	params := map[string]string{}
+	for _, t := range values {
+		key, value, ok := strings.Cut(t, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid tag %q: tags must be in the format key=value", t)
+		}
+		if _, ok := configTags[key]; !ok {
+			return nil, fmt.Errorf("tag %s isn't in the [tags] table in provider.toml", key)
+		}
+		params[TagParameter(key)] = value
+	}
+
+	for _, k := range sortedKeys(configTags) {
+		if _, ok := params[TagParameter(k)]; !ok && configTags[k] == "" {
+			return nil, fmt.Errorf("tag %s has no value in provider.toml, set it with --tag %s=<value>", k, k)
+		}
+	}
+	return params, nil
+}
+
+// ValidateTags checks the tags from provider.toml. Tags beginning with 'common-fate-'
+// are set by Common Fate and tags beginning with 'aws:' are reserved by AWS.
+func ValidateTags(t map[string]string) error {
+	params := map[string]string{}
+	for _, k := range sortedKeys(t) {
+		if strings.HasPrefix(k, accessrole.ReservedTagPrefix) {
+			return fmt.Errorf("tag %s is reserved: tags must not begin with %s", k, accessrole.ReservedTagPrefix)
+		}
+		if strings.HasPrefix(strings.ToLower(k), "aws:") {
+			return fmt.Errorf("tag %s is reserved: tags must not begin with aws:", k)
+		}
+		if !tagKeyPattern.MatchString(k) {
+			return fmt.Errorf("invalid tag key %q: keys must be 1 to 128 letters, numbers, spaces or the characters _.:/=+-@", k)
+		}
+		if len(t[k]) > 256 {
+			return fmt.Errorf("the value of tag %s must be at most 256 characters long", k)
+		}
+		p := TagParameter(k)
+		if p == "Tag" {
+			return fmt.Errorf("tag %s must contain at least one letter or number", k)
+		}
+		if other, ok := params[p]; ok {
+			return fmt.Errorf("tags %s and %s would both use the %s parameter", other, k, p)
+		}
+		params[p] = k
+	}
+	return nil
+}
+
+// addTagParameters adds a parameter for each tag in provider.toml, so that the values
+// can be set when the Provider is deployed. Tags with an empty value in provider.toml
+// must be provided when deploying. It returns the tags to apply to each resource.
+func addTagParameters(template *cfn.Template, t map[string]string) ([]tags.Tag, error) {
+	err := ValidateTags(t)
+	if err != nil {
+		return nil, err
+	}
+
+	var resourceTags []tags.Tag
+	for _, k := range sortedKeys(t) {
+		name := TagParameter(k)
+		p := cfn.Parameter{
+			Type:        "String",
+			Default:     t[k],
+			MaxLength:   cfn.Int(256),
+			Description: cfn.String(fmt.Sprintf("The value of the '%s' tag applied to all resources", k)),
+		}
+		if t[k] == "" {
+			p.Default = nil
+			p.MinLength = cfn.Int(1)
+		}
+		template.Parameters[name] = p
+		resourceTags = append(resourceTags, tags.Tag{Key: k, Value: cfn.Ref(name)})
+	}
+	return resourceTags, nil
+}
+
+// withTags returns the tags of a resource with the tags from provider.toml added.
+// Tags which the resource already sets take precedence.
+func withTags(resourceTags []tags.Tag, providerTags []tags.Tag) []tags.Tag {
+	set := map[string]bool{}
+	for _, t := range resourceTags {
+		set[t.Key] = true
+	}
+	for _, t := range providerTags {
+		if !set[t.Key] {
+			resourceTags = append(resourceTags, t)
+		}
+	}
+	return resourceTags
+}
+
+// addTagsProperty sets the tags of an untyped resource, if there are any.
+func addTagsProperty(r *resource, t []tags.Tag) {
+	if len(t) == 0 {
+		return
+	}
+	var props []map[string]string
+	for _, tag := range t {
+		props = append(props, map[string]string{"Key": tag.Key, "Value": tag.Value})
+	}
+	r.Properties["Tags"] = props
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
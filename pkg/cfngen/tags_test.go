@@ -0,0 +1,64 @@
+package cfngen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    map[string]string
+		wantErr string
+	}{
+		{name: "ok", give: map[string]string{"cost-centre": "1234", "team:owner": "", "Data Class": "internal"}},
+		{name: "reserved", give: map[string]string{"common-fate-abac-role": "x"}, wantErr: "tag common-fate-abac-role is reserved: tags must not begin with common-fate-"},
+		{name: "aws", give: map[string]string{"AWS:Name": "x"}, wantErr: "tag AWS:Name is reserved: tags must not begin with aws:"},
+		{name: "invalid key", give: map[string]string{"cost#centre": "x"}, wantErr: `invalid tag key "cost#centre": keys must be 1 to 128 letters, numbers, spaces or the characters _.:/=+-@`},
+		{name: "parameter clash", give: map[string]string{"cost-centre": "x", "cost_centre": "y"}, wantErr: "tags cost-centre and cost_centre would both use the TagCostCentre parameter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTags(tt.give)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("want error %q got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTagParameterValues(t *testing.T) {
+	configTags := map[string]string{"cost-centre": "1234", "owner": ""}
+	tests := []struct {
+		name    string
+		give    []string
+		want    map[string]string
+		wantErr string
+	}{
+		{name: "ok", give: []string{"owner=platform"}, want: map[string]string{"TagOwner": "platform"}},
+		{name: "override default", give: []string{"owner=platform", "cost-centre=5678"}, want: map[string]string{"TagOwner": "platform", "TagCostCentre": "5678"}},
+		{name: "missing required", give: nil, wantErr: "tag owner has no value in provider.toml, set it with --tag owner=<value>"},
+		{name: "unknown", give: []string{"team=x"}, wantErr: "tag team isn't in the [tags] table in provider.toml"},
+		{name: "invalid", give: []string{"owner"}, wantErr: `invalid tag "owner": tags must be in the format key=value`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TagParameterValues(configTags, tt.give)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("want error %q got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want %v got %v", tt.want, got)
+			}
+		})
+	}
+}
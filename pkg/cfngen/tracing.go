@@ -0,0 +1,58 @@
+package cfngen
+
+import (
+	cfn "github.com/awslabs/goformation/v7/cloudformation"
+	"github.com/awslabs/goformation/v7/cloudformation/iam"
+	"github.com/awslabs/goformation/v7/cloudformation/lambda"
+	"github.com/common-fate/pdk/pkg/cfngen/ref"
+	"github.com/common-fate/pdk/pkg/iamp"
+)
+
+// OtelExecWrapper is the wrapper script in the AWS Distro for OpenTelemetry Lambda layers,
+// which instruments the Provider before it runs.
+const OtelExecWrapper = "/opt/otel-instrument"
+
+// TracingActions allow the Lambda function to send traces to X-Ray.
+var TracingActions = iamp.Value{
+	"xray:PutTraceSegments",
+	"xray:PutTelemetryRecords",
+}
+
+// addTracing adds the parameters which enable X-Ray active tracing and attach an
+// OpenTelemetry layer to the Lambda function, so that the time spent inside a grant
+// can be traced.
+func addTracing(template *cfn.Template, lambdaFunction *lambda.Function) {
+	template.Parameters[ref.TracingEnabled] = cfn.Parameter{
+		Type:          "String",
+		Default:       "false",
+		AllowedValues: []any{"true", "false"},
+		Description:   cfn.String("Whether to enable X-Ray active tracing of the Lambda function"),
+	}
+
+	template.Parameters[ref.OtelLayerArn] = cfn.Parameter{
+		Type:        "String",
+		Default:     "",
+		Description: cfn.String("(Optional) The ARN of an AWS Distro for OpenTelemetry Lambda layer for Python, which instruments the Provider to trace calls to downstream APIs"),
+	}
+
+	template.Conditions[ref.HasTracing] = cfn.Equals(cfn.Ref(ref.TracingEnabled), "true")
+	template.Conditions[ref.HasOtelLayer] = cfn.Not([]string{cfn.Equals(cfn.Ref(ref.OtelLayerArn), "")})
+
+	lambdaFunction.TracingConfig = &lambda.Function_TracingConfig{
+		Mode: cfn.IfPtr(ref.HasTracing, "Active", "PassThrough"),
+	}
+	lambdaFunction.Layers = []string{cfn.If(ref.HasOtelLayer, cfn.Ref(ref.OtelLayerArn), ref.AWSNoValueRef)}
+	lambdaFunction.Environment.Variables["AWS_LAMBDA_EXEC_WRAPPER"] = cfn.If(ref.HasOtelLayer, OtelExecWrapper, ref.AWSNoValueRef)
+
+	// these are the permissions which Lambda needs to send traces when active tracing is enabled.
+	template.Resources[ref.LambdaTracingPolicy] = &iam.Policy{
+		PolicyName: "handler-tracing-policy",
+		Roles:      []string{cfn.Ref(ref.LambdaRole)},
+		PolicyDocument: iamp.NewPolicy(iamp.Statement{
+			Effect:   iamp.Allow,
+			Action:   TracingActions,
+			Resource: iamp.Value{"*"},
+		}),
+		AWSCloudFormationCondition: ref.HasTracing,
+	}
+}
@@ -0,0 +1,662 @@
+package cfngen
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	cfn "github.com/awslabs/goformation/v7/cloudformation"
+)
+
+// CloudFormation limits which are checked by Validate.
+const (
+	// MaxTemplateBodySize is the maximum size of a template passed in the TemplateBody
+	// of an API call, in bytes. It applies to 'pdk devhandler deploy' and to the access
+	// role templates embedded in StackSets.
+	MaxTemplateBodySize = 51200
+	MaxParameters       = 200
+	MaxResources        = 500
+	MaxOutputs          = 200
+	MaxLogicalIDLength  = 255
+)
+
+// pseudoParameters are the parameters predefined by CloudFormation.
+var pseudoParameters = map[string]bool{
+	"AWS::AccountId":        true,
+	"AWS::NotificationARNs": true,
+	"AWS::NoValue":          true,
+	"AWS::Partition":        true,
+	"AWS::Region":           true,
+	"AWS::StackId":          true,
+	"AWS::StackName":        true,
+	"AWS::URLSuffix":        true,
+}
+
+var parameterTypes = map[string]bool{
+	"String":             true,
+	"Number":             true,
+	"List<Number>":       true,
+	"CommaDelimitedList": true,
+}
+
+var logicalIDPattern = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+
+var subVariablePattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// resourceSpecJSON describes the properties of the resource types used in our templates.
+// Resources with other types aren't checked.
+//
+//go:embed resource_spec.json
+var resourceSpecJSON []byte
+
+type propertySpec struct {
+	// Type is one of 'string', 'integer', 'number', 'boolean', 'list', 'object' or 'json'.
+	Type string `json:"type"`
+	// Items is the type of the list items, if Type is 'list'.
+	Items     string `json:"items"`
+	Required  bool   `json:"required"`
+	MaxLength int    `json:"maxLength"`
+}
+
+var resourceSpec = mustLoadResourceSpec()
+
+func mustLoadResourceSpec() map[string]map[string]propertySpec {
+	var s map[string]map[string]propertySpec
+	err := json.Unmarshal(resourceSpecJSON, &s)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// ValidationError is a problem with a template which would cause it to fail when it's deployed.
+type ValidationError struct {
+	// Path is the location of the problem in the template, e.g. 'Resources.LambdaRole.Properties.RoleName'.
+	// It's empty for problems with the template as a whole.
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors is returned by Validate if a template has any problems.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return "invalid CloudFormation template: " + e[0].Error()
+	}
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid CloudFormation template, found %d problems:\n%s", len(e), strings.Join(msgs, "\n"))
+}
+
+type templateDoc struct {
+	Description string
+	Metadata    map[string]json.RawMessage
+	Parameters  map[string]parameterDoc
+	Conditions  map[string]any
+	Mappings    map[string]any
+	Resources   map[string]resourceDoc
+	Outputs     map[string]outputDoc
+}
+
+type parameterDoc struct {
+	Type           string
+	Default        any
+	AllowedValues  []any
+	AllowedPattern string
+	MinLength      *int
+	MaxLength      *int
+	Description    string
+}
+
+type resourceDoc struct {
+	Type       string
+	Properties map[string]any
+	Metadata   map[string]any
+	DependsOn  any
+	Condition  string
+}
+
+type outputDoc struct {
+	Value       any
+	Description string
+	Export      any
+	Condition   string
+}
+
+// Validate checks a JSON CloudFormation template for problems which would otherwise
+// only be found when the template is deployed, such as references to undefined
+// parameters or resources, names which are too long and properties with the wrong type.
+// It returns ValidationErrors if the template has any problems.
+func Validate(template []byte) error {
+	var doc templateDoc
+	err := json.Unmarshal(template, &doc)
+	if err != nil {
+		return fmt.Errorf("parsing CloudFormation template: %w", err)
+	}
+
+	v := validator{doc: doc}
+	v.checkLimits(len(template))
+	v.checkParameters()
+	for name, c := range doc.Conditions {
+		v.checkLogicalID("Conditions."+name, name)
+		v.walk("Conditions."+name, c, true)
+	}
+	v.checkResources()
+	v.checkOutputs()
+	v.checkInterface()
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.errs, func(i, j int) bool { return v.errs[i].Path < v.errs[j].Path })
+	return v.errs
+}
+
+// marshal returns the template as JSON, after checking that it's valid.
+func marshal(template *cfn.Template) ([]byte, error) {
+	b, err := template.JSON()
+	if err != nil {
+		return nil, err
+	}
+	err = Validate(b)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+type validator struct {
+	doc  templateDoc
+	errs ValidationErrors
+}
+
+func (v *validator) addf(path string, format string, args ...any) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) checkLimits(size int) {
+	if size > MaxTemplateBodySize {
+		v.addf("", "the template is %d bytes, which is larger than the %d byte limit for templates deployed without uploading them to S3", size, MaxTemplateBodySize)
+	}
+	if len(v.doc.Description) > 1024 {
+		v.addf("Description", "must be at most 1024 bytes, but is %d bytes", len(v.doc.Description))
+	}
+	if len(v.doc.Parameters) > MaxParameters {
+		v.addf("Parameters", "the template has %d parameters, the maximum is %d", len(v.doc.Parameters), MaxParameters)
+	}
+	if len(v.doc.Resources) > MaxResources {
+		v.addf("Resources", "the template has %d resources, the maximum is %d", len(v.doc.Resources), MaxResources)
+	}
+	if len(v.doc.Outputs) > MaxOutputs {
+		v.addf("Outputs", "the template has %d outputs, the maximum is %d", len(v.doc.Outputs), MaxOutputs)
+	}
+}
+
+func (v *validator) checkLogicalID(path string, id string) {
+	if !logicalIDPattern.MatchString(id) {
+		v.addf(path, "%q must only contain alphanumeric characters", id)
+	}
+	if len(id) > MaxLogicalIDLength {
+		v.addf(path, "must be at most %d characters long", MaxLogicalIDLength)
+	}
+}
+
+func (v *validator) checkParameters() {
+	for name, p := range v.doc.Parameters {
+		path := "Parameters." + name
+		v.checkLogicalID(path, name)
+
+		if !parameterTypes[p.Type] && !strings.HasPrefix(p.Type, "AWS::") && !strings.HasPrefix(p.Type, "List<AWS::") {
+			v.addf(path+".Type", "%q isn't a valid parameter type", p.Type)
+		}
+		if len(p.Description) > 4000 {
+			v.addf(path+".Description", "must be at most 4000 characters long")
+		}
+		if p.Default == nil {
+			continue
+		}
+
+		// the default is used when the parameter isn't provided, so it must satisfy the constraints.
+		def := fmt.Sprint(p.Default)
+		if p.Type == "Number" {
+			if _, err := strconv.ParseFloat(def, 64); err != nil {
+				v.addf(path+".Default", "%q isn't a number", def)
+			}
+		}
+		if len(p.AllowedValues) > 0 {
+			allowed := false
+			for _, a := range p.AllowedValues {
+				if fmt.Sprint(a) == def {
+					allowed = true
+				}
+			}
+			if !allowed {
+				v.addf(path+".Default", "%q isn't one of the allowed values", def)
+			}
+		}
+		if p.AllowedPattern != "" {
+			// CloudFormation uses Java regular expressions, so patterns which Go can't compile are skipped.
+			re, err := regexp.Compile(p.AllowedPattern)
+			if err == nil && !re.MatchString(def) {
+				v.addf(path+".Default", "%q doesn't match the allowed pattern %s", def, p.AllowedPattern)
+			}
+		}
+		if p.MinLength != nil && len(def) < *p.MinLength {
+			v.addf(path+".Default", "%q is shorter than the minimum length of %d", def, *p.MinLength)
+		}
+		if p.MaxLength != nil && len(def) > *p.MaxLength {
+			v.addf(path+".Default", "%q is longer than the maximum length of %d", def, *p.MaxLength)
+		}
+	}
+}
+
+func (v *validator) checkResources() {
+	for name, r := range v.doc.Resources {
+		path := "Resources." + name
+		v.checkLogicalID(path, name)
+		v.checkCondition(path+".Condition", r.Condition)
+
+		switch d := r.DependsOn.(type) {
+		case string:
+			v.checkDependsOn(path+".DependsOn", d)
+		case []any:
+			for i, dep := range d {
+				s, _ := dep.(string)
+				v.checkDependsOn(fmt.Sprintf("%s.DependsOn[%d]", path, i), s)
+			}
+		}
+
+		for k, val := range r.Properties {
+			v.walk(path+".Properties."+k, val, false)
+		}
+		for k, val := range r.Metadata {
+			v.walk(path+".Metadata."+k, val, false)
+		}
+
+		spec, ok := resourceSpec[r.Type]
+		if !ok {
+			continue
+		}
+		for k, ps := range spec {
+			if _, ok := r.Properties[k]; ps.Required && !ok {
+				v.addf(path+".Properties", "the required property %s is missing", k)
+			}
+		}
+		for k, val := range r.Properties {
+			propPath := path + ".Properties." + k
+			ps, ok := spec[k]
+			if !ok {
+				v.addf(propPath, "%s isn't a property of %s", k, r.Type)
+				continue
+			}
+			v.checkType(propPath, val, ps.Type, ps.Items)
+			if ps.MaxLength > 0 {
+				if n := v.minLength(val); n > ps.MaxLength {
+					v.addf(propPath, "is at least %d characters long with the default parameter values, the maximum is %d", n, ps.MaxLength)
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) checkOutputs() {
+	for name, o := range v.doc.Outputs {
+		path := "Outputs." + name
+		v.checkLogicalID(path, name)
+		v.checkCondition(path+".Condition", o.Condition)
+		if o.Value == nil {
+			v.addf(path, "the output doesn't have a Value")
+		}
+		if len(o.Description) > 1024 {
+			v.addf(path+".Description", "must be at most 1024 bytes long")
+		}
+		v.walk(path+".Value", o.Value, false)
+		v.walk(path+".Export", o.Export, false)
+	}
+}
+
+// checkInterface checks that the parameters in the console metadata are defined.
+func (v *validator) checkInterface() {
+	raw, ok := v.doc.Metadata["AWS::CloudFormation::Interface"]
+	if !ok {
+		return
+	}
+	var iface struct {
+		ParameterGroups []struct {
+			Parameters []string
+		}
+		ParameterLabels map[string]any
+	}
+	err := json.Unmarshal(raw, &iface)
+	if err != nil {
+		v.addf("Metadata.AWS::CloudFormation::Interface", "invalid interface metadata: %s", err)
+		return
+	}
+
+	grouped := map[string]bool{}
+	for i, g := range iface.ParameterGroups {
+		for j, p := range g.Parameters {
+			path := fmt.Sprintf("Metadata.AWS::CloudFormation::Interface.ParameterGroups[%d].Parameters[%d]", i, j)
+			if _, ok := v.doc.Parameters[p]; !ok {
+				v.addf(path, "%s isn't a parameter", p)
+			}
+			if grouped[p] {
+				v.addf(path, "%s is in more than one group", p)
+			}
+			grouped[p] = true
+		}
+	}
+	for p := range iface.ParameterLabels {
+		if _, ok := v.doc.Parameters[p]; !ok {
+			v.addf("Metadata.AWS::CloudFormation::Interface.ParameterLabels."+p, "%s isn't a parameter", p)
+		}
+	}
+}
+
+func (v *validator) checkCondition(path string, name string) {
+	if name == "" {
+		return
+	}
+	if _, ok := v.doc.Conditions[name]; !ok {
+		v.addf(path, "the condition %s isn't defined", name)
+	}
+}
+
+func (v *validator) checkDependsOn(path string, name string) {
+	if _, ok := v.doc.Resources[name]; !ok {
+		v.addf(path, "depends on %q, which isn't a resource", name)
+	}
+}
+
+// walk checks the intrinsic functions in a value. Conditions can only reference parameters,
+// so inConditions should be true when walking the Conditions section.
+func (v *validator) walk(path string, value any, inConditions bool) {
+	switch val := value.(type) {
+	case map[string]any:
+		if len(val) == 1 {
+			for fn, arg := range val {
+				v.checkIntrinsic(path, fn, arg, inConditions)
+			}
+		}
+		for k, child := range val {
+			v.walk(path+"."+k, child, inConditions)
+		}
+	case []any:
+		for i, child := range val {
+			v.walk(fmt.Sprintf("%s[%d]", path, i), child, inConditions)
+		}
+	}
+}
+
+func (v *validator) checkIntrinsic(path string, fn string, arg any, inConditions bool) {
+	switch fn {
+	case "Ref":
+		name, ok := arg.(string)
+		if !ok {
+			v.addf(path, "Ref must be a string")
+			return
+		}
+		v.checkRef(path, name, inConditions)
+
+	case "Fn::GetAtt":
+		var resource string
+		switch a := arg.(type) {
+		case string:
+			resource, _, _ = strings.Cut(a, ".")
+		case []any:
+			if len(a) == 2 {
+				resource, _ = a[0].(string)
+			}
+		}
+		if resource == "" {
+			v.addf(path, "Fn::GetAtt must be a list of a resource name and an attribute")
+			return
+		}
+		v.checkGetAtt(path, resource, inConditions)
+
+	case "Fn::Sub":
+		var s string
+		vars := map[string]any{}
+		switch a := arg.(type) {
+		case string:
+			s = a
+		case []any:
+			if len(a) == 2 {
+				s, _ = a[0].(string)
+				vars, _ = a[1].(map[string]any)
+			}
+		}
+		for _, m := range subVariablePattern.FindAllStringSubmatch(s, -1) {
+			name := m[1]
+			// '${!Literal}' is written to the output as '${Literal}'.
+			if strings.HasPrefix(name, "!") {
+				continue
+			}
+			if _, ok := vars[name]; ok {
+				continue
+			}
+			if resource, _, ok := strings.Cut(name, "."); ok {
+				v.checkGetAtt(path, resource, inConditions)
+			} else {
+				v.checkRef(path, name, inConditions)
+			}
+		}
+
+	case "Fn::If":
+		a, ok := arg.([]any)
+		if !ok || len(a) != 3 {
+			v.addf(path, "Fn::If must be a list of a condition name and two values")
+			return
+		}
+		name, _ := a[0].(string)
+		v.checkCondition(path, name)
+
+	case "Condition":
+		if !inConditions {
+			return
+		}
+		name, _ := arg.(string)
+		v.checkCondition(path, name)
+	}
+}
+
+func (v *validator) checkRef(path string, name string, inConditions bool) {
+	if strings.HasPrefix(name, "AWS::") {
+		if !pseudoParameters[name] {
+			v.addf(path, "references %s, which isn't a pseudo parameter", name)
+		}
+		return
+	}
+	if _, ok := v.doc.Parameters[name]; ok {
+		return
+	}
+	if _, ok := v.doc.Resources[name]; ok {
+		if inConditions {
+			v.addf(path, "references the resource %s, but conditions can only reference parameters", name)
+		}
+		return
+	}
+	v.addf(path, "references %s, which isn't a parameter or resource", name)
+}
+
+func (v *validator) checkGetAtt(path string, resource string, inConditions bool) {
+	if inConditions {
+		v.addf(path, "gets an attribute of %s, but conditions can only reference parameters", resource)
+		return
+	}
+	if _, ok := v.doc.Resources[resource]; !ok {
+		v.addf(path, "gets an attribute of %s, which isn't a resource", resource)
+	}
+}
+
+// checkType checks that a property value has the expected type. Values which
+// use intrinsic functions aren't checked, because they're resolved when deploying.
+func (v *validator) checkType(path string, value any, typ string, items string) {
+	if isIntrinsic(value) {
+		return
+	}
+
+	ok := true
+	switch typ {
+	case "string":
+		// CloudFormation converts numbers and booleans to strings.
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			ok = false
+		}
+	case "integer":
+		switch val := value.(type) {
+		case float64:
+			ok = val == float64(int64(val))
+		case string:
+			_, err := strconv.ParseInt(val, 10, 64)
+			ok = err == nil
+		default:
+			ok = false
+		}
+	case "number":
+		switch val := value.(type) {
+		case float64:
+		case string:
+			_, err := strconv.ParseFloat(val, 64)
+			ok = err == nil
+		default:
+			ok = false
+		}
+	case "boolean":
+		switch val := value.(type) {
+		case bool:
+		case string:
+			ok = val == "true" || val == "false"
+		default:
+			ok = false
+		}
+	case "list":
+		list, isList := value.([]any)
+		ok = isList
+		if isList && items != "" {
+			for i, item := range list {
+				v.checkType(fmt.Sprintf("%s[%d]", path, i), item, items, "")
+			}
+		}
+	case "object":
+		_, ok = value.(map[string]any)
+	case "json":
+		switch value.(type) {
+		case map[string]any, string:
+		default:
+			ok = false
+		}
+	}
+
+	if !ok {
+		v.addf(path, "must be of type %s, but is %s", typ, describeType(value))
+	}
+}
+
+// minLength returns the shortest length that a value can have when the template is
+// deployed with the default parameter values. Parameters without a default are
+// assumed to have their minimum length, and attributes of resources are assumed to be empty.
+func (v *validator) minLength(value any) int {
+	switch val := value.(type) {
+	case string:
+		return len(val)
+	case float64, bool:
+		return len(fmt.Sprint(val))
+	case map[string]any:
+		if len(val) != 1 {
+			return 0
+		}
+		if name, ok := val["Ref"].(string); ok {
+			return v.refLength(name)
+		}
+		if a, ok := val["Fn::Join"].([]any); ok && len(a) == 2 {
+			delim, _ := a[0].(string)
+			parts, ok := a[1].([]any)
+			if !ok || len(parts) == 0 {
+				return 0
+			}
+			n := len(delim) * (len(parts) - 1)
+			for _, p := range parts {
+				n += v.minLength(p)
+			}
+			return n
+		}
+		if a, ok := val["Fn::If"].([]any); ok && len(a) == 3 {
+			t, f := v.minLength(a[1]), v.minLength(a[2])
+			if t < f {
+				return t
+			}
+			return f
+		}
+		if s, ok := val["Fn::Sub"].(string); ok {
+			n := len(s)
+			for _, m := range subVariablePattern.FindAllStringSubmatch(s, -1) {
+				n -= len(m[0])
+				if !strings.HasPrefix(m[1], "!") && !strings.Contains(m[1], ".") {
+					n += v.refLength(m[1])
+				}
+			}
+			return n
+		}
+	}
+	return 0
+}
+
+func (v *validator) refLength(name string) int {
+	p, ok := v.doc.Parameters[name]
+	if !ok {
+		return 0
+	}
+	if p.Default != nil {
+		return len(fmt.Sprint(p.Default))
+	}
+	if p.MinLength != nil {
+		return *p.MinLength
+	}
+	return 0
+}
+
+func isIntrinsic(value any) bool {
+	m, ok := value.(map[string]any)
+	if !ok || len(m) != 1 {
+		return false
+	}
+	for k := range m {
+		return k == "Ref" || strings.HasPrefix(k, "Fn::")
+	}
+	return false
+}
+
+func describeType(value any) string {
+	switch value.(type) {
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
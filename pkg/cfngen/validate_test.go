@@ -0,0 +1,223 @@
+package cfngen
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/common-fate/pdk/pkg/accessrole"
+	"github.com/common-fate/pdk/pkg/iamp"
+	"github.com/common-fate/pdk/pkg/pythonconfig"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     ValidationErrors
+	}{
+		{
+			name: "ok",
+			template: `{
+				"Parameters": {
+					"HandlerID": {"Type": "String", "MinLength": 1},
+					"RolePath": {"Type": "String", "Default": "/", "AllowedPattern": "^/.*$"}
+				},
+				"Conditions": {
+					"HasHandlerID": {"Fn::Not": [{"Fn::Equals": [{"Ref": "HandlerID"}, ""]}]}
+				},
+				"Resources": {
+					"Role": {
+						"Type": "AWS::IAM::Role",
+						"Properties": {
+							"AssumeRolePolicyDocument": {"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "sts:AssumeRole", "Condition": {"StringEquals": {"aws:PrincipalOrgID": "o-123"}}}]},
+							"RoleName": {"Fn::Sub": "${HandlerID}-${!Literal}"},
+							"Path": {"Ref": "RolePath"},
+							"MaxSessionDuration": "3600"
+						}
+					},
+					"Policy": {
+						"Type": "AWS::IAM::Policy",
+						"Condition": "HasHandlerID",
+						"DependsOn": ["Role"],
+						"Properties": {
+							"PolicyName": "policy",
+							"PolicyDocument": {},
+							"Roles": [{"Ref": "Role"}]
+						}
+					}
+				},
+				"Outputs": {
+					"RoleArn": {"Value": {"Fn::GetAtt": ["Role", "Arn"]}, "Export": {"Name": {"Fn::Sub": "${AWS::StackName}-RoleArn"}}}
+				}
+			}`,
+		},
+		{
+			name: "references",
+			template: `{
+				"Parameters": {"HandlerID": {"Type": "String"}},
+				"Conditions": {
+					"HasRole": {"Fn::Equals": [{"Ref": "Role"}, ""]},
+					"Other": {"Condition": "Missing"}
+				},
+				"Resources": {
+					"Role": {
+						"Type": "AWS::IAM::Role",
+						"DependsOn": "Missing",
+						"Properties": {
+							"AssumeRolePolicyDocument": {},
+							"RoleName": {"Fn::Sub": ["${Var}", {"Var": {"Ref": "HandlerID"}}]},
+							"Path": {"Fn::If": ["Missing", "/", "/"]}
+						}
+					}
+				},
+				"Outputs": {
+					"Arn": {"Value": {"Fn::GetAtt": "Missing.Arn"}, "Condition": "Missing"}
+				}
+			}`,
+			want: ValidationErrors{
+				{Path: "Conditions.HasRole.Fn::Equals[0]", Message: "references the resource Role, but conditions can only reference parameters"},
+				{Path: "Conditions.Other", Message: "the condition Missing isn't defined"},
+				{Path: "Outputs.Arn.Condition", Message: "the condition Missing isn't defined"},
+				{Path: "Outputs.Arn.Value", Message: "gets an attribute of Missing, which isn't a resource"},
+				{Path: "Resources.Role.DependsOn", Message: `depends on "Missing", which isn't a resource`},
+				{Path: "Resources.Role.Properties.Path", Message: "the condition Missing isn't defined"},
+			},
+		},
+		{
+			name: "sub variables",
+			template: `{
+				"Resources": {
+					"Role": {
+						"Type": "AWS::IAM::Role",
+						"Properties": {
+							"AssumeRolePolicyDocument": {},
+							"Description": {"Fn::Sub": "${Missing} ${Other.Arn} ${AWS::Foo}"}
+						}
+					}
+				}
+			}`,
+			want: ValidationErrors{
+				{Path: "Resources.Role.Properties.Description", Message: "references Missing, which isn't a parameter or resource"},
+				{Path: "Resources.Role.Properties.Description", Message: "gets an attribute of Other, which isn't a resource"},
+				{Path: "Resources.Role.Properties.Description", Message: "references AWS::Foo, which isn't a pseudo parameter"},
+			},
+		},
+		{
+			name: "parameters",
+			template: `{
+				"Parameters": {
+					"bad_name": {"Type": "String"},
+					"Retention": {"Type": "Number", "Default": "7", "AllowedValues": [1, 3]},
+					"Path": {"Type": "String", "Default": "path", "AllowedPattern": "^/.*$"},
+					"Name": {"Type": "Strin", "Default": "", "MinLength": 1}
+				}
+			}`,
+			want: ValidationErrors{
+				{Path: "Parameters.Name.Default", Message: `"" is shorter than the minimum length of 1`},
+				{Path: "Parameters.Name.Type", Message: `"Strin" isn't a valid parameter type`},
+				{Path: "Parameters.Path.Default", Message: `"path" doesn't match the allowed pattern ^/.*$`},
+				{Path: "Parameters.Retention.Default", Message: `"7" isn't one of the allowed values`},
+				{Path: "Parameters.bad_name", Message: `"bad_name" must only contain alphanumeric characters`},
+			},
+		},
+		{
+			name: "interface",
+			template: `{
+				"Metadata": {
+					"AWS::CloudFormation::Interface": {
+						"ParameterGroups": [
+							{"Label": {"default": "A"}, "Parameters": ["HandlerID", "Missing"]},
+							{"Label": {"default": "B"}, "Parameters": ["HandlerID"]}
+						],
+						"ParameterLabels": {"Other": {"default": "Other"}}
+					}
+				},
+				"Parameters": {"HandlerID": {"Type": "String"}}
+			}`,
+			want: ValidationErrors{
+				{Path: "Metadata.AWS::CloudFormation::Interface.ParameterGroups[0].Parameters[1]", Message: "Missing isn't a parameter"},
+				{Path: "Metadata.AWS::CloudFormation::Interface.ParameterGroups[1].Parameters[0]", Message: "HandlerID is in more than one group"},
+				{Path: "Metadata.AWS::CloudFormation::Interface.ParameterLabels.Other", Message: "Other isn't a parameter"},
+			},
+		},
+		{
+			name: "properties",
+			template: `{
+				"Parameters": {"HandlerID": {"Type": "String", "Default": "cf-handler-common-fate-a-very-long-provider-name"}},
+				"Resources": {
+					"Function": {
+						"Type": "AWS::Lambda::Function",
+						"Properties": {
+							"Code": "code.zip",
+							"Timeout": 1.5,
+							"Layers": ["arn", {"Arn": "arn"}, {"Ref": "HandlerID"}],
+							"Runtimee": "python3.9"
+						}
+					},
+					"Role": {
+						"Type": "AWS::IAM::Role",
+						"Properties": {
+							"AssumeRolePolicyDocument": {},
+							"RoleName": {"Fn::Join": ["", [{"Ref": "HandlerID"}, "-access-", "read-only-access"]]}
+						}
+					},
+					"Bucket": {"Type": "AWS::S3::Bucket", "Properties": {"Anything": 1}}
+				}
+			}`,
+			want: ValidationErrors{
+				{Path: "Resources.Function.Properties", Message: "the required property Role is missing"},
+				{Path: "Resources.Function.Properties.Code", Message: "must be of type object, but is a string"},
+				{Path: "Resources.Function.Properties.Layers[1]", Message: "must be of type string, but is an object"},
+				{Path: "Resources.Function.Properties.Runtimee", Message: "Runtimee isn't a property of AWS::Lambda::Function"},
+				{Path: "Resources.Function.Properties.Timeout", Message: "must be of type integer, but is a number"},
+				{Path: "Resources.Role.Properties.RoleName", Message: "is at least 72 characters long with the default parameter values, the maximum is 64"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate([]byte(tt.template))
+			if tt.want == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var got ValidationErrors
+			if !errors.As(err, &got) {
+				t.Fatalf("want ValidationErrors, got %v", err)
+			}
+			if !reflect.DeepEqual(sorted(got), sorted(tt.want)) {
+				t.Errorf("want\n%v\ngot\n%v", tt.want, got)
+			}
+		})
+	}
+}
+
+// sorted returns the error messages sorted, as the order of errors with the same path isn't stable.
+func sorted(errs ValidationErrors) []string {
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	sort.Strings(msgs)
+	return msgs
+}
+
+func TestGenerateAccessRoleNameTooLong(t *testing.T) {
+	pconfig := pythonconfig.Config{Publisher: "common-fate", Name: "aws-identity-center"}
+	role := accessrole.FromPolicy(iamp.NewPolicy(iamp.Statement{
+		Effect:   iamp.Allow,
+		Action:   iamp.Value{"s3:ListBucket"},
+		Resource: iamp.Value{"*"},
+	}))
+
+	_, err := GenerateAccessRole(pconfig, "permission-set-administrator", role)
+	if err == nil || !strings.Contains(err.Error(), "Resources.Role.Properties.RoleName: is at least 78 characters long") {
+		t.Errorf("want role name length error, got %v", err)
+	}
+}
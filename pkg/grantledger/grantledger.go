@@ -0,0 +1,199 @@
+// Package grantledger records the grants made by 'pdk run' in a local file,
+// so that they can be revoked later without retyping their arguments and state.
+package grantledger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+)
+
+// DefaultDir is the directory of the ledger, relative to the Provider.
+const DefaultDir = ".pdk"
+
+// fileName is the name of the ledger file in the directory.
+const fileName = "grants.json"
+
+// ErrNotFound is returned when a grant isn't in the ledger.
+var ErrNotFound = errors.New("grant not found in the ledger")
+
+// Grant is an entry in the ledger.
+type Grant struct {
+	RequestID string            `json:"requestId"`
+	Subject   string            `json:"subject"`
+	Kind      string            `json:"kind"`
+	Args      map[string]string `json:"args"`
+	// State is returned by the Provider when access is granted. It's stored as
+	// JSON, so that it's passed back to the Provider with the same types on revoke.
+	State     map[string]any `json:"state,omitempty"`
+	GrantedAt time.Time      `json:"grantedAt"`
+	RevokedAt *time.Time     `json:"revokedAt,omitempty"`
+}
+
+// Active returns true if the grant hasn't been revoked.
+func (g Grant) Active() bool {
+	return g.RevokedAt == nil
+}
+
+// RevokeRequest returns the request which revokes the grant.
+func (g Grant) RevokeRequest() msg.Revoke {
+	return msg.Revoke{
+		Subject: g.Subject,
+		Target:  msg.Target{Kind: g.Kind, Arguments: g.Args},
+		Request: msg.AccessRequest{ID: g.RequestID},
+		State:   g.State,
+	}
+}
+
+// FromRequest creates a ledger entry for a grant request and the state returned by the Provider.
+func FromRequest(req msg.Grant, state map[string]any, grantedAt time.Time) Grant {
+	return Grant{
+		RequestID: req.Request.ID,
+		Subject:   req.Subject,
+		Kind:      req.Target.Kind,
+		Args:      req.Target.Arguments,
+		State:     state,
+		GrantedAt: grantedAt.UTC(),
+	}
+}
+
+// Ledger is a JSON file of grants.
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Open returns the ledger in the directory. The file is created when the first grant is recorded.
+func Open(dir string) *Ledger {
+	return &Ledger{path: filepath.Join(dir, fileName)}
+}
+
+type file struct {
+	Grants []Grant `json:"grants"`
+}
+
+// Record adds a grant to the ledger. A grant with the same request ID is replaced.
+func (l *Ledger) Record(g Grant) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := l.read()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range f.Grants {
+		if f.Grants[i].RequestID == g.RequestID {
+			f.Grants[i] = g
+			replaced = true
+		}
+	}
+	if !replaced {
+		f.Grants = append(f.Grants, g)
+	}
+	return l.write(f)
+}
+
+// Get returns the grant with the request ID, or ErrNotFound.
+func (l *Ledger) Get(requestID string) (Grant, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := l.read()
+	if err != nil {
+		return Grant{}, err
+	}
+	for _, g := range f.Grants {
+		if g.RequestID == requestID {
+			return g, nil
+		}
+	}
+	return Grant{}, fmt.Errorf("%w: %s", ErrNotFound, requestID)
+}
+
+// Active returns the grants which haven't been revoked, oldest first.
+func (l *Ledger) Active() ([]Grant, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	var active []Grant
+	for _, g := range f.Grants {
+		if g.Active() {
+			active = append(active, g)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].GrantedAt.Before(active[j].GrantedAt)
+	})
+	return active, nil
+}
+
+// MarkRevoked records that the grant was revoked. It returns ErrNotFound if the grant isn't in the ledger.
+func (l *Ledger) MarkRevoked(requestID string, revokedAt time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := l.read()
+	if err != nil {
+		return err
+	}
+	for i := range f.Grants {
+		if f.Grants[i].RequestID == requestID {
+			t := revokedAt.UTC()
+			f.Grants[i].RevokedAt = &t
+			return l.write(f)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, requestID)
+}
+
+func (l *Ledger) read() (file, error) {
+	var f file
+	b, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return f, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	// keep numbers in the state as they were returned, rather than converting them to floats.
+	dec.UseNumber()
+	err = dec.Decode(&f)
+	if err != nil {
+		return f, fmt.Errorf("reading grant ledger %s: %w", l.path, err)
+	}
+	return f, nil
+}
+
+// write replaces the ledger file, via a temporary file so that it isn't left partially written.
+func (l *Ledger) write(f file) error {
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(l.path), 0755)
+	if err != nil {
+		return err
+	}
+	tmp := l.path + ".tmp"
+	err = os.WriteFile(tmp, b, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
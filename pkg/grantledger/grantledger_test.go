@@ -0,0 +1,74 @@
+package grantledger
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+)
+
+func TestLedger(t *testing.T) {
+	l := Open(t.TempDir())
+	t0 := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	active, err := l.Active()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 0 {
+		t.Fatalf("expected an empty ledger, got %v", active)
+	}
+
+	grant := msg.Grant{
+		Subject: "alice@example.com",
+		Target:  msg.Target{Kind: "Group", Arguments: map[string]string{"group": "admins"}},
+		Request: msg.AccessRequest{ID: "req_1"},
+	}
+	// the state has types which would be lost if it was passed as string flags.
+	state := map[string]any{"membershipId": json.Number("9007199254740993"), "nested": map[string]any{"enabled": true}}
+
+	err = l.Record(FromRequest(grant, state, t0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.Record(FromRequest(msg.Grant{Subject: "bob@example.com", Target: msg.Target{Kind: "Group"}, Request: msg.AccessRequest{ID: "req_2"}}, nil, t0.Add(time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := l.Get("req_1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoke, err := json.Marshal(got.RevokeRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"subject":"alice@example.com","target":{"kind":"Group","arguments":{"group":"admins"}},"request":{"id":"req_1"},"state":{"membershipId":9007199254740993,"nested":{"enabled":true}}}`
+	if string(revoke) != want {
+		t.Errorf("want revoke request\n%s\ngot\n%s", want, revoke)
+	}
+
+	err = l.MarkRevoked("req_1", t0.Add(2*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, err = l.Active()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 1 || active[0].RequestID != "req_2" {
+		t.Errorf("expected only req_2 to be active, got %v", active)
+	}
+
+	_, err = l.Get("req_3")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("want ErrNotFound got %v", err)
+	}
+	err = l.MarkRevoked("req_3", t0)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("want ErrNotFound got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+// Package handlerserver serves a Provider over HTTP, so that it can be called
+// in the same way as a deployed handler while running locally.
+//
+// Payloads can be sent to two endpoints:
+//
+//	POST /                                              the Lambda payload, e.g. {"type": "describe", "data": {}}
+//	POST /2015-03-31/functions/{function}/invocations   the Lambda Invoke API
+//
+// The Invoke API allows the AWS SDK to call the server by overriding the Lambda endpoint.
+package handlerserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/common-fate/clio"
+	"github.com/common-fate/pdk/pkg/lambdainvoke"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/handlerclient"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+)
+
+// invokePathPrefix is the path prefix of the Lambda Invoke API.
+const invokePathPrefix = "/2015-03-31/functions/"
+
+// Server is an http.Handler which runs requests with the Executor.
+// Requests are handled concurrently.
+type Server struct {
+	Executor handlerclient.Executor
+
+	// count is used to number requests in the logs.
+	count atomic.Int64
+}
+
+// errorResponse is returned when the handler fails, in the same format as the Lambda runtime.
+type errorResponse struct {
+	ErrorMessage string `json:"errorMessage"`
+	ErrorType    string `json:"errorType"`
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := s.count.Add(1)
+	start := time.Now()
+
+	status, requestType := s.serve(w, r)
+
+	clio.Infow("handled request", "id", id, "method", r.Method, "path", r.URL.Path, "type", requestType, "status", status, "duration", time.Since(start).String())
+}
+
+// serve handles the request and returns the status code and the type of the handler request, for logging.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) (int, msg.RequestType) {
+	invokeAPI := strings.HasPrefix(r.URL.Path, invokePathPrefix) && strings.HasSuffix(r.URL.Path, "/invocations")
+	if r.URL.Path != "/" && !invokeAPI {
+		return writeJSON(w, http.StatusNotFound, errorResponse{ErrorType: "NotFound", ErrorMessage: "use POST / or the Lambda Invoke API"}), ""
+	}
+	if r.Method != http.MethodPost {
+		return writeJSON(w, http.StatusMethodNotAllowed, errorResponse{ErrorType: "MethodNotAllowed", ErrorMessage: "only POST is supported"}), ""
+	}
+	if invokeAPI && r.Header.Get("X-Amz-Invocation-Type") == "Event" {
+		// asynchronous invocations aren't supported, as their response would be lost.
+		return writeJSON(w, http.StatusBadRequest, errorResponse{ErrorType: "InvalidParameterValueException", ErrorMessage: "only RequestResponse invocations are supported"}), ""
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return writeJSON(w, http.StatusBadRequest, errorResponse{ErrorType: "InvalidRequestContentException", ErrorMessage: err.Error()}), ""
+	}
+
+	request, err := lambdainvoke.DecodePayload(body)
+	if err != nil {
+		return writeJSON(w, http.StatusBadRequest, errorResponse{ErrorType: "InvalidRequestContentException", ErrorMessage: fmt.Sprintf("invalid payload: %s", err)}), ""
+	}
+
+	res, err := s.Executor.Execute(r.Context(), request)
+	if err != nil {
+		fe := errorResponse{ErrorType: "Error", ErrorMessage: err.Error()}
+		if invokeAPI {
+			// the Invoke API succeeds when the function fails, and reports the error in a header.
+			w.Header().Set("X-Amz-Function-Error", "Unhandled")
+			return writeJSON(w, http.StatusOK, fe), request.Type()
+		}
+		return writeJSON(w, http.StatusInternalServerError, fe), request.Type()
+	}
+
+	if invokeAPI {
+		w.Header().Set("X-Amz-Executed-Version", "$LATEST")
+	}
+	return writeJSON(w, http.StatusOK, res), request.Type()
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) int {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		clio.Errorf("error writing response: %s", err)
+	}
+	return status
+}
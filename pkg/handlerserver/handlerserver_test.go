@@ -0,0 +1,115 @@
+package handlerserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/common-fate/pdk/pkg/lambdainvoke"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+)
+
+type fakeExecutor struct{}
+
+func (fakeExecutor) Execute(ctx context.Context, request msg.Request) (*msg.Result, error) {
+	if g, ok := request.(msg.Grant); ok && g.Subject == "mallory@example.com" {
+		return nil, errors.New("user not found")
+	}
+	return &msg.Result{Response: json.RawMessage(`{"ok":true}`)}, nil
+}
+
+func TestServePayload(t *testing.T) {
+	srv := httptest.NewServer(&Server{Executor: fakeExecutor{}})
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "describe",
+			path:       "/",
+			body:       `{"type":"describe","data":{}}`,
+			wantStatus: http.StatusOK,
+			wantBody:   `{"response":{"ok":true}}`,
+		},
+		{
+			name:       "handler error",
+			path:       "/",
+			body:       `{"type":"grant","data":{"subject":"mallory@example.com","target":{"kind":"Group"},"request":{"id":"req_1"}}}`,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"errorMessage":"user not found","errorType":"Error"}`,
+		},
+		{
+			name:       "invalid payload",
+			path:       "/",
+			body:       `{"type":"delete"}`,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"errorMessage":"invalid payload: unknown request type \"delete\", must be one of grant, revoke, describe or load","errorType":"InvalidRequestContentException"}`,
+		},
+		{
+			name:       "unknown path",
+			path:       "/other",
+			body:       `{}`,
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"errorMessage":"use POST / or the Lambda Invoke API","errorType":"NotFound"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Post(srv.URL+tt.path, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != tt.wantStatus {
+				t.Errorf("want status %d got %d", tt.wantStatus, res.StatusCode)
+			}
+			if strings.TrimSpace(string(body)) != tt.wantBody {
+				t.Errorf("want body %s got %s", tt.wantBody, body)
+			}
+		})
+	}
+}
+
+// TestLambdaInvokeAPI calls the server with the AWS SDK Lambda client, in the same way as a deployed handler.
+func TestLambdaInvokeAPI(t *testing.T) {
+	srv := httptest.NewServer(&Server{Executor: fakeExecutor{}})
+	defer srv.Close()
+
+	cfg := aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: srv.URL}, nil
+		}),
+	}
+	e := lambdainvoke.New(cfg, "cf-handler-local", "")
+
+	res, err := e.Execute(context.Background(), msg.Describe{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Response) != `{"ok":true}` {
+		t.Errorf("unexpected response %s", res.Response)
+	}
+
+	_, err = e.Execute(context.Background(), msg.Grant{Subject: "mallory@example.com"})
+	if err == nil || err.Error() != "the handler returned an error: Error: user not found" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// Package handlerstack resolves a deployed Provider handler from the
+// outputs of the CloudFormation stack generated by cfngen.Generate.
+package handlerstack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/common-fate/pdk/pkg/cfngen/ref"
+)
+
+// DescribeStacksAPI is the subset of the CloudFormation client used by Resolve.
+type DescribeStacksAPI interface {
+	DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error)
+}
+
+// Handler is a Provider handler deployed with CloudFormation.
+type Handler struct {
+	StackName         string
+	LambdaFunctionArn string
+	LambdaRoleArn     string
+	// InvokeRoleArn is the role which Common Fate assumes to invoke the Lambda function.
+	InvokeRoleArn string
+	LogGroupName  string
+}
+
+// FunctionName returns the name of the Lambda function, from its ARN.
+func (h Handler) FunctionName() string {
+	// arn:aws:lambda:us-east-1:123456789012:function:cf-handler-example
+	parts := strings.Split(h.LambdaFunctionArn, ":")
+	if len(parts) < 7 {
+		return h.LambdaFunctionArn
+	}
+	return parts[6]
+}
+
+// Resolve looks up a handler from the outputs of its CloudFormation stack.
+func Resolve(ctx context.Context, client DescribeStacksAPI, stackName string) (Handler, error) {
+	out, err := client.DescribeStacks(ctx, &cloudformation.DescribeStacksInput{
+		StackName: &stackName,
+	})
+	if err != nil {
+		return Handler{}, fmt.Errorf("describing handler stack %s: %w", stackName, err)
+	}
+	if len(out.Stacks) == 0 {
+		return Handler{}, fmt.Errorf("handler stack %s was not found", stackName)
+	}
+
+	outputs := map[string]string{}
+	for _, o := range out.Stacks[0].Outputs {
+		outputs[aws.ToString(o.OutputKey)] = aws.ToString(o.OutputValue)
+	}
+
+	h := Handler{StackName: stackName}
+	fields := []struct {
+		output string
+		dst    *string
+	}{
+		{ref.LambdaFunctionArnOutput, &h.LambdaFunctionArn},
+		{ref.LambdaRoleArnOutput, &h.LambdaRoleArn},
+		{ref.LambdaInvocationRoleArnOutput, &h.InvokeRoleArn},
+		{ref.LogGroupNameOutput, &h.LogGroupName},
+	}
+	for _, f := range fields {
+		v, ok := outputs[f.output]
+		if !ok {
+			return Handler{}, fmt.Errorf("handler stack %s doesn't have a %s output: redeploy it with a template generated by a newer version of pdk", stackName, f.output)
+		}
+		*f.dst = v
+	}
+	return h, nil
+}
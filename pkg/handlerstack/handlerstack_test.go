@@ -0,0 +1,147 @@
+package handlerstack
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logstypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+type fakeCloudFormation struct {
+	outputs map[string]string
+}
+
+func (f fakeCloudFormation) DescribeStacks(ctx context.Context, params *cloudformation.DescribeStacksInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStacksOutput, error) {
+	var outputs []types.Output
+	for k, v := range f.outputs {
+		outputs = append(outputs, types.Output{OutputKey: aws.String(k), OutputValue: aws.String(v)})
+	}
+	return &cloudformation.DescribeStacksOutput{
+		Stacks: []types.Stack{{StackName: params.StackName, Outputs: outputs}},
+	}, nil
+}
+
+func TestResolve(t *testing.T) {
+	client := fakeCloudFormation{outputs: map[string]string{
+		"LambdaFunctionArn":       "arn:aws:lambda:us-east-1:123456789012:function:cf-handler-example",
+		"LambdaRoleArn":           "arn:aws:iam::123456789012:role/cf-handler-example",
+		"LambdaInvocationRoleArn": "arn:aws:iam::123456789012:role/cf-handler-example-invoke",
+		"LogGroupName":            "/aws/lambda/cf-handler-example",
+	}}
+
+	got, err := Resolve(context.Background(), client, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Handler{
+		StackName:         "example",
+		LambdaFunctionArn: "arn:aws:lambda:us-east-1:123456789012:function:cf-handler-example",
+		LambdaRoleArn:     "arn:aws:iam::123456789012:role/cf-handler-example",
+		InvokeRoleArn:     "arn:aws:iam::123456789012:role/cf-handler-example-invoke",
+		LogGroupName:      "/aws/lambda/cf-handler-example",
+	}
+	if got != want {
+		t.Errorf("want %+v got %+v", want, got)
+	}
+	if got.FunctionName() != "cf-handler-example" {
+		t.Errorf("unexpected function name %s", got.FunctionName())
+	}
+
+	delete(client.outputs, "LogGroupName")
+	_, err = Resolve(context.Background(), client, "example")
+	if err == nil {
+		t.Error("expected an error for a stack without outputs")
+	}
+}
+
+type fakeStackResources struct {
+	resources []types.StackResource
+	err       error
+}
+
+func (f fakeStackResources) DescribeStackResources(ctx context.Context, params *cloudformation.DescribeStackResourcesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackResourcesOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &cloudformation.DescribeStackResourcesOutput{StackResources: f.resources}, nil
+}
+
+// fakeLogGroups returns the log groups which match the prefix.
+type fakeLogGroups struct {
+	groups []string
+}
+
+func (f fakeLogGroups) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	var out cloudwatchlogs.DescribeLogGroupsOutput
+	for _, g := range f.groups {
+		if strings.HasPrefix(g, aws.ToString(params.LogGroupNamePrefix)) {
+			out.LogGroups = append(out.LogGroups, logstypes.LogGroup{LogGroupName: aws.String(g)})
+		}
+	}
+	return &out, nil
+}
+
+func (f fakeLogGroups) DeleteLogGroup(ctx context.Context, params *cloudwatchlogs.DeleteLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error) {
+	return &cloudwatchlogs.DeleteLogGroupOutput{}, nil
+}
+
+func TestUnmanagedLogGroup(t *testing.T) {
+	logs := fakeLogGroups{groups: []string{"/aws/lambda/cf-handler-example", "/aws/lambda/cf-handler-example-2"}}
+
+	tests := []struct {
+		name      string
+		client    fakeStackResources
+		handlerID string
+		want      bool
+		wantErr   bool
+	}{
+		{
+			name:      "stack without a log group",
+			client:    fakeStackResources{resources: []types.StackResource{{LogicalResourceId: aws.String("LambdaFunction")}}},
+			handlerID: "cf-handler-example",
+			want:      true,
+		},
+		{
+			name:      "new stack",
+			client:    fakeStackResources{err: errors.New("ValidationError: Stack with id cf-handler-example does not exist")},
+			handlerID: "cf-handler-example",
+			want:      true,
+		},
+		{
+			name:      "log group in the stack",
+			client:    fakeStackResources{resources: []types.StackResource{{LogicalResourceId: aws.String("LogGroup")}}},
+			handlerID: "cf-handler-example",
+			want:      false,
+		},
+		{
+			name:      "no log group",
+			client:    fakeStackResources{},
+			handlerID: "cf-handler-other",
+			want:      false,
+		},
+		{
+			name:      "access denied",
+			client:    fakeStackResources{err: errors.New("AccessDenied")},
+			handlerID: "cf-handler-example",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmanagedLogGroup(context.Background(), tt.client, logs, tt.handlerID, tt.handlerID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("want %v got %v", tt.want, got)
+			}
+		})
+	}
+}
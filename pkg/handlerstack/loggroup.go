@@ -0,0 +1,72 @@
+package handlerstack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/common-fate/pdk/pkg/cfngen/ref"
+)
+
+// DescribeStackResourcesAPI is the subset of the CloudFormation client used by UnmanagedLogGroup.
+type DescribeStackResourcesAPI interface {
+	DescribeStackResources(ctx context.Context, params *cloudformation.DescribeStackResourcesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackResourcesOutput, error)
+}
+
+// LogGroupsAPI is the subset of the CloudWatch Logs client used by UnmanagedLogGroup and DeleteLogGroup.
+type LogGroupsAPI interface {
+	DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error)
+	DeleteLogGroup(ctx context.Context, params *cloudwatchlogs.DeleteLogGroupInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error)
+}
+
+// LogGroupName returns the name of the log group of a handler, which Lambda uses by default.
+func LogGroupName(handlerID string) string {
+	return "/aws/lambda/" + handlerID
+}
+
+// UnmanagedLogGroup returns true if the handler's log group exists but isn't part of the stack.
+//
+// Lambda creates the log group when the function is first invoked, so handlers deployed with
+// templates from before the log group was added to the stack already have one. Deploying the
+// stack then fails, as CloudFormation can't create a log group which already exists.
+func UnmanagedLogGroup(ctx context.Context, client DescribeStackResourcesAPI, logs LogGroupsAPI, stackName string, handlerID string) (bool, error) {
+	out, err := client.DescribeStackResources(ctx, &cloudformation.DescribeStackResourcesInput{
+		StackName: &stackName,
+	})
+	// CloudFormation returns a ValidationError if the stack doesn't exist yet.
+	if err != nil && !strings.Contains(err.Error(), "does not exist") {
+		return false, fmt.Errorf("describing handler stack %s: %w", stackName, err)
+	}
+	if out != nil {
+		for _, r := range out.StackResources {
+			if aws.ToString(r.LogicalResourceId) == ref.LogGroup {
+				return false, nil
+			}
+		}
+	}
+
+	name := LogGroupName(handlerID)
+	groups, err := logs.DescribeLogGroups(ctx, &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: aws.String(name),
+	})
+	if err != nil {
+		return false, fmt.Errorf("describing log group %s: %w", name, err)
+	}
+	for _, g := range groups.LogGroups {
+		if aws.ToString(g.LogGroupName) == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// DeleteLogGroup deletes the handler's log group, including its log events.
+func DeleteLogGroup(ctx context.Context, logs LogGroupsAPI, handlerID string) error {
+	_, err := logs.DeleteLogGroup(ctx, &cloudwatchlogs.DeleteLogGroupInput{
+		LogGroupName: aws.String(LogGroupName(handlerID)),
+	})
+	return err
+}
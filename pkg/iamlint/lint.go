@@ -0,0 +1,276 @@
+// Package iamlint checks IAM policies for common security problems, such as
+// wildcard permissions and actions which allow privilege escalation.
+package iamlint
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/common-fate/pdk/pkg/iamp"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+	// SeverityNone is used to disable a rule, or as a FailOn threshold which never fails.
+	SeverityNone Severity = "none"
+)
+
+// rank orders severities so that they can be compared to the FailOn threshold.
+func (s Severity) rank() int {
+	switch s {
+	case SeverityError:
+		return 3
+	case SeverityWarning:
+		return 2
+	case SeverityNote:
+		return 1
+	}
+	return 0
+}
+
+// AtLeast returns true if the severity is at least as severe as the threshold.
+func (s Severity) AtLeast(threshold Severity) bool {
+	if threshold == SeverityNone {
+		return false
+	}
+	return s.rank() >= threshold.rank()
+}
+
+func parseSeverity(s string) (Severity, error) {
+	switch sev := Severity(s); sev {
+	case SeverityError, SeverityWarning, SeverityNote, SeverityNone:
+		return sev, nil
+	}
+	return "", fmt.Errorf("invalid severity %s: must be one of error, warning, note, none", s)
+}
+
+// Config configures the linter. It is read from the [lint] table in provider.toml:
+//
+//	[lint]
+//	fail_on = "error"
+//	disable = ["IAM005"]
+//
+//	[lint.severity]
+//	IAM002 = "error"
+//
+//	[[lint.suppress]]
+//	rule = "IAM002"
+//	location = "roles/read.*"
+//	sid = "ListAllBuckets"
+//	reason = "s3:ListAllMyBuckets does not support resource-level permissions"
+type Config struct {
+	// FailOn is the minimum severity which causes linting to fail. Defaults to 'error'.
+	FailOn string `toml:"fail_on"`
+	// Disable is a list of rule IDs which are not run.
+	Disable []string `toml:"disable"`
+	// Severity overrides the default severity of rules.
+	Severity map[string]string `toml:"severity"`
+	// Suppress ignores findings for specific rules and policies.
+	Suppress []Suppression `toml:"suppress"`
+}
+
+// Suppression ignores findings matching a rule, and optionally a location and statement ID.
+type Suppression struct {
+	Rule string `toml:"rule"`
+	// Location is a glob matching the location of the policy, e.g. 'roles/*'.
+	Location string `toml:"location"`
+	Sid      string `toml:"sid"`
+	Reason   string `toml:"reason"`
+}
+
+func (s Suppression) matches(f Finding) bool {
+	if s.Rule != f.RuleID {
+		return false
+	}
+	if s.Sid != "" && s.Sid != f.Sid {
+		return false
+	}
+	if s.Location != "" {
+		ok, err := path.Match(s.Location, f.Location)
+		if err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate checks that the configuration refers to known rules and severities.
+func (c Config) Validate() error {
+	if c.FailOn != "" {
+		if _, err := parseSeverity(c.FailOn); err != nil {
+			return fmt.Errorf("invalid lint fail_on: %w", err)
+		}
+	}
+	for _, id := range c.Disable {
+		if _, ok := ruleByID(id); !ok {
+			return fmt.Errorf("unknown lint rule %s in disable", id)
+		}
+	}
+	for id, sev := range c.Severity {
+		if _, ok := ruleByID(id); !ok {
+			return fmt.Errorf("unknown lint rule %s in severity", id)
+		}
+		if _, err := parseSeverity(sev); err != nil {
+			return fmt.Errorf("invalid severity for lint rule %s: %w", id, err)
+		}
+	}
+	for _, s := range c.Suppress {
+		if _, ok := ruleByID(s.Rule); !ok {
+			return fmt.Errorf("unknown lint rule %s in suppress", s.Rule)
+		}
+		if s.Location != "" {
+			if _, err := path.Match(s.Location, ""); err != nil {
+				return fmt.Errorf("invalid suppression location %s: %w", s.Location, err)
+			}
+		}
+	}
+	return nil
+}
+
+// failOn returns the FailOn threshold, defaulting to errors.
+func (c Config) failOn() Severity {
+	if c.FailOn == "" {
+		return SeverityError
+	}
+	return Severity(c.FailOn)
+}
+
+func (c Config) severity(r Rule) Severity {
+	for _, id := range c.Disable {
+		if id == r.ID {
+			return SeverityNone
+		}
+	}
+	if sev, ok := c.Severity[r.ID]; ok {
+		return Severity(sev)
+	}
+	return r.DefaultSeverity
+}
+
+// Policy is a policy to be linted.
+type Policy struct {
+	// Location identifies where the policy came from, e.g. 'roles/read.json#access-policy'.
+	Location string
+	// File is the path of the file containing the policy, used for SARIF output.
+	File   string
+	Policy iamp.Policy
+}
+
+type Finding struct {
+	RuleID   string
+	Severity Severity
+	Message  string
+	Location string
+	File     string
+	// Statement is the index of the statement in the policy.
+	Statement int
+	Sid       string
+	// Suppressed is true if the finding matched a suppression in the config.
+	Suppressed bool
+}
+
+func (f Finding) String() string {
+	stmt := fmt.Sprintf("statement %d", f.Statement)
+	if f.Sid != "" {
+		stmt = fmt.Sprintf("statement %q", f.Sid)
+	}
+	return fmt.Sprintf("%s: %s (%s) %s: %s", f.Severity, f.Location, stmt, f.RuleID, f.Message)
+}
+
+// Result contains the findings from linting a set of policies.
+type Result struct {
+	Findings []Finding
+	failOn   Severity
+}
+
+// Failed returns true if any unsuppressed findings are at or above the FailOn threshold.
+func (r Result) Failed() bool {
+	for _, f := range r.Findings {
+		if !f.Suppressed && f.Severity.AtLeast(r.failOn) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint runs the enabled rules against the policies.
+func Lint(cfg Config, policies ...Policy) (Result, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return Result{}, err
+	}
+
+	res := Result{failOn: cfg.failOn()}
+
+	for _, p := range policies {
+		for _, r := range Rules {
+			sev := cfg.severity(r)
+			if sev == SeverityNone {
+				continue
+			}
+			for _, f := range r.check(p.Policy) {
+				f.RuleID = r.ID
+				f.Severity = sev
+				f.Location = p.Location
+				f.File = p.File
+				f.Sid = p.Policy.Statements[f.Statement].Sid
+				for _, s := range cfg.Suppress {
+					if s.matches(f) {
+						f.Suppressed = true
+						break
+					}
+				}
+				res.Findings = append(res.Findings, f)
+			}
+		}
+	}
+
+	sort.SliceStable(res.Findings, func(i, j int) bool {
+		a, b := res.Findings[i], res.Findings[j]
+		if a.Location != b.Location {
+			return a.Location < b.Location
+		}
+		return a.Statement < b.Statement
+	})
+
+	return res, nil
+}
+
+// hasValue returns true if the value contains the string.
+func hasValue(v iamp.Value, s string) bool {
+	for _, item := range v {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// grantsAction returns true if the statement's actions include the action.
+func grantsAction(s iamp.Statement, action string) bool {
+	if len(s.NotAction) > 0 {
+		for _, p := range s.NotAction {
+			if iamp.MatchAction(p, action) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, p := range s.Action {
+		if iamp.MatchAction(p, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWildcardService returns true for actions such as 'iam:*'.
+func isWildcardService(action string) bool {
+	return strings.HasSuffix(action, ":*")
+}
@@ -0,0 +1,332 @@
+package iamlint
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/common-fate/pdk/pkg/cfngen"
+	"github.com/common-fate/pdk/pkg/iamp"
+	"github.com/common-fate/pdk/pkg/pythonconfig"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/providerregistrysdk"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		statements []iamp.Statement
+		want       []string
+	}{
+		{
+			name: "scoped policy",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"s3:GetObject"}, Resource: iamp.Value{"arn:aws:s3:::bucket/*"}},
+			},
+		},
+		{
+			name: "wildcard action and resource",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"s3:*"}, Resource: iamp.Value{"*"}},
+			},
+			want: []string{"IAM001", "IAM002"},
+		},
+		{
+			name: "privilege escalation",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"iam:CreatePolicyVersion"}, Resource: iamp.Value{"arn:aws:iam::123456789012:policy/example"}},
+			},
+			want: []string{"IAM003"},
+		},
+		{
+			name: "privilege escalation through wildcard",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"iam:Put*"}, Resource: iamp.Value{"arn:aws:iam::123456789012:role/example"}},
+			},
+			want: []string{"IAM003"},
+		},
+		{
+			name: "pass role on all resources",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"iam:PassRole"}, Resource: iamp.Value{"*"}},
+			},
+			want: []string{"IAM002", "IAM003"},
+		},
+		{
+			name: "pass role on a specific role",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"iam:PassRole"}, Resource: iamp.Value{"arn:aws:iam::123456789012:role/example"}},
+			},
+		},
+		{
+			name: "assume role without condition",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"sts:AssumeRole"}, Resource: iamp.Value{"arn:aws:iam::123456789012:role/example"}},
+			},
+			want: []string{"IAM004"},
+		},
+		{
+			name: "assume role with condition",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"sts:AssumeRole"}, Resource: iamp.Value{"*"}, Condition: iamp.Condition{"StringEquals": {"iam:ResourceTag/team": {"platform"}}}},
+			},
+		},
+		{
+			name: "service trust policies are ignored",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"sts:AssumeRole"}, Principal: iamp.Principal{"Service": {"lambda.amazonaws.com"}}},
+			},
+		},
+		{
+			name: "trust policy without condition",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"sts:AssumeRole"}, Principal: iamp.Principal{"AWS": {"arn:aws:iam::123456789012:root"}}},
+			},
+			want: []string{"IAM004"},
+		},
+		{
+			name: "trust policy with external ID",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"sts:AssumeRole"}, Principal: iamp.Principal{"AWS": {"arn:aws:iam::123456789012:root"}}, Condition: iamp.Condition{"StringEquals": {"sts:ExternalId": {"example"}}}},
+			},
+		},
+		{
+			name: "overridden by deny",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"s3:Get*"}, Resource: iamp.Value{"arn:aws:s3:::bucket/*"}},
+				{Effect: iamp.Deny, Action: iamp.Value{"s3:*"}, Resource: iamp.Value{"arn:aws:s3:::bucket/*"}},
+			},
+			want: []string{"IAM005"},
+		},
+		{
+			name: "partially overridden by deny",
+			statements: []iamp.Statement{
+				{Effect: iamp.Allow, Action: iamp.Value{"s3:GetObject", "s3:PutObject"}, Resource: iamp.Value{"arn:aws:s3:::bucket/*"}},
+				{Effect: iamp.Deny, Action: iamp.Value{"s3:PutObject"}, Resource: iamp.Value{"arn:aws:s3:::bucket/*"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Lint(Config{}, Policy{Location: "test", Policy: iamp.NewPolicy(tt.statements...)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range res.Findings {
+				got = append(got, f.RuleID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("want findings %v got %v", tt.want, res.Findings)
+			}
+		})
+	}
+}
+
+func TestConfig(t *testing.T) {
+	policy := Policy{
+		Location: "roles/read.json#access-policy",
+		File:     "roles/read.json",
+		Policy: iamp.NewPolicy(
+			iamp.Statement{Sid: "ListAllBuckets", Effect: iamp.Allow, Action: iamp.Value{"s3:ListAllMyBuckets"}, Resource: iamp.Value{"*"}},
+			iamp.Statement{Effect: iamp.Allow, Action: iamp.Value{"iam:AttachRolePolicy"}, Resource: iamp.Value{"arn:aws:iam::123456789012:role/example"}},
+		),
+	}
+
+	tests := []struct {
+		name       string
+		cfg        Config
+		wantFailed bool
+		wantErr    bool
+		// wantActive are the rule IDs of the findings which are not suppressed.
+		wantActive []string
+	}{
+		{
+			name:       "defaults",
+			wantFailed: true,
+			wantActive: []string{"IAM002", "IAM003"},
+		},
+		{
+			name: "suppressed",
+			cfg: Config{
+				Suppress: []Suppression{
+					{Rule: "IAM002", Location: "roles/*", Sid: "ListAllBuckets"},
+					{Rule: "IAM003"},
+				},
+			},
+		},
+		{
+			name: "suppression for another sid",
+			cfg: Config{
+				Suppress: []Suppression{{Rule: "IAM002", Sid: "Other"}},
+			},
+			wantFailed: true,
+			wantActive: []string{"IAM002", "IAM003"},
+		},
+		{
+			name:       "disabled and severity override",
+			cfg:        Config{Disable: []string{"IAM003"}, Severity: map[string]string{"IAM002": "error"}},
+			wantFailed: true,
+			wantActive: []string{"IAM002"},
+		},
+		{
+			name:       "fail on none",
+			cfg:        Config{FailOn: "none"},
+			wantActive: []string{"IAM002", "IAM003"},
+		},
+		{
+			name:    "unknown rule",
+			cfg:     Config{Disable: []string{"IAM999"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Lint(tt.cfg, policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if res.Failed() != tt.wantFailed {
+				t.Errorf("want failed %v got %v", tt.wantFailed, res.Failed())
+			}
+			var active []string
+			for _, f := range res.Findings {
+				if !f.Suppressed {
+					active = append(active, f.RuleID)
+				}
+			}
+			if !reflect.DeepEqual(active, tt.wantActive) {
+				t.Errorf("want active findings %v got %v", tt.wantActive, active)
+			}
+		})
+	}
+}
+
+func TestTemplatePolicies(t *testing.T) {
+	template := `{
+  "Resources": {
+    "LambdaRole": {
+      "Type": "AWS::IAM::Role",
+      "Properties": {
+        "AssumeRolePolicyDocument": {
+          "Version": "2012-10-17",
+          "Statement": [{"Effect": "Allow", "Action": "sts:AssumeRole", "Principal": {"Service": "lambda.amazonaws.com"}}]
+        },
+        "Policies": [
+          {
+            "PolicyName": "handler-policy",
+            "PolicyDocument": {
+              "Version": "2012-10-17",
+              "Statement": [
+                {"Effect": "Allow", "Action": "kms:Decrypt", "Resource": {"Ref": "KmsKeyArn"}},
+                {"Fn::If": [
+                  "UseSecretsManager",
+                  {"Effect": "Allow", "Action": "secretsmanager:GetSecretValue", "Resource": {"Fn::Join": ["", ["arn:", {"Ref": "AWS::Partition"}, ":secretsmanager:*"]]}},
+                  {"Ref": "AWS::NoValue"}
+                ]}
+              ]
+            }
+          }
+        ]
+      }
+    }
+  }
+}`
+
+	got, err := TemplatePolicies("dist/cloudformation.json", []byte(template))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("want 2 policies got %d", len(got))
+	}
+
+	if got[1].Location != "dist/cloudformation.json#LambdaRole/handler-policy" {
+		t.Errorf("unexpected location %s", got[1].Location)
+	}
+
+	want := `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"kms:Decrypt","Resource":"${KmsKeyArn}"},{"Effect":"Allow","Action":"secretsmanager:GetSecretValue","Resource":"arn:${AWS::Partition}:secretsmanager:*"}]}`
+	if got[1].Policy.String() != want {
+		t.Errorf("want policy %s got %s", want, got[1].Policy)
+	}
+}
+
+// TestGeneratedTemplate checks that the template generated by cfngen doesn't have
+// any findings which Provider authors can't fix.
+func TestGeneratedTemplate(t *testing.T) {
+	pconfig := pythonconfig.Config{Name: "test", Publisher: "example-org"}
+	schema := cfngen.Schema{
+		Schema: providerregistrysdk.Schema{
+			Resources: &providerregistrysdk.Resources{
+				Loaders: map[string]providerregistrysdk.Loader{"fetch_groups": {Title: "Groups"}},
+			},
+		},
+		Config: map[string]cfngen.ConfigField{"api_key": {Type: "string", Secret: true}},
+	}
+	template, err := cfngen.GenerateFromSchema(pconfig, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policies, err := TemplatePolicies("dist/cloudformation.json", template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Lint(Config{Suppress: TemplateSuppressions("dist/cloudformation.json")}, policies...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range res.Findings {
+		if !f.Suppressed {
+			t.Errorf("unexpected finding in the generated template: %s", f)
+		}
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	res, err := Lint(Config{}, Policy{
+		Location: "roles/read.json#access-policy",
+		File:     "roles/read.json",
+		Policy:   iamp.NewPolicy(iamp.Statement{Effect: iamp.Allow, Action: iamp.Value{"*"}, Resource: iamp.Value{"arn:aws:s3:::bucket"}}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := res.SARIF("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID    string
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string
+						}
+					}
+				}
+			}
+		}
+	}
+	err = json.Unmarshal(b, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 2 {
+		t.Fatalf("unexpected SARIF output: %s", b)
+	}
+	r := log.Runs[0].Results[0]
+	if r.RuleID != "IAM001" || r.Level != "warning" || r.Locations[0].PhysicalLocation.ArtifactLocation.URI != "roles/read.json" {
+		t.Errorf("unexpected SARIF result: %+v", r)
+	}
+}
@@ -0,0 +1,273 @@
+package iamlint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/common-fate/pdk/pkg/iamp"
+)
+
+// Rule is a lint rule which is run against each policy.
+type Rule struct {
+	ID              string
+	Name            string
+	Description     string
+	DefaultSeverity Severity
+	// check returns findings with the Statement and Message fields set.
+	check func(p iamp.Policy) []Finding
+}
+
+// Rules are the lint rules which are run against policies.
+var Rules = []Rule{
+	{
+		ID:              "IAM001",
+		Name:            "wildcard-action",
+		Description:     "Allow statements should not grant all actions, or all actions for a service.",
+		DefaultSeverity: SeverityWarning,
+		check:           checkWildcardAction,
+	},
+	{
+		ID:              "IAM002",
+		Name:            "wildcard-resource",
+		Description:     "Allow statements should be scoped to specific resources, or use conditions to limit access.",
+		DefaultSeverity: SeverityWarning,
+		check:           checkWildcardResource,
+	},
+	{
+		ID:              "IAM003",
+		Name:            "privilege-escalation",
+		Description:     "Allow statements should not grant actions which can be used to escalate privileges.",
+		DefaultSeverity: SeverityError,
+		check:           checkPrivilegeEscalation,
+	},
+	{
+		ID:              "IAM004",
+		Name:            "assume-role-without-condition",
+		Description:     "Statements allowing sts:AssumeRole should use conditions to limit which roles can be assumed.",
+		DefaultSeverity: SeverityWarning,
+		check:           checkAssumeRoleCondition,
+	},
+	{
+		ID:              "IAM005",
+		Name:            "overridden-by-deny",
+		Description:     "Allow statements which are always overridden by a Deny statement have no effect.",
+		DefaultSeverity: SeverityWarning,
+		check:           checkOverriddenByDeny,
+	},
+}
+
+func ruleByID(id string) (Rule, bool) {
+	for _, r := range Rules {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// privilegeEscalationActions can be used to grant a principal
+// more permissions than the policy gives it directly.
+var privilegeEscalationActions = []string{
+	"iam:AddUserToGroup",
+	"iam:AttachGroupPolicy",
+	"iam:AttachRolePolicy",
+	"iam:AttachUserPolicy",
+	"iam:CreateAccessKey",
+	"iam:CreateLoginProfile",
+	"iam:CreatePolicyVersion",
+	"iam:DeleteRolePermissionsBoundary",
+	"iam:DeleteUserPermissionsBoundary",
+	"iam:PutGroupPolicy",
+	"iam:PutRolePermissionsBoundary",
+	"iam:PutRolePolicy",
+	"iam:PutUserPermissionsBoundary",
+	"iam:PutUserPolicy",
+	"iam:SetDefaultPolicyVersion",
+	"iam:UpdateAssumeRolePolicy",
+	"iam:UpdateLoginProfile",
+	"lambda:UpdateFunctionCode",
+}
+
+// identityAllows returns the indexes of Allow statements in identity policies.
+// Statements with a principal are part of trust or resource policies, which the
+// identity policy rules don't apply to.
+func identityAllows(p iamp.Policy) []int {
+	var idx []int
+	for i, s := range p.Statements {
+		if s.Effect == iamp.Allow && len(s.Principal) == 0 && len(s.NotPrincipal) == 0 {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+func checkWildcardAction(p iamp.Policy) []Finding {
+	var findings []Finding
+	for _, i := range identityAllows(p) {
+		s := p.Statements[i]
+		if len(s.NotAction) > 0 {
+			findings = append(findings, Finding{
+				Statement: i,
+				Message:   fmt.Sprintf("NotAction allows all actions except %s", strings.Join(s.NotAction, ", ")),
+			})
+			continue
+		}
+		for _, a := range s.Action {
+			if a == "*" {
+				findings = append(findings, Finding{Statement: i, Message: "allows all actions"})
+			} else if isWildcardService(a) {
+				findings = append(findings, Finding{Statement: i, Message: fmt.Sprintf("allows all actions for a service (%s)", a)})
+			}
+		}
+	}
+	return findings
+}
+
+func checkWildcardResource(p iamp.Policy) []Finding {
+	var findings []Finding
+	for _, i := range identityAllows(p) {
+		s := p.Statements[i]
+		// conditions such as ABAC tag checks limit the resources which the statement applies to.
+		if len(s.Condition) > 0 {
+			continue
+		}
+		if len(s.NotResource) > 0 {
+			findings = append(findings, Finding{
+				Statement: i,
+				Message:   fmt.Sprintf("NotResource allows all resources except %s", strings.Join(s.NotResource, ", ")),
+			})
+			continue
+		}
+		if hasValue(s.Resource, "*") {
+			findings = append(findings, Finding{Statement: i, Message: "allows access to all resources"})
+		}
+	}
+	return findings
+}
+
+func checkPrivilegeEscalation(p iamp.Policy) []Finding {
+	var findings []Finding
+	for _, i := range identityAllows(p) {
+		s := p.Statements[i]
+		var actions []string
+		for _, a := range privilegeEscalationActions {
+			if grantsAction(s, a) {
+				actions = append(actions, a)
+			}
+		}
+		// passing any role allows a service to act with that role's permissions.
+		if grantsAction(s, "iam:PassRole") && (hasValue(s.Resource, "*") || len(s.NotResource) > 0) {
+			actions = append(actions, "iam:PassRole on all resources")
+		}
+		if len(actions) > 0 {
+			findings = append(findings, Finding{
+				Statement: i,
+				Message:   fmt.Sprintf("allows actions which can escalate privileges: %s", strings.Join(actions, ", ")),
+			})
+		}
+	}
+	return findings
+}
+
+func checkAssumeRoleCondition(p iamp.Policy) []Finding {
+	var findings []Finding
+	for _, i := range identityAllows(p) {
+		s := p.Statements[i]
+		if len(s.Condition) > 0 {
+			continue
+		}
+		for _, a := range s.Action {
+			// a '*' action is reported by the wildcard-action rule.
+			if a != "*" && iamp.MatchAction(a, "sts:AssumeRole") {
+				findings = append(findings, Finding{
+					Statement: i,
+					Message:   fmt.Sprintf("allows %s without a condition, such as a resource tag or an external ID", a),
+				})
+				break
+			}
+		}
+	}
+
+	// trust policies which allow other AWS accounts or roles to assume the role
+	// should require an external ID, to prevent confused deputy attacks.
+	// Trust statements for AWS services are not checked.
+	for i, s := range p.Statements {
+		if s.Effect != iamp.Allow || len(s.Principal["AWS"]) == 0 || len(s.Condition) > 0 {
+			continue
+		}
+		for _, a := range s.Action {
+			if iamp.MatchAction(a, "sts:AssumeRole") {
+				findings = append(findings, Finding{
+					Statement: i,
+					Message:   fmt.Sprintf("trusts %s to assume the role without a condition, such as an external ID", strings.Join(s.Principal["AWS"], ", ")),
+				})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+func checkOverriddenByDeny(p iamp.Policy) []Finding {
+	var denies []iamp.Statement
+	for _, s := range p.Statements {
+		// a Deny with a condition only applies to some requests.
+		if s.Effect == iamp.Deny && len(s.Condition) == 0 && len(s.NotAction) == 0 && len(s.NotResource) == 0 {
+			denies = append(denies, s)
+		}
+	}
+	if len(denies) == 0 {
+		return nil
+	}
+
+	var findings []Finding
+	for i, s := range p.Statements {
+		if s.Effect != iamp.Allow || len(s.NotAction) > 0 || len(s.NotResource) > 0 || len(s.Action) == 0 {
+			continue
+		}
+		if coveredByDenies(s, denies) {
+			findings = append(findings, Finding{
+				Statement: i,
+				Message:   "all of the actions and resources allowed by this statement are denied by another statement",
+			})
+		}
+	}
+	return findings
+}
+
+// coveredByDenies returns true if every action and resource pair in the
+// statement is denied by at least one of the deny statements.
+func coveredByDenies(allow iamp.Statement, denies []iamp.Statement) bool {
+	resources := allow.Resource
+	if len(resources) == 0 {
+		// trust and resource policies don't have a Resource.
+		resources = iamp.Value{""}
+	}
+
+	for _, a := range allow.Action {
+		for _, r := range resources {
+			covered := false
+			for _, d := range denies {
+				if covers(d.Action, a, iamp.MatchAction) && (len(d.Resource) == 0 || covers(d.Resource, r, iamp.MatchResource)) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// covers returns true if one of the patterns covers the value. Wildcards in
+// the value are compared literally, so 's3:*' covers 's3:Get*' but not the reverse.
+func covers(patterns iamp.Value, value string, match func(pattern, value string) bool) bool {
+	for _, p := range patterns {
+		if match(p, value) {
+			return true
+		}
+	}
+	return false
+}
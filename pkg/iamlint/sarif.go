@@ -0,0 +1,96 @@
+package iamlint
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SARIF returns the findings in SARIF 2.1.0 format, which can be uploaded to
+// code scanning tools such as GitHub code scanning.
+// See: https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+func (r Result) SARIF(toolVersion string) ([]byte, error) {
+	type message struct {
+		Text string `json:"text"`
+	}
+	type rule struct {
+		ID                   string            `json:"id"`
+		Name                 string            `json:"name"`
+		ShortDescription     message           `json:"shortDescription"`
+		DefaultConfiguration map[string]string `json:"defaultConfiguration"`
+	}
+	type artifactLocation struct {
+		URI string `json:"uri"`
+	}
+	type location struct {
+		PhysicalLocation *struct {
+			ArtifactLocation artifactLocation `json:"artifactLocation"`
+		} `json:"physicalLocation,omitempty"`
+		LogicalLocations []map[string]string `json:"logicalLocations,omitempty"`
+	}
+	type suppression struct {
+		Kind string `json:"kind"`
+	}
+	type result struct {
+		RuleID       string        `json:"ruleId"`
+		Level        string        `json:"level"`
+		Message      message       `json:"message"`
+		Locations    []location    `json:"locations"`
+		Suppressions []suppression `json:"suppressions,omitempty"`
+	}
+
+	var rules []rule
+	for _, ru := range Rules {
+		rules = append(rules, rule{
+			ID:                   ru.ID,
+			Name:                 ru.Name,
+			ShortDescription:     message{Text: ru.Description},
+			DefaultConfiguration: map[string]string{"level": string(ru.DefaultSeverity)},
+		})
+	}
+
+	results := []result{}
+	for _, f := range r.Findings {
+		loc := location{
+			LogicalLocations: []map[string]string{
+				{"fullyQualifiedName": fmt.Sprintf("%s/Statement[%d]", f.Location, f.Statement)},
+			},
+		}
+		if f.File != "" {
+			loc.PhysicalLocation = &struct {
+				ArtifactLocation artifactLocation `json:"artifactLocation"`
+			}{ArtifactLocation: artifactLocation{URI: f.File}}
+		}
+
+		res := result{
+			RuleID:    f.RuleID,
+			Level:     string(f.Severity),
+			Message:   message{Text: f.Message},
+			Locations: []location{loc},
+		}
+		if f.Suppressed {
+			// suppressions in provider.toml are external to the file containing the policy.
+			res.Suppressions = []suppression{{Kind: "external"}}
+		}
+		results = append(results, res)
+	}
+
+	log := map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []any{
+			map[string]any{
+				"tool": map[string]any{
+					"driver": map[string]any{
+						"name":           "pdk",
+						"version":        toolVersion,
+						"informationUri": "https://github.com/common-fate/pdk",
+						"rules":          rules,
+					},
+				},
+				"results": results,
+			},
+		},
+	}
+
+	return json.MarshalIndent(log, "", "  ")
+}
@@ -0,0 +1,225 @@
+package iamlint
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/common-fate/pdk/pkg/cfngen/ref"
+	"github.com/common-fate/pdk/pkg/iamp"
+)
+
+// TemplateSuppressions suppress the findings in the handler template generated by cfngen
+// which Provider authors can't fix. file is the path of the template, as passed to TemplatePolicies.
+func TemplateSuppressions(file string) []Suppression {
+	return []Suppression{
+		{
+			Rule:     "IAM004",
+			Location: file + "#" + ref.LambdaInvocationRole + "/AssumeRolePolicyDocument",
+			Reason:   "Common Fate assumes the invoke role from its own account, which is set by the CommonFateAWSAccountID parameter",
+		},
+		{
+			Rule:     "IAM002",
+			Location: file + "#" + ref.LambdaTracingPolicy,
+			Reason:   "X-Ray doesn't support resource-level permissions for sending traces",
+		},
+	}
+}
+
+// TemplatePolicies extracts the IAM policies from a CloudFormation template, so that
+// the policies generated by cfngen can be linted. Intrinsic functions are rendered
+// as placeholders, e.g. {"Ref": "KmsKeyArn"} becomes '${KmsKeyArn}', and statements
+// which are included using Fn::If are linted as if the condition is true.
+func TemplatePolicies(file string, template []byte) ([]Policy, error) {
+	var t struct {
+		Resources map[string]struct {
+			Type       string
+			Properties map[string]any
+		}
+	}
+	err := json.Unmarshal(template, &t)
+	if err != nil {
+		return nil, err
+	}
+
+	var logicalIDs []string
+	for id := range t.Resources {
+		logicalIDs = append(logicalIDs, id)
+	}
+	sort.Strings(logicalIDs)
+
+	var policies []Policy
+
+	add := func(location string, doc any) error {
+		p, err := renderPolicy(doc)
+		if err != nil {
+			return fmt.Errorf("parsing policy %s in %s: %w", location, file, err)
+		}
+		policies = append(policies, Policy{Location: file + "#" + location, File: file, Policy: p})
+		return nil
+	}
+
+	for _, id := range logicalIDs {
+		r := t.Resources[id]
+		switch r.Type {
+		case "AWS::IAM::Role":
+			if doc, ok := r.Properties["AssumeRolePolicyDocument"]; ok {
+				err = add(id+"/AssumeRolePolicyDocument", doc)
+				if err != nil {
+					return nil, err
+				}
+			}
+			inline, _ := r.Properties["Policies"].([]any)
+			for i, item := range inline {
+				p, _ := item.(map[string]any)
+				name, ok := render(p["PolicyName"]).(string)
+				if !ok {
+					name = fmt.Sprintf("Policies[%d]", i)
+				}
+				err = add(id+"/"+name, p["PolicyDocument"])
+				if err != nil {
+					return nil, err
+				}
+			}
+		case "AWS::IAM::Policy", "AWS::IAM::ManagedPolicy":
+			err = add(id, r.Properties["PolicyDocument"])
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return policies, nil
+}
+
+// renderPolicy converts a policy document containing intrinsic functions into a policy.
+func renderPolicy(doc any) (iamp.Policy, error) {
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return iamp.Policy{}, fmt.Errorf("policy document must be an object")
+	}
+
+	rendered := map[string]any{}
+	for k, v := range m {
+		if k == "Statement" {
+			rendered[k] = renderStatements(v)
+			continue
+		}
+		rendered[k] = render(v)
+	}
+
+	b, err := json.Marshal(rendered)
+	if err != nil {
+		return iamp.Policy{}, err
+	}
+	var p iamp.Policy
+	err = json.Unmarshal(b, &p)
+	return p, err
+}
+
+// renderStatements expands conditional statements, so that both branches of
+// an Fn::If are linted.
+func renderStatements(v any) []any {
+	var out []any
+	switch val := v.(type) {
+	case []any:
+		for _, item := range val {
+			out = append(out, renderStatements(item)...)
+		}
+	case map[string]any:
+		if args, ok := val["Fn::If"].([]any); ok && len(val) == 1 && len(args) == 3 {
+			out = append(out, renderStatements(args[1])...)
+			out = append(out, renderStatements(args[2])...)
+			return out
+		}
+		if isNoValue(val) {
+			return nil
+		}
+		out = append(out, render(val))
+	}
+	return out
+}
+
+func isNoValue(v any) bool {
+	m, ok := v.(map[string]any)
+	return ok && len(m) == 1 && m["Ref"] == "AWS::NoValue"
+}
+
+// render replaces intrinsic functions with placeholder strings.
+func render(v any) any {
+	switch val := v.(type) {
+	case []any:
+		var out []any
+		for _, item := range val {
+			if isNoValue(item) {
+				continue
+			}
+			out = append(out, render(item))
+		}
+		return out
+	case map[string]any:
+		if len(val) == 1 {
+			for fn, args := range val {
+				if s, ok := renderIntrinsic(fn, args); ok {
+					return s
+				}
+			}
+		}
+		out := map[string]any{}
+		for k, child := range val {
+			out[k] = render(child)
+		}
+		return out
+	}
+	return v
+}
+
+func renderIntrinsic(fn string, args any) (any, bool) {
+	switch fn {
+	case "Ref":
+		return fmt.Sprintf("${%v}", args), true
+	case "Fn::GetAtt":
+		parts, _ := args.([]any)
+		var s []string
+		for _, p := range parts {
+			s = append(s, fmt.Sprintf("%v", p))
+		}
+		return "${" + strings.Join(s, ".") + "}", true
+	case "Fn::Sub":
+		switch a := args.(type) {
+		case string:
+			return a, true
+		case []any:
+			if len(a) > 0 {
+				return fmt.Sprintf("%v", a[0]), true
+			}
+		}
+		return "${Fn::Sub}", true
+	case "Fn::Join":
+		a, _ := args.([]any)
+		if len(a) != 2 {
+			return "${Fn::Join}", true
+		}
+		sep, _ := a[0].(string)
+		items, _ := render(a[1]).([]any)
+		var s []string
+		for _, item := range items {
+			s = append(s, fmt.Sprintf("%v", item))
+		}
+		return strings.Join(s, sep), true
+	case "Fn::If":
+		a, _ := args.([]any)
+		if len(a) != 3 {
+			return "${Fn::If}", true
+		}
+		if isNoValue(a[1]) {
+			return render(a[2]), true
+		}
+		return render(a[1]), true
+	}
+	if strings.HasPrefix(fn, "Fn::") {
+		return "${" + fn + "}", true
+	}
+	return nil, false
+}
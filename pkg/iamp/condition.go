@@ -0,0 +1,139 @@
+package iamp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Condition maps condition operators to condition keys and the values to compare them against, e.g.
+//
+//	{"StringEquals": {"aws:PrincipalTag/team": ["platform"]}}
+//
+// Operators may use the IfExists suffix and the ForAnyValue: or ForAllValues: set qualifiers.
+type Condition map[string]map[string]Value
+
+// Validate checks that the condition only uses valid operators.
+func (c Condition) Validate() error {
+	for op := range c {
+		if _, err := ParseOperator(op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set qualifiers for condition operators which compare multi-valued keys.
+const (
+	ForAnyValue  = "ForAnyValue"
+	ForAllValues = "ForAllValues"
+)
+
+// operators are the base condition operators, without qualifiers or the IfExists suffix.
+// See: https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_elements_condition_operators.html
+var operators = map[string]bool{
+	"StringEquals":              true,
+	"StringNotEquals":           true,
+	"StringEqualsIgnoreCase":    true,
+	"StringNotEqualsIgnoreCase": true,
+	"StringLike":                true,
+	"StringNotLike":             true,
+	"NumericEquals":             true,
+	"NumericNotEquals":          true,
+	"NumericLessThan":           true,
+	"NumericLessThanEquals":     true,
+	"NumericGreaterThan":        true,
+	"NumericGreaterThanEquals":  true,
+	"DateEquals":                true,
+	"DateNotEquals":             true,
+	"DateLessThan":              true,
+	"DateLessThanEquals":        true,
+	"DateGreaterThan":           true,
+	"DateGreaterThanEquals":     true,
+	"Bool":                      true,
+	"BinaryEquals":              true,
+	"IpAddress":                 true,
+	"NotIpAddress":              true,
+	"ArnEquals":                 true,
+	"ArnLike":                   true,
+	"ArnNotEquals":              true,
+	"ArnNotLike":                true,
+	"Null":                      true,
+}
+
+// Operator is a parsed condition operator, such as 'ForAnyValue:StringLikeIfExists'.
+type Operator struct {
+	// Base is the operator without qualifiers, e.g. 'StringLike'.
+	Base string
+	// SetQualifier is ForAnyValue or ForAllValues for operators which compare multi-valued keys.
+	SetQualifier string
+	// IfExists is true if the condition matches when the key is not present in the request.
+	IfExists bool
+}
+
+// ParseOperator parses a condition operator.
+func ParseOperator(s string) (Operator, error) {
+	var op Operator
+	rest := s
+
+	if qualifier, base, ok := strings.Cut(rest, ":"); ok {
+		if qualifier != ForAnyValue && qualifier != ForAllValues {
+			return Operator{}, fmt.Errorf("invalid condition operator %s: set qualifier must be %s or %s", s, ForAnyValue, ForAllValues)
+		}
+		op.SetQualifier = qualifier
+		rest = base
+	}
+
+	// 'Null' checks for the presence of a key, so it can't be combined with IfExists.
+	if base := strings.TrimSuffix(rest, "IfExists"); base != rest && base != "Null" {
+		op.IfExists = true
+		rest = base
+	}
+
+	if !operators[rest] {
+		return Operator{}, fmt.Errorf("invalid condition operator %s", s)
+	}
+	op.Base = rest
+	return op, nil
+}
+
+func (o Operator) String() string {
+	s := o.Base
+	if o.IfExists {
+		s += "IfExists"
+	}
+	if o.SetQualifier != "" {
+		s = o.SetQualifier + ":" + s
+	}
+	return s
+}
+
+// ConditionEntry is a condition which limits a statement to a time window.
+//
+// Deprecated: Statement.Condition is now a Condition, so a ConditionEntry can't be
+// assigned to it. This is a breaking change: replace 'Condition: &ConditionEntry{...}'
+// with 'Condition: ConditionEntry{...}.Condition()', or build the Condition directly.
+type ConditionEntry struct {
+	DateGreaterThan AWSTime
+	DateLessThan    AWSTime
+}
+
+// AWSTime compares the aws:CurrentTime condition key against a time.
+//
+// Deprecated: only used by ConditionEntry. Use Condition with a date operator on the
+// aws:CurrentTime key instead.
+type AWSTime struct {
+	Time time.Time `json:"aws:CurrentTime"`
+}
+
+// Condition converts the entry to a Condition. Times which aren't set are left out.
+func (c ConditionEntry) Condition() Condition {
+	cond := Condition{}
+	if !c.DateGreaterThan.Time.IsZero() {
+		cond["DateGreaterThan"] = map[string]Value{"aws:CurrentTime": {c.DateGreaterThan.Time.Format(time.RFC3339)}}
+	}
+	if !c.DateLessThan.Time.IsZero() {
+		cond["DateLessThan"] = map[string]Value{"aws:CurrentTime": {c.DateLessThan.Time.Format(time.RFC3339)}}
+	}
+	return cond
+}
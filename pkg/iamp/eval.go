@@ -0,0 +1,241 @@
+package iamp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Request is the request context which policies are evaluated against.
+type Request struct {
+	// Principal is the ARN of the principal making the request. It's only
+	// used for statements with a Principal or NotPrincipal, such as in trust policies.
+	Principal string
+	// Action is the action being performed, e.g. 's3:GetObject'.
+	Action string
+	// Resource is the ARN of the resource the action is performed on.
+	Resource string
+	// Context contains the condition keys of the request, e.g. {"aws:PrincipalTag/team": ["platform"]}.
+	// Condition keys are case-insensitive.
+	Context map[string]Value
+}
+
+// Decision is the result of evaluating policies against a request.
+type Decision string
+
+const (
+	// DecisionAllow means that a statement allows the request and no statement denies it.
+	DecisionAllow Decision = "allow"
+	// DecisionExplicitDeny means that a Deny statement matches the request.
+	DecisionExplicitDeny Decision = "explicit deny"
+	// DecisionImplicitDeny means that no statement allows the request.
+	DecisionImplicitDeny Decision = "implicit deny"
+)
+
+// MatchedStatement is a statement which applies to a request.
+type MatchedStatement struct {
+	// Policy is the index of the policy passed to Evaluate.
+	Policy int
+	// Index is the index of the statement in the policy.
+	Index     int
+	Statement Statement
+}
+
+// EvalResult is the result of Evaluate.
+type EvalResult struct {
+	Decision Decision
+	// Matched are the statements which caused the decision: the matching Deny
+	// statements for an explicit deny, or the matching Allow statements for an allow.
+	Matched []MatchedStatement
+}
+
+// Evaluate evaluates policies against a request, following the AWS policy evaluation
+// logic for a single set of policies: an explicit deny in any statement overrides
+// any allows, and a request which isn't allowed is implicitly denied.
+//
+// Evaluate doesn't resolve managed policies, permissions boundaries, SCPs or session policies.
+func Evaluate(req Request, policies ...Policy) (EvalResult, error) {
+	context := map[string]Value{}
+	for k, v := range req.Context {
+		context[strings.ToLower(k)] = v
+	}
+
+	var allows, denies []MatchedStatement
+	for pi, p := range policies {
+		for si, s := range p.Statements {
+			ok, err := s.appliesTo(req, context)
+			if err != nil {
+				return EvalResult{}, fmt.Errorf("%s statement %d: %w", policyName(pi, p), si, err)
+			}
+			if !ok {
+				continue
+			}
+
+			m := MatchedStatement{Policy: pi, Index: si, Statement: s}
+			switch s.Effect {
+			case Allow:
+				allows = append(allows, m)
+			case Deny:
+				denies = append(denies, m)
+			default:
+				return EvalResult{}, fmt.Errorf("%s statement %d: invalid effect %q", policyName(pi, p), si, s.Effect)
+			}
+		}
+	}
+
+	if len(denies) > 0 {
+		return EvalResult{Decision: DecisionExplicitDeny, Matched: denies}, nil
+	}
+	if len(allows) > 0 {
+		return EvalResult{Decision: DecisionAllow, Matched: allows}, nil
+	}
+	return EvalResult{Decision: DecisionImplicitDeny}, nil
+}
+
+// policyName identifies a policy in errors, by its Id if it has one or otherwise its index.
+func policyName(index int, p Policy) string {
+	if p.Id != nil && *p.Id != "" {
+		return fmt.Sprintf("policy %q", *p.Id)
+	}
+	return fmt.Sprintf("policy %d", index)
+}
+
+// appliesTo returns true if the principal, action, resource and conditions of the statement match the request.
+func (s Statement) appliesTo(req Request, context map[string]Value) (bool, error) {
+	if !s.matchesPrincipal(req.Principal) {
+		return false, nil
+	}
+
+	switch {
+	case len(s.Action) > 0:
+		if !matchAny(s.Action, req.Action, MatchAction) {
+			return false, nil
+		}
+	case len(s.NotAction) > 0:
+		if matchAny(s.NotAction, req.Action, MatchAction) {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+
+	// trust and resource policies don't have a Resource, as they apply to the resource they're attached to.
+	switch {
+	case len(s.Resource) > 0:
+		if !matchAny(substituteAll(s.Resource, context), req.Resource, MatchResource) {
+			return false, nil
+		}
+	case len(s.NotResource) > 0:
+		if matchAny(substituteAll(s.NotResource, context), req.Resource, MatchResource) {
+			return false, nil
+		}
+	}
+
+	return s.Condition.evaluate(context)
+}
+
+func (s Statement) matchesPrincipal(principal string) bool {
+	switch {
+	case len(s.Principal) > 0:
+		return principal != "" && principalMatches(s.Principal, principal)
+	case len(s.NotPrincipal) > 0:
+		return principal != "" && !principalMatches(s.NotPrincipal, principal)
+	}
+	// identity policies apply to the principal they're attached to.
+	return true
+}
+
+// principalMatches returns true if one of the principals matches. An account ID
+// matches any principal in the account, and '*' matches any principal.
+func principalMatches(p Principal, principal string) bool {
+	for _, values := range p {
+		for _, v := range values {
+			if v == "*" || v == principal {
+				return true
+			}
+			if isAccountID(v) && accountOf(principal) == v {
+				return true
+			}
+			if strings.HasSuffix(v, ":root") && accountOf(v) != "" && accountOf(v) == accountOf(principal) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isAccountID(s string) bool {
+	if len(s) != 12 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// accountOf returns the account ID from an ARN, such as arn:aws:iam::123456789012:role/example.
+func accountOf(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[4]
+}
+
+func matchAny(patterns Value, value string, match func(pattern, value string) bool) bool {
+	for _, p := range patterns {
+		if match(p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func substituteAll(values Value, context map[string]Value) Value {
+	out := make(Value, len(values))
+	for i, v := range values {
+		out[i] = substitute(v, context)
+	}
+	return out
+}
+
+// substitute replaces policy variables such as '${aws:username}' with their values from the request context.
+// Variables which aren't in the context, or have multiple values, are left as they are so that they don't match.
+// See: https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_variables.html
+func substitute(s string, context map[string]Value) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start == -1 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := strings.Index(s[start:], "}")
+		if end == -1 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end += start
+
+		b.WriteString(s[:start])
+		name := s[start+2 : end]
+		switch name {
+		// escaped special characters, e.g. '${*}'.
+		case "*", "?", "$":
+			b.WriteString(name)
+		default:
+			if v, ok := context[strings.ToLower(name)]; ok && len(v) == 1 {
+				b.WriteString(v[0])
+			} else {
+				b.WriteString(s[start : end+1])
+			}
+		}
+		s = s[end+1:]
+	}
+}
@@ -0,0 +1,259 @@
+package iamp
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// evaluate returns true if all of the conditions match the request context.
+// Condition keys in the context must be lowercase.
+func (c Condition) evaluate(context map[string]Value) (bool, error) {
+	// sort the operators so that errors are deterministic.
+	ops := make([]string, 0, len(c))
+	for op := range c {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	for _, name := range ops {
+		op, err := ParseOperator(name)
+		if err != nil {
+			return false, err
+		}
+		for key, want := range c[name] {
+			got, present := context[strings.ToLower(key)]
+			if !op.evaluate(got, present, substituteAll(want, context)) {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// evaluate compares the values of a condition key in the request with the values in the policy.
+// See: https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_policies_multi-value-conditions.html
+func (o Operator) evaluate(got Value, present bool, want Value) bool {
+	if o.Base == "Null" {
+		// 'true' means that the key must not be present.
+		for _, w := range want {
+			if strings.EqualFold(w, "true") != present {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !present || len(got) == 0 {
+		switch {
+		case o.IfExists:
+			return true
+		case o.SetQualifier == ForAllValues:
+			// every value of an empty set matches.
+			return true
+		case o.SetQualifier == ForAnyValue:
+			return false
+		}
+		// negated operators match when the key isn't present.
+		return negated(o.Base)
+	}
+
+	compare, negate := comparison(o.Base)
+	matches := func(v string) bool {
+		for _, w := range want {
+			if compare(v, w) {
+				return !negate
+			}
+		}
+		return negate
+	}
+
+	switch o.SetQualifier {
+	case ForAllValues:
+		for _, v := range got {
+			if !matches(v) {
+				return false
+			}
+		}
+		return true
+	case ForAnyValue:
+		for _, v := range got {
+			if matches(v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// without a set qualifier, a negated operator requires that none of the
+	// values match, and other operators require that any value matches.
+	if negate {
+		for _, v := range got {
+			if !matches(v) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, v := range got {
+		if matches(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func negated(base string) bool {
+	_, negate := comparison(base)
+	return negate
+}
+
+// comparison returns the function comparing a request value with a policy value for an
+// operator, and whether the result is negated.
+func comparison(base string) (compare func(got, want string) bool, negate bool) {
+	switch base {
+	case "StringEquals":
+		return stringEquals, false
+	case "StringNotEquals":
+		return stringEquals, true
+	case "StringEqualsIgnoreCase":
+		return strings.EqualFold, false
+	case "StringNotEqualsIgnoreCase":
+		return strings.EqualFold, true
+	case "StringLike":
+		return stringLike, false
+	case "StringNotLike":
+		return stringLike, true
+	case "NumericEquals":
+		return numeric(func(c int) bool { return c == 0 }), false
+	case "NumericNotEquals":
+		return numeric(func(c int) bool { return c == 0 }), true
+	case "NumericLessThan":
+		return numeric(func(c int) bool { return c < 0 }), false
+	case "NumericLessThanEquals":
+		return numeric(func(c int) bool { return c <= 0 }), false
+	case "NumericGreaterThan":
+		return numeric(func(c int) bool { return c > 0 }), false
+	case "NumericGreaterThanEquals":
+		return numeric(func(c int) bool { return c >= 0 }), false
+	case "DateEquals":
+		return date(func(c int) bool { return c == 0 }), false
+	case "DateNotEquals":
+		return date(func(c int) bool { return c == 0 }), true
+	case "DateLessThan":
+		return date(func(c int) bool { return c < 0 }), false
+	case "DateLessThanEquals":
+		return date(func(c int) bool { return c <= 0 }), false
+	case "DateGreaterThan":
+		return date(func(c int) bool { return c > 0 }), false
+	case "DateGreaterThanEquals":
+		return date(func(c int) bool { return c >= 0 }), false
+	case "Bool":
+		return strings.EqualFold, false
+	case "BinaryEquals":
+		return stringEquals, false
+	case "IpAddress":
+		return ipAddress, false
+	case "NotIpAddress":
+		return ipAddress, true
+	case "ArnEquals", "ArnLike":
+		return arnLike, false
+	case "ArnNotEquals", "ArnNotLike":
+		return arnLike, true
+	}
+	// ParseOperator only returns known operators.
+	return func(string, string) bool { return false }, false
+}
+
+func stringEquals(got, want string) bool {
+	return got == want
+}
+
+func stringLike(got, want string) bool {
+	return matchWildcard(want, got)
+}
+
+// numeric compares numbers. Values which aren't numbers don't match.
+func numeric(cmp func(c int) bool) func(got, want string) bool {
+	return func(got, want string) bool {
+		g, err := strconv.ParseFloat(got, 64)
+		if err != nil {
+			return false
+		}
+		w, err := strconv.ParseFloat(want, 64)
+		if err != nil {
+			return false
+		}
+		switch {
+		case g < w:
+			return cmp(-1)
+		case g > w:
+			return cmp(1)
+		}
+		return cmp(0)
+	}
+}
+
+// date compares dates, which are either ISO 8601 timestamps or epoch seconds.
+// Values which aren't dates don't match.
+func date(cmp func(c int) bool) func(got, want string) bool {
+	return func(got, want string) bool {
+		g, ok := parseDate(got)
+		if !ok {
+			return false
+		}
+		w, ok := parseDate(want)
+		if !ok {
+			return false
+		}
+		switch {
+		case g.Before(w):
+			return cmp(-1)
+		case g.After(w):
+			return cmp(1)
+		}
+		return cmp(0)
+	}
+}
+
+func parseDate(s string) (time.Time, bool) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), true
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05Z0700", "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ipAddress returns true if the IP address is in a CIDR block, or equal to an IP address.
+func ipAddress(got, want string) bool {
+	ip := net.ParseIP(got)
+	if ip == nil {
+		return false
+	}
+	if _, block, err := net.ParseCIDR(want); err == nil {
+		return block.Contains(ip)
+	}
+	return ip.Equal(net.ParseIP(want))
+}
+
+// arnLike compares each of the six colon-separated components of an ARN,
+// where each component of the policy value may contain wildcards.
+func arnLike(got, want string) bool {
+	g := strings.SplitN(got, ":", 6)
+	w := strings.SplitN(want, ":", 6)
+	if len(g) != 6 || len(w) != 6 {
+		return false
+	}
+	for i := range g {
+		if !matchWildcard(w[i], g[i]) {
+			return false
+		}
+	}
+	return true
+}
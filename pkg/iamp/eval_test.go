@@ -0,0 +1,222 @@
+package iamp
+
+import (
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name        string
+		statements  []Statement
+		req         Request
+		want        Decision
+		wantMatched []int
+	}{
+		{
+			name: "no statements",
+			req:  Request{Action: "s3:GetObject", Resource: "arn:aws:s3:::bucket/key"},
+			want: DecisionImplicitDeny,
+		},
+		{
+			name: "wildcard allow",
+			statements: []Statement{
+				{Effect: Allow, Action: Value{"s3:Get*"}, Resource: Value{"arn:aws:s3:::bucket/*"}},
+			},
+			req:         Request{Action: "s3:GetObject", Resource: "arn:aws:s3:::bucket/key"},
+			want:        DecisionAllow,
+			wantMatched: []int{0},
+		},
+		{
+			name: "other resource",
+			statements: []Statement{
+				{Effect: Allow, Action: Value{"s3:Get*"}, Resource: Value{"arn:aws:s3:::bucket/*"}},
+			},
+			req:  Request{Action: "s3:GetObject", Resource: "arn:aws:s3:::other/key"},
+			want: DecisionImplicitDeny,
+		},
+		{
+			name: "explicit deny overrides allow",
+			statements: []Statement{
+				{Effect: Allow, Action: Value{"*"}, Resource: Value{"*"}},
+				{Effect: Deny, Action: Value{"s3:DeleteObject"}, Resource: Value{"*"}},
+			},
+			req:         Request{Action: "s3:DeleteObject", Resource: "arn:aws:s3:::bucket/key"},
+			want:        DecisionExplicitDeny,
+			wantMatched: []int{1},
+		},
+		{
+			name: "not action",
+			statements: []Statement{
+				{Effect: Allow, NotAction: Value{"iam:*"}, Resource: Value{"*"}},
+			},
+			req:  Request{Action: "iam:CreateUser", Resource: "*"},
+			want: DecisionImplicitDeny,
+		},
+		{
+			name: "not resource",
+			statements: []Statement{
+				{Effect: Deny, Action: Value{"s3:*"}, NotResource: Value{"arn:aws:s3:::public/*"}},
+				{Effect: Allow, Action: Value{"s3:*"}, Resource: Value{"*"}},
+			},
+			req:         Request{Action: "s3:GetObject", Resource: "arn:aws:s3:::public/key"},
+			want:        DecisionAllow,
+			wantMatched: []int{1},
+		},
+		{
+			name: "condition matches",
+			statements: []Statement{
+				{Effect: Allow, Action: Value{"ec2:StopInstances"}, Resource: Value{"*"}, Condition: Condition{
+					"StringEquals": {"aws:ResourceTag/team": {"${aws:PrincipalTag/team}"}},
+				}},
+			},
+			req: Request{Action: "ec2:StopInstances", Resource: "arn:aws:ec2:us-east-1:123456789012:instance/i-1", Context: map[string]Value{
+				"aws:ResourceTag/team":  {"platform"},
+				"aws:PrincipalTag/Team": {"platform"},
+			}},
+			want:        DecisionAllow,
+			wantMatched: []int{0},
+		},
+		{
+			name: "condition key missing",
+			statements: []Statement{
+				{Effect: Allow, Action: Value{"ec2:StopInstances"}, Resource: Value{"*"}, Condition: Condition{
+					"StringEquals": {"aws:ResourceTag/team": {"platform"}},
+				}},
+			},
+			req:  Request{Action: "ec2:StopInstances", Resource: "*"},
+			want: DecisionImplicitDeny,
+		},
+		{
+			name: "trust policy",
+			statements: []Statement{
+				{Effect: Allow, Action: Value{"sts:AssumeRole"}, Principal: Principal{"AWS": {"123456789012"}}, Condition: Condition{
+					"StringEquals": {"sts:ExternalId": {"example"}},
+				}},
+			},
+			req: Request{Principal: "arn:aws:iam::123456789012:role/handler", Action: "sts:AssumeRole", Context: map[string]Value{
+				"sts:ExternalId": {"example"},
+			}},
+			want:        DecisionAllow,
+			wantMatched: []int{0},
+		},
+		{
+			name: "trust policy other account",
+			statements: []Statement{
+				{Effect: Allow, Action: Value{"sts:AssumeRole"}, Principal: Principal{"AWS": {"arn:aws:iam::123456789012:root"}}},
+			},
+			req:  Request{Principal: "arn:aws:iam::210987654321:role/handler", Action: "sts:AssumeRole"},
+			want: DecisionImplicitDeny,
+		},
+		{
+			name: "trust policy account in another partition",
+			statements: []Statement{
+				{Effect: Allow, Action: Value{"sts:AssumeRole"}, Principal: Principal{"AWS": {"123456789012"}}},
+			},
+			req:         Request{Principal: "arn:aws-cn:iam::123456789012:root", Action: "sts:AssumeRole"},
+			want:        DecisionAllow,
+			wantMatched: []int{0},
+		},
+		{
+			name: "trust policy root in another partition",
+			statements: []Statement{
+				{Effect: Allow, Action: Value{"sts:AssumeRole"}, Principal: Principal{"AWS": {"arn:aws-us-gov:iam::123456789012:root"}}},
+			},
+			req:         Request{Principal: "arn:aws-us-gov:iam::123456789012:role/handler", Action: "sts:AssumeRole"},
+			want:        DecisionAllow,
+			wantMatched: []int{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.req, NewPolicy(tt.statements...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Decision != tt.want {
+				t.Errorf("want %s got %s", tt.want, got.Decision)
+			}
+			var matched []int
+			for _, m := range got.Matched {
+				matched = append(matched, m.Index)
+			}
+			if len(matched) != len(tt.wantMatched) {
+				t.Fatalf("want matched statements %v got %v", tt.wantMatched, matched)
+			}
+			for i := range matched {
+				if matched[i] != tt.wantMatched[i] {
+					t.Errorf("want matched statements %v got %v", tt.wantMatched, matched)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateErrorIdentifiesPolicy(t *testing.T) {
+	statement := Statement{Effect: "Maybe", Action: Value{"*"}, Resource: Value{"*"}}
+	id := "example"
+	named := NewPolicy(statement)
+	named.Id = &id
+
+	tests := []struct {
+		name     string
+		policies []Policy
+		want     string
+	}{
+		{name: "index", policies: []Policy{NewPolicy(), NewPolicy(statement)}, want: `policy 1 statement 0: invalid effect "Maybe"`},
+		{name: "id", policies: []Policy{named}, want: `policy "example" statement 0: invalid effect "Maybe"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Evaluate(Request{Action: "s3:GetObject", Resource: "*"}, tt.policies...)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("want error %s got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestConditionOperators(t *testing.T) {
+	tests := []struct {
+		op      string
+		got     Value
+		present bool
+		want    Value
+		match   bool
+	}{
+		{op: "StringEquals", got: Value{"a"}, present: true, want: Value{"a", "b"}, match: true},
+		{op: "StringEquals", got: Value{"A"}, present: true, want: Value{"a"}, match: false},
+		{op: "StringEqualsIgnoreCase", got: Value{"A"}, present: true, want: Value{"a"}, match: true},
+		{op: "StringNotEquals", got: Value{"c"}, present: true, want: Value{"a", "b"}, match: true},
+		{op: "StringNotEquals", present: false, want: Value{"a"}, match: true},
+		{op: "StringLike", got: Value{"team-a"}, present: true, want: Value{"team-*"}, match: true},
+		{op: "StringEqualsIfExists", present: false, want: Value{"a"}, match: true},
+		{op: "NumericLessThan", got: Value{"10"}, present: true, want: Value{"20"}, match: true},
+		{op: "NumericGreaterThanEquals", got: Value{"10"}, present: true, want: Value{"20"}, match: false},
+		{op: "NumericEquals", got: Value{"ten"}, present: true, want: Value{"10"}, match: false},
+		{op: "DateLessThan", got: Value{"2023-01-01T00:00:00Z"}, present: true, want: Value{"2024-01-01T00:00:00Z"}, match: true},
+		{op: "DateGreaterThan", got: Value{"1700000000"}, present: true, want: Value{"2023-01-01T00:00:00Z"}, match: true},
+		{op: "Bool", got: Value{"true"}, present: true, want: Value{"true"}, match: true},
+		{op: "IpAddress", got: Value{"10.0.1.5"}, present: true, want: Value{"10.0.0.0/16"}, match: true},
+		{op: "NotIpAddress", got: Value{"10.0.1.5"}, present: true, want: Value{"10.0.0.0/16"}, match: false},
+		{op: "ArnLike", got: Value{"arn:aws:iam::123456789012:role/admin"}, present: true, want: Value{"arn:aws:iam::*:role/*"}, match: true},
+		{op: "ArnNotLike", got: Value{"arn:aws:iam::123456789012:role/admin"}, present: true, want: Value{"arn:aws:iam::*:user/*"}, match: true},
+		{op: "Null", present: false, want: Value{"true"}, match: true},
+		{op: "Null", got: Value{"a"}, present: true, want: Value{"true"}, match: false},
+		{op: "Null", got: Value{"a"}, present: true, want: Value{"false"}, match: true},
+		{op: "ForAnyValue:StringEquals", got: Value{"a", "z"}, present: true, want: Value{"a", "b"}, match: true},
+		{op: "ForAnyValue:StringEquals", present: false, want: Value{"a"}, match: false},
+		{op: "ForAllValues:StringEquals", got: Value{"a", "z"}, present: true, want: Value{"a", "b"}, match: false},
+		{op: "ForAllValues:StringEquals", got: Value{"a", "b"}, present: true, want: Value{"a", "b"}, match: true},
+		{op: "ForAllValues:StringEquals", present: false, want: Value{"a"}, match: true},
+		{op: "ForAllValues:StringNotEquals", got: Value{"y", "z"}, present: true, want: Value{"a", "b"}, match: true},
+	}
+	for _, tt := range tests {
+		op, err := ParseOperator(tt.op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := op.evaluate(tt.got, tt.present, tt.want); got != tt.match {
+			t.Errorf("%s %v (present %v) against %v: want %v got %v", tt.op, tt.got, tt.present, tt.want, tt.match, got)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package iamp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// IAM policies allow many values to be written as either a single string or an array,
+// and condition values may also be booleans or numbers. Values are always parsed into
+// a Value, and the original JSON form is recorded so that parsed policies are written
+// back without changes.
+
+type jsonKind int
+
+const (
+	kindString jsonKind = iota
+	kindBool
+	kindNumber
+)
+
+// valueForm is the JSON form of a Value. The zero value is an array of strings.
+type valueForm struct {
+	scalar bool
+	kind   jsonKind
+}
+
+func isNull(b []byte) bool {
+	return len(b) == 0 || string(bytes.TrimSpace(b)) == "null"
+}
+
+// decodeValue parses a string, boolean, number, or an array of them.
+func decodeValue(b []byte) (Value, valueForm, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var raw any
+	err := dec.Decode(&raw)
+	if err != nil {
+		return nil, valueForm{}, err
+	}
+
+	switch v := raw.(type) {
+	case string, bool, json.Number:
+		s, kind := scalar(v)
+		return Value{s}, valueForm{scalar: true, kind: kind}, nil
+	case []any:
+		var items Value
+		kinds := map[jsonKind]bool{}
+		for _, item := range v {
+			switch item.(type) {
+			case string, bool, json.Number:
+				s, kind := scalar(item)
+				items = append(items, s)
+				kinds[kind] = true
+			default:
+				return nil, valueForm{}, fmt.Errorf("invalid value element %s: allowed is only string or []string", b)
+			}
+		}
+		var form valueForm
+		// arrays with mixed types are written back as strings.
+		if len(kinds) == 1 {
+			for k := range kinds {
+				form.kind = k
+			}
+		}
+		return items, form, nil
+	}
+	return nil, valueForm{}, fmt.Errorf("invalid value element %s: allowed is only string or []string", b)
+}
+
+func scalar(v any) (string, jsonKind) {
+	switch val := v.(type) {
+	case bool:
+		return strconv.FormatBool(val), kindBool
+	case json.Number:
+		return val.String(), kindNumber
+	}
+	return v.(string), kindString
+}
+
+// encodeValue writes a value in its original form.
+func encodeValue(v Value, form valueForm) (json.RawMessage, error) {
+	if form.scalar && len(v) == 1 {
+		return encodeScalar(v[0], form.kind)
+	}
+	items := make([]json.RawMessage, len(v))
+	for i, s := range v {
+		b, err := encodeScalar(s, form.kind)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = b
+	}
+	return json.Marshal(items)
+}
+
+func encodeScalar(s string, kind jsonKind) (json.RawMessage, error) {
+	switch kind {
+	case kindBool:
+		if s == "true" || s == "false" {
+			return json.RawMessage(s), nil
+		}
+	case kindNumber:
+		if _, err := strconv.ParseFloat(s, 64); err == nil && json.Valid([]byte(s)) {
+			return json.RawMessage(s), nil
+		}
+	}
+	return json.Marshal(s)
+}
+
+// statementJSON is the JSON representation of a statement.
+type statementJSON struct {
+	Sid          string                                `json:"Sid,omitempty"`
+	Effect       Effect                                `json:"Effect"`
+	Principal    json.RawMessage                       `json:"Principal,omitempty"`
+	NotPrincipal json.RawMessage                       `json:"NotPrincipal,omitempty"`
+	Action       json.RawMessage                       `json:"Action,omitempty"`
+	NotAction    json.RawMessage                       `json:"NotAction,omitempty"`
+	Resource     json.RawMessage                       `json:"Resource,omitempty"`
+	NotResource  json.RawMessage                       `json:"NotResource,omitempty"`
+	Condition    map[string]map[string]json.RawMessage `json:"Condition,omitempty"`
+}
+
+func (s *Statement) setForm(path string, form valueForm) {
+	if form == (valueForm{}) {
+		return
+	}
+	if s.forms == nil {
+		s.forms = map[string]valueForm{}
+	}
+	s.forms[path] = form
+}
+
+func (s *Statement) UnmarshalJSON(b []byte) error {
+	var raw statementJSON
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+
+	*s = Statement{Sid: raw.Sid, Effect: raw.Effect}
+
+	values := []struct {
+		path string
+		raw  json.RawMessage
+		dst  *Value
+	}{
+		{"Action", raw.Action, &s.Action},
+		{"NotAction", raw.NotAction, &s.NotAction},
+		{"Resource", raw.Resource, &s.Resource},
+		{"NotResource", raw.NotResource, &s.NotResource},
+	}
+	for _, v := range values {
+		if isNull(v.raw) {
+			continue
+		}
+		val, form, err := decodeValue(v.raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", v.path, err)
+		}
+		*v.dst = val
+		s.setForm(v.path, form)
+	}
+
+	s.Principal, err = s.decodePrincipal("Principal", raw.Principal)
+	if err != nil {
+		return err
+	}
+	s.NotPrincipal, err = s.decodePrincipal("NotPrincipal", raw.NotPrincipal)
+	if err != nil {
+		return err
+	}
+
+	if raw.Condition != nil {
+		s.Condition = Condition{}
+		for op, keys := range raw.Condition {
+			s.Condition[op] = map[string]Value{}
+			for k, rv := range keys {
+				val, form, err := decodeValue(rv)
+				if err != nil {
+					return fmt.Errorf("Condition %s %s: %w", op, k, err)
+				}
+				s.Condition[op][k] = val
+				s.setForm("Condition/"+op+"/"+k, form)
+			}
+		}
+		err = s.Condition.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Statement) decodePrincipal(path string, b json.RawMessage) (Principal, error) {
+	if isNull(b) {
+		return nil, nil
+	}
+
+	var wildcard string
+	if err := json.Unmarshal(b, &wildcard); err == nil {
+		if wildcard != "*" {
+			return nil, fmt.Errorf("invalid %s %s: must be '*' or an object", path, wildcard)
+		}
+		s.setForm(path, valueForm{scalar: true})
+		return Principal{"AWS": {"*"}}, nil
+	}
+
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+
+	p := Principal{}
+	for k, rv := range raw {
+		val, form, err := decodeValue(rv)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", path, k, err)
+		}
+		p[k] = val
+		s.setForm(path+"/"+k, form)
+	}
+	return p, nil
+}
+
+func (s Statement) MarshalJSON() ([]byte, error) {
+	out := statementJSON{Sid: s.Sid, Effect: s.Effect}
+
+	var err error
+	values := []struct {
+		path string
+		val  Value
+		dst  *json.RawMessage
+	}{
+		{"Action", s.Action, &out.Action},
+		{"NotAction", s.NotAction, &out.NotAction},
+		{"Resource", s.Resource, &out.Resource},
+		{"NotResource", s.NotResource, &out.NotResource},
+	}
+	for _, v := range values {
+		if len(v.val) == 0 {
+			continue
+		}
+		*v.dst, err = encodeValue(v.val, s.forms[v.path])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out.Principal, err = s.encodePrincipal("Principal", s.Principal)
+	if err != nil {
+		return nil, err
+	}
+	out.NotPrincipal, err = s.encodePrincipal("NotPrincipal", s.NotPrincipal)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(s.Condition) > 0 {
+		out.Condition = map[string]map[string]json.RawMessage{}
+		for op, keys := range s.Condition {
+			out.Condition[op] = map[string]json.RawMessage{}
+			for k, v := range keys {
+				out.Condition[op][k], err = encodeValue(v, s.forms["Condition/"+op+"/"+k])
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+func (s Statement) encodePrincipal(path string, p Principal) (json.RawMessage, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+
+	if s.forms[path].scalar && len(p) == 1 && len(p["AWS"]) == 1 && p["AWS"][0] == "*" {
+		return json.RawMessage(`"*"`), nil
+	}
+
+	// sort the keys so that the output is stable.
+	var keys []string
+	for k := range p {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := map[string]json.RawMessage{}
+	for _, k := range keys {
+		b, err := encodeValue(p[k], s.forms[path+"/"+k])
+		if err != nil {
+			return nil, err
+		}
+		out[k] = b
+	}
+	return json.Marshal(out)
+}
+
+// policyJSON is the JSON representation of a policy.
+type policyJSON struct {
+	Version   string          `json:"Version"`
+	Id        *string         `json:"Id,omitempty"`
+	Statement json.RawMessage `json:"Statement"`
+}
+
+func (p *Policy) UnmarshalJSON(b []byte) error {
+	var raw policyJSON
+	err := json.Unmarshal(b, &raw)
+	if err != nil {
+		return err
+	}
+
+	*p = Policy{Version: raw.Version, Id: raw.Id}
+
+	if isNull(raw.Statement) {
+		return nil
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(raw.Statement), []byte("{")) {
+		var s Statement
+		err = json.Unmarshal(raw.Statement, &s)
+		if err != nil {
+			return err
+		}
+		p.Statements = []Statement{s}
+		p.singleStatement = true
+		return nil
+	}
+
+	return json.Unmarshal(raw.Statement, &p.Statements)
+}
+
+func (p Policy) MarshalJSON() ([]byte, error) {
+	out := policyJSON{Version: p.Version, Id: p.Id}
+
+	var err error
+	if p.singleStatement && len(p.Statements) == 1 {
+		out.Statement, err = json.Marshal(p.Statements[0])
+	} else {
+		out.Statement, err = json.Marshal(p.Statements)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(out)
+}
@@ -0,0 +1,46 @@
+package iamp
+
+import "strings"
+
+// MatchAction returns true if an IAM action matches a pattern from a policy,
+// such as 's3:Get*'. Actions are case-insensitive.
+func MatchAction(pattern, action string) bool {
+	return matchWildcard(strings.ToLower(pattern), strings.ToLower(action))
+}
+
+// MatchResource returns true if a resource ARN matches a pattern from a policy,
+// such as 'arn:aws:s3:::bucket/*'. Resources are case-sensitive.
+func MatchResource(pattern, resource string) bool {
+	return matchWildcard(pattern, resource)
+}
+
+// matchWildcard matches a string against a pattern where '*' matches any sequence
+// of characters and '?' matches any single character.
+func matchWildcard(pattern, s string) bool {
+	// track the position of the last '*' so that we can backtrack to it.
+	var p, i int
+	star, match := -1, 0
+
+	for i < len(s) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == s[i]):
+			p++
+			i++
+		case p < len(pattern) && pattern[p] == '*':
+			star = p
+			match = i
+			p++
+		case star != -1:
+			p = star + 1
+			match++
+			i = match
+		default:
+			return false
+		}
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -4,8 +4,6 @@ package iamp
 
 import (
 	"encoding/json"
-	"fmt"
-	"time"
 )
 
 // NewPolicy creates a new IAM policy document
@@ -21,6 +19,10 @@ type Policy struct {
 	Version    string      `json:"Version"`
 	Id         *string     `json:"Id,omitempty"`
 	Statements []Statement `json:"Statement"`
+
+	// singleStatement is true if the Statement was a single object rather than
+	// an array when the policy was parsed, so that it's written back the same way.
+	singleStatement bool
 }
 
 func (p Policy) String() string {
@@ -39,52 +41,33 @@ const (
 )
 
 type Statement struct {
-	Sid          string           `json:"Sid,omitempty"`          // statement ID, service specific
-	Effect       Effect           `json:"Effect"`                 // Allow or Deny
-	Principal    map[string]Value `json:"Principal,omitempty"`    // principal that is allowed or denied
-	NotPrincipal map[string]Value `json:"NotPrincipal,omitempty"` // exception to a list of principals
-	Action       Value            `json:"Action,omitempty"`       // allowed or denied action
-	NotAction    Value            `json:"NotAction,omitempty"`    // matches everything except
-	Resource     Value            `json:"Resource,omitempty"`     // object or objects that the statement covers
-	NotResource  Value            `json:"NotResource,omitempty"`  // matches everything except
-	Condition    *ConditionEntry  `json:"Condition,omitempty"`    // conditions for when a policy is in effect
-}
+	Sid          string    `json:"Sid,omitempty"`          // statement ID, service specific
+	Effect       Effect    `json:"Effect"`                 // Allow or Deny
+	Principal    Principal `json:"Principal,omitempty"`    // principal that is allowed or denied
+	NotPrincipal Principal `json:"NotPrincipal,omitempty"` // exception to a list of principals
+	Action       Value     `json:"Action,omitempty"`       // allowed or denied action
+	NotAction    Value     `json:"NotAction,omitempty"`    // matches everything except
+	Resource     Value     `json:"Resource,omitempty"`     // object or objects that the statement covers
+	NotResource  Value     `json:"NotResource,omitempty"`  // matches everything except
+	Condition    Condition `json:"Condition,omitempty"`    // conditions for when a policy is in effect
 
-type ConditionEntry struct {
-	DateGreaterThan AWSTime
-	DateLessThan    AWSTime
+	// forms records the JSON form of values which weren't arrays of strings
+	// when the statement was parsed, keyed by their path in the statement.
+	forms map[string]valueForm
 }
 
-type AWSTime struct {
-	Time time.Time `json:"aws:CurrentTime"`
-}
+// Principal maps principal types, such as 'AWS' or 'Service', to principals.
+// The wildcard principal '"Principal": "*"' is parsed as {"AWS": ["*"]}.
+type Principal map[string]Value
 
 // AWS allows string or []string as value, we convert everything to []string to avoid casting
 type Value []string
 
 func (value *Value) UnmarshalJSON(b []byte) error {
-
-	var raw interface{}
-	err := json.Unmarshal(b, &raw)
+	v, _, err := decodeValue(b)
 	if err != nil {
 		return err
 	}
-
-	var p []string
-	//  value can be string or []string, convert everything to []string
-	switch v := raw.(type) {
-	case string:
-		p = []string{v}
-	case []interface{}:
-		var items []string
-		for _, item := range v {
-			items = append(items, fmt.Sprintf("%v", item))
-		}
-		p = items
-	default:
-		return fmt.Errorf("invalid %s value element: allowed is only string or []string", value)
-	}
-
-	*value = p
+	*value = v
 	return nil
 }
@@ -0,0 +1,186 @@
+package iamp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPolicyRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		give string
+	}{
+		{
+			name: "arrays",
+			give: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["s3:GetObject","s3:ListBucket"],"Resource":["*"]}]}`,
+		},
+		{
+			name: "single strings",
+			give: `{"Version":"2012-10-17","Statement":[{"Sid":"Read","Effect":"Allow","Action":"s3:GetObject","NotResource":"arn:aws:s3:::secret/*"}]}`,
+		},
+		{
+			name: "single statement",
+			give: `{"Version":"2012-10-17","Statement":{"Effect":"Deny","NotAction":"iam:*","Resource":"*"}}`,
+		},
+		{
+			name: "principals",
+			give: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"AWS":["arn:aws:iam::123456789012:root"],"Service":"lambda.amazonaws.com"},"Action":"sts:AssumeRole"}]}`,
+		},
+		{
+			name: "wildcard principal",
+			give: `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::public/*"}]}`,
+		},
+		{
+			name: "conditions",
+			give: `{"Version":"2012-10-17","Id":"example","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"*","Condition":{"ArnLike":{"aws:SourceArn":["arn:aws:lambda:*:123456789012:function:*"]},"Bool":{"aws:SecureTransport":true,"aws:ViaAWSService":"false"},"ForAnyValue:StringEquals":{"aws:TagKeys":["team","env"]},"NumericLessThanIfExists":{"aws:MultiFactorAuthAge":3600},"StringEquals":{"aws:PrincipalOrgID":"o-abcd1234"}}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Policy
+			err := json.Unmarshal([]byte(tt.give), &p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.String(); got != tt.give {
+				t.Errorf("round trip changed the policy:\nwant %s\ngot  %s", tt.give, got)
+			}
+		})
+	}
+}
+
+func TestPolicyUnmarshal(t *testing.T) {
+	give := `{"Version":"2012-10-17","Statement":{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Condition":{"Bool":{"aws:SecureTransport":true},"NumericLessThan":{"s3:max-keys":[10,20]}}}}`
+
+	var p Policy
+	err := json.Unmarshal([]byte(give), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Statement{
+		{
+			Effect:    Allow,
+			Principal: Principal{"AWS": {"*"}},
+			Action:    Value{"s3:GetObject"},
+			Condition: Condition{
+				"Bool":            {"aws:SecureTransport": {"true"}},
+				"NumericLessThan": {"s3:max-keys": {"10", "20"}},
+			},
+		},
+	}
+
+	// compare without the recorded JSON forms.
+	for i := range p.Statements {
+		p.Statements[i].forms = nil
+	}
+	if !reflect.DeepEqual(p.Statements, want) {
+		t.Errorf("want %+v got %+v", want, p.Statements)
+	}
+
+	// modifying a single value into multiple values writes an array.
+	p.Statements[0].Action = append(p.Statements[0].Action, "s3:PutObject")
+	b, err := json.Marshal(p.Statements[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	_ = json.Unmarshal(b, &got)
+	if !reflect.DeepEqual(got["Action"], []any{"s3:GetObject", "s3:PutObject"}) {
+		t.Errorf("want an array of actions, got %v", got["Action"])
+	}
+}
+
+func TestPolicyUnmarshalInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown operator":   `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Condition":{"StringEqual":{"aws:PrincipalOrgID":"o-abcd1234"}}}]}`,
+		"unknown qualifier":  `{"Statement":[{"Effect":"Allow","Action":"s3:GetObject","Condition":{"ForSomeValues:StringEquals":{"aws:TagKeys":"team"}}}]}`,
+		"invalid principal":  `{"Statement":[{"Effect":"Allow","Principal":"arn:aws:iam::123456789012:root","Action":"sts:AssumeRole"}]}`,
+		"object action":      `{"Statement":[{"Effect":"Allow","Action":{"s3":"GetObject"}}]}`,
+		"nested array value": `{"Statement":[{"Effect":"Allow","Action":[["s3:GetObject"]]}]}`,
+	}
+	for name, give := range tests {
+		t.Run(name, func(t *testing.T) {
+			var p Policy
+			if err := json.Unmarshal([]byte(give), &p); err == nil {
+				t.Errorf("expected an error parsing %s", give)
+			}
+		})
+	}
+}
+
+func TestParseOperator(t *testing.T) {
+	tests := map[string]Operator{
+		"StringEquals":                         {Base: "StringEquals"},
+		"ArnLikeIfExists":                      {Base: "ArnLike", IfExists: true},
+		"ForAnyValue:StringLike":               {Base: "StringLike", SetQualifier: ForAnyValue},
+		"ForAllValues:StringEqualsIfExists":    {Base: "StringEquals", SetQualifier: ForAllValues, IfExists: true},
+		"Null":                                 {Base: "Null"},
+		"Bool":                                 {Base: "Bool"},
+		"ForAnyValue:NumericGreaterThanEquals": {Base: "NumericGreaterThanEquals", SetQualifier: ForAnyValue},
+	}
+	for give, want := range tests {
+		got, err := ParseOperator(give)
+		if err != nil {
+			t.Errorf("ParseOperator(%s): %s", give, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseOperator(%s): want %+v got %+v", give, want, got)
+		}
+		if got.String() != give {
+			t.Errorf("Operator.String(): want %s got %s", give, got.String())
+		}
+	}
+
+	for _, give := range []string{"NullIfExists", "StringEqual", "ForAnyValue:", "Any:StringEquals"} {
+		if _, err := ParseOperator(give); err == nil {
+			t.Errorf("ParseOperator(%s): expected an error", give)
+		}
+	}
+}
+
+func TestConditionEntry(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := ConditionEntry{
+		DateGreaterThan: AWSTime{Time: start},
+		DateLessThan:    AWSTime{Time: start.Add(time.Hour)},
+	}
+	want := Condition{
+		"DateGreaterThan": {"aws:CurrentTime": {"2023-01-01T00:00:00Z"}},
+		"DateLessThan":    {"aws:CurrentTime": {"2023-01-01T01:00:00Z"}},
+	}
+	if got := entry.Condition(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		action  bool
+		want    bool
+	}{
+		{pattern: "*", value: "s3:GetObject", action: true, want: true},
+		{pattern: "s3:Get*", value: "s3:GetObject", action: true, want: true},
+		{pattern: "S3:GETOBJECT", value: "s3:GetObject", action: true, want: true},
+		{pattern: "s3:Get?bject", value: "s3:GetObject", action: true, want: true},
+		{pattern: "s3:Put*", value: "s3:GetObject", action: true, want: false},
+		{pattern: "arn:aws:s3:::bucket/*", value: "arn:aws:s3:::bucket/a/b", want: true},
+		{pattern: "arn:aws:s3:::bucket/*", value: "arn:aws:s3:::bucket", want: false},
+		{pattern: "arn:aws:s3:::Bucket/*", value: "arn:aws:s3:::bucket/a", want: false},
+		{pattern: "arn:aws:iam::*:role/*-admin", value: "arn:aws:iam::123456789012:role/team-admin", want: true},
+	}
+	for _, tt := range tests {
+		match := MatchResource
+		if tt.action {
+			match = MatchAction
+		}
+		if got := match(tt.pattern, tt.value); got != tt.want {
+			t.Errorf("match(%s, %s): want %v got %v", tt.pattern, tt.value, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package iamsuggest
+
+import (
+	_ "embed"
+	"encoding/json"
+	"strings"
+)
+
+// catalogJSON maps boto3 service names to their IAM action prefix, and lists the
+// client methods which don't map to an action with the same name as the method,
+// such as 's3.list_objects_v2' which requires 's3:ListBucket'.
+//
+//go:embed catalog.json
+var catalogJSON []byte
+
+type catalogService struct {
+	Prefix string `json:"prefix"`
+	// Methods maps client methods to IAM actions. An empty list means
+	// that the method doesn't require any permissions.
+	Methods map[string][]string `json:"methods"`
+}
+
+var catalog = mustLoadCatalog()
+
+func mustLoadCatalog() map[string]catalogService {
+	var c map[string]catalogService
+	err := json.Unmarshal(catalogJSON, &c)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// ActionsForCall returns the IAM actions required by a boto3 client method.
+// Methods which aren't in the catalog map to the action with the same name in
+// PascalCase, e.g. 'sso-admin' 'list_permission_sets' maps to 'sso:ListPermissionSets'.
+// known is false if the service isn't in the catalog.
+func ActionsForCall(service, method string) (actions []string, known bool) {
+	svc, known := catalog[service]
+	prefix := svc.Prefix
+	if !known {
+		prefix = service
+	}
+	if a, ok := svc.Methods[method]; ok {
+		return a, known
+	}
+	return []string{prefix + ":" + pascalCase(method)}, known
+}
+
+func pascalCase(s string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(s, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,230 @@
+// Package iamsuggest suggests least-privilege IAM policies for a Provider by statically
+// scanning its Python code for boto3 client calls and mapping them to IAM actions.
+//
+// The scanner doesn't parse Python. It finds client assignments such as
+//
+//	self.sso = boto3.client("sso-admin")
+//
+// and method calls on those clients, such as 'self.sso.list_permission_sets(...)'.
+// Clients passed between functions under a different name aren't found.
+package iamsuggest
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Call is a boto3 client method call found in the Provider code.
+type Call struct {
+	// Service is the boto3 service name, e.g. 'sso-admin'.
+	Service string
+	// Method is the client method, e.g. 'list_permission_sets'.
+	Method string
+	File   string
+	Line   int
+}
+
+// ScanResult contains the boto3 calls found in the Provider code.
+type ScanResult struct {
+	Calls []Call
+	// Warnings are usages which can't be mapped to IAM actions, such as boto3 resources.
+	Warnings []string
+}
+
+var (
+	// matches 'x = boto3.client("s3")', 'self.x: Client = session.client(service_name="s3", ...)'
+	clientAssignRe = regexp.MustCompile(`([A-Za-z_][\w.]*)\s*(?::[^=\n]+)?=\s*(?:[\w.]+\.)?client\(\s*(?:service_name\s*=\s*)?["']([\w-]+)["']`)
+	// matches 'boto3.client("s3").get_object('
+	chainedCallRe = regexp.MustCompile(`(?:[\w.]+\.)?client\(\s*(?:service_name\s*=\s*)?["']([\w-]+)["'][^()]*\)\s*\.(\w+)\(`)
+	// matches 'self.client.list_accounts(' with the receiver and the method.
+	methodCallRe = regexp.MustCompile(`([A-Za-z_][\w.]*)\.(\w+)\(`)
+	// matches the operation name passed to get_paginator.
+	paginatorRe = regexp.MustCompile(`^\s*["'](\w+)["']`)
+	// matches boto3 resources, which aren't supported.
+	resourceRe = regexp.MustCompile(`(?:[\w.]+\.)?resource\(\s*(?:service_name\s*=\s*)?["']([\w-]+)["']`)
+	commentRe  = regexp.MustCompile(`(?m)^\s*#.*$`)
+)
+
+// clientMethods are methods of boto3 clients which don't call an AWS API.
+var clientMethods = map[string]bool{
+	"get_waiter":              true,
+	"can_paginate":            true,
+	"close":                   true,
+	"generate_presigned_url":  true,
+	"generate_presigned_post": true,
+}
+
+type assignment struct {
+	// name is the last part of the assignment target, e.g. 'sso' for 'self.sso'.
+	name string
+	// attribute is true if the target is an attribute, such as 'self.sso', which
+	// can be used in other files.
+	attribute bool
+	service   string
+	offset    int
+}
+
+// ScanDir scans the Python files in a directory, skipping virtual environments,
+// hidden directories and tests.
+func ScanDir(dir string) (ScanResult, error) {
+	files := map[string][]byte{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || name == "__pycache__" || name == "tests" || name == "dist") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(name) != ".py" || strings.HasPrefix(name, "test_") || strings.HasSuffix(name, "_test.py") {
+			return nil
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = src
+		return nil
+	})
+	if err != nil {
+		return ScanResult{}, err
+	}
+	return ScanFiles(files), nil
+}
+
+// ScanFiles scans Python source files, keyed by their path.
+func ScanFiles(files map[string][]byte) ScanResult {
+	var res ScanResult
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	// blank out comments so that offsets still match line numbers.
+	sources := map[string][]byte{}
+	for _, p := range paths {
+		sources[p] = commentRe.ReplaceAllFunc(files[p], func(b []byte) []byte {
+			return bytes.Repeat([]byte(" "), len(b))
+		})
+	}
+
+	assignments := map[string][]assignment{}
+	// attributes maps client attribute names to services across all files.
+	attributes := map[string]map[string]bool{}
+	for _, p := range paths {
+		for _, m := range clientAssignRe.FindAllSubmatchIndex(sources[p], -1) {
+			target := string(sources[p][m[2]:m[3]])
+			a := assignment{
+				name:      lastSegment(target),
+				attribute: strings.Contains(target, "."),
+				service:   string(sources[p][m[4]:m[5]]),
+				offset:    m[0],
+			}
+			assignments[p] = append(assignments[p], a)
+			if a.attribute {
+				if attributes[a.name] == nil {
+					attributes[a.name] = map[string]bool{}
+				}
+				attributes[a.name][a.service] = true
+			}
+		}
+	}
+
+	for _, p := range paths {
+		src := sources[p]
+
+		for _, m := range chainedCallRe.FindAllSubmatchIndex(src, -1) {
+			res.Calls = append(res.Calls, Call{
+				Service: string(src[m[2]:m[3]]),
+				Method:  string(src[m[4]:m[5]]),
+				File:    p,
+				Line:    lineOf(src, m[0]),
+			})
+		}
+
+		for _, m := range methodCallRe.FindAllSubmatchIndex(src, -1) {
+			receiver := string(src[m[2]:m[3]])
+			method := string(src[m[4]:m[5]])
+			if clientMethods[method] {
+				continue
+			}
+
+			services := resolveClient(lastSegment(receiver), strings.Contains(receiver, "."), m[0], assignments[p], attributes)
+			if len(services) == 0 {
+				continue
+			}
+
+			if method == "get_paginator" {
+				op := paginatorRe.FindSubmatch(src[m[1]:])
+				if op == nil {
+					continue
+				}
+				method = string(op[1])
+			}
+
+			for _, s := range services {
+				res.Calls = append(res.Calls, Call{Service: s, Method: method, File: p, Line: lineOf(src, m[0])})
+			}
+		}
+
+		for _, m := range resourceRe.FindAllSubmatchIndex(src, -1) {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("%s:%d: boto3 resources are not supported, calls on the '%s' resource are not included", p, lineOf(src, m[0]), src[m[2]:m[3]]))
+		}
+	}
+
+	return res
+}
+
+// resolveClient returns the services of the client a method is called on. A client
+// assigned in the same file takes precedence, using the closest assignment before the
+// call. Otherwise, attributes such as 'self.sso' assigned in other files are used.
+func resolveClient(name string, attribute bool, offset int, assignments []assignment, attributes map[string]map[string]bool) []string {
+	var found *assignment
+	for i, a := range assignments {
+		if a.name != name {
+			continue
+		}
+		if found == nil || a.offset < offset {
+			found = &assignments[i]
+		}
+	}
+	if found != nil {
+		return []string{found.service}
+	}
+
+	if !attribute {
+		return nil
+	}
+	var services []string
+	for s := range attributes[name] {
+		services = append(services, s)
+	}
+	sort.Strings(services)
+	return services
+}
+
+func lastSegment(s string) string {
+	if i := strings.LastIndex(s, "."); i != -1 {
+		return s[i+1:]
+	}
+	return s
+}
+
+func lineOf(src []byte, offset int) int {
+	return bytes.Count(src[:offset], []byte("\n")) + 1
+}
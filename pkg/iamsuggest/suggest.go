@@ -0,0 +1,169 @@
+package iamsuggest
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/common-fate/pdk/pkg/accessrole"
+	"github.com/common-fate/pdk/pkg/iamp"
+)
+
+// Action is an IAM action required by the Provider code.
+type Action struct {
+	Name string
+	// Calls are the boto3 calls which require the action.
+	Calls []Call
+	// Guessed is true if the service of the call isn't in the catalog,
+	// so the action name is based on the boto3 service and method names.
+	Guessed bool
+}
+
+// Actions returns the IAM actions required by the calls, sorted by name.
+func Actions(calls []Call) []Action {
+	byName := map[string]*Action{}
+	for _, c := range calls {
+		names, known := ActionsForCall(c.Service, c.Method)
+		for _, n := range names {
+			a, ok := byName[n]
+			if !ok {
+				a = &Action{Name: n, Guessed: !known}
+				byName[n] = a
+			}
+			a.Calls = append(a.Calls, c)
+		}
+	}
+
+	actions := make([]Action, 0, len(byName))
+	for _, a := range byName {
+		actions = append(actions, *a)
+	}
+	sort.Slice(actions, func(i, j int) bool { return actions[i].Name < actions[j].Name })
+	return actions
+}
+
+// DraftPolicy returns a policy allowing the actions, with a statement for each service.
+// The statements allow all resources and should be scoped down before they're used.
+func DraftPolicy(actions []Action) iamp.Policy {
+	var statements []iamp.Statement
+	index := map[string]int{}
+	for _, a := range actions {
+		prefix, _, _ := strings.Cut(a.Name, ":")
+		i, ok := index[prefix]
+		if !ok {
+			i = len(statements)
+			index[prefix] = i
+			statements = append(statements, iamp.Statement{
+				Sid:      sid(prefix),
+				Effect:   iamp.Allow,
+				Resource: iamp.Value{"*"},
+			})
+		}
+		statements[i].Action = append(statements[i].Action, a.Name)
+	}
+	return iamp.NewPolicy(statements...)
+}
+
+// sid returns a statement ID for a service prefix, which may only contain alphanumeric characters.
+func sid(prefix string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(prefix, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// Role is an access role defined in the Provider's roles folder.
+type Role struct {
+	// File is the path of the role definition, e.g. 'roles/read.json'.
+	File       string
+	Definition accessrole.Definition
+}
+
+// Unused is an action granted by a role which isn't used by the Provider code.
+type Unused struct {
+	File   string
+	Policy string
+	// Action is the action as written in the policy, which may contain wildcards.
+	Action string
+}
+
+// Diff is the difference between the actions used by the Provider code and the access roles.
+type Diff struct {
+	// Missing are actions which are used, but not allowed by any role.
+	Missing []Action
+	// Unused are actions allowed by a role which don't match any used action.
+	Unused []Unused
+}
+
+// Compare compares the actions used by the Provider code with the actions allowed by
+// the inline policies of the roles. Resources, conditions and Deny statements aren't compared.
+func Compare(actions []Action, roles ...Role) Diff {
+	var diff Diff
+
+	for _, a := range actions {
+		allowed := false
+		for _, r := range roles {
+			for _, p := range r.Definition.Policies {
+				if allowsAction(p.PolicyDocument, a.Name) {
+					allowed = true
+				}
+			}
+		}
+		if !allowed {
+			diff.Missing = append(diff.Missing, a)
+		}
+	}
+
+	for _, r := range roles {
+		for _, p := range r.Definition.Policies {
+			for _, s := range p.PolicyDocument.Statements {
+				if s.Effect != iamp.Allow {
+					continue
+				}
+				for _, pattern := range s.Action {
+					if !matchesAny(pattern, actions) {
+						diff.Unused = append(diff.Unused, Unused{File: r.File, Policy: p.PolicyName, Action: pattern})
+					}
+				}
+			}
+		}
+	}
+
+	return diff
+}
+
+// allowsAction returns true if an Allow statement in the policy includes the action.
+func allowsAction(p iamp.Policy, action string) bool {
+	for _, s := range p.Statements {
+		if s.Effect != iamp.Allow {
+			continue
+		}
+		if len(s.Action) > 0 && matchesPattern(s.Action, action) {
+			return true
+		}
+		if len(s.NotAction) > 0 && !matchesPattern(s.NotAction, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesPattern(patterns iamp.Value, action string) bool {
+	for _, p := range patterns {
+		if iamp.MatchAction(p, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(pattern string, actions []Action) bool {
+	for _, a := range actions {
+		if iamp.MatchAction(pattern, a.Name) {
+			return true
+		}
+	}
+	return false
+}
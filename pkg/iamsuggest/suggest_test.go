@@ -0,0 +1,105 @@
+package iamsuggest
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/common-fate/pdk/pkg/accessrole"
+	"github.com/common-fate/pdk/pkg/iamp"
+)
+
+func TestScanFiles(t *testing.T) {
+	files := map[string][]byte{
+		"provider.py": []byte(`import boto3
+
+
+class Provider:
+    def setup(self):
+        session = boto3.Session()
+        self.sso: SSOAdminClient = session.client("sso-admin", region_name=self.region)
+        # self.sso.delete_permission_set(...)
+        self.idstore = session.client(service_name="identitystore")
+`),
+		"grants.py": []byte(`import boto3
+
+
+def grant(p, subject, args):
+    user = p.idstore.get_user_id(
+        IdentityStoreId=p.identity_store_id,
+    )
+    p.sso.create_account_assignment(PrincipalId=user["UserId"])
+    paginator = p.sso.get_paginator(
+        "list_permission_sets"
+    )
+    boto3.client("sts").get_caller_identity()
+    s3 = boto3.resource("s3")
+    client = boto3.client("s3")
+    client.list_objects_v2(Bucket="example")
+    response = client.get_object(Bucket="example", Key="key")
+    response.get("Body")
+`),
+	}
+
+	got := ScanFiles(files)
+
+	want := []Call{
+		{Service: "sts", Method: "get_caller_identity", File: "grants.py", Line: 12},
+		{Service: "identitystore", Method: "get_user_id", File: "grants.py", Line: 5},
+		{Service: "sso-admin", Method: "create_account_assignment", File: "grants.py", Line: 8},
+		{Service: "sso-admin", Method: "list_permission_sets", File: "grants.py", Line: 9},
+		{Service: "s3", Method: "list_objects_v2", File: "grants.py", Line: 15},
+		{Service: "s3", Method: "get_object", File: "grants.py", Line: 16},
+	}
+	if !reflect.DeepEqual(got.Calls, want) {
+		t.Errorf("want calls\n%+v\ngot\n%+v", want, got.Calls)
+	}
+	if len(got.Warnings) != 1 {
+		t.Errorf("want 1 warning for the boto3 resource, got %v", got.Warnings)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	actions := Actions([]Call{
+		{Service: "sso-admin", Method: "create_account_assignment"},
+		{Service: "sso-admin", Method: "list_permission_sets"},
+		{Service: "s3", Method: "list_objects_v2"},
+		{Service: "s3", Method: "copy_object"},
+		{Service: "sts", Method: "get_caller_identity"},
+	})
+
+	var names []string
+	for _, a := range actions {
+		names = append(names, a.Name)
+	}
+	wantNames := []string{"s3:GetObject", "s3:ListBucket", "s3:PutObject", "sso:CreateAccountAssignment", "sso:ListPermissionSets"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("want actions %v got %v", wantNames, names)
+	}
+
+	wantPolicy := `{"Version":"2012-10-17","Statement":[{"Sid":"S3","Effect":"Allow","Action":["s3:GetObject","s3:ListBucket","s3:PutObject"],"Resource":["*"]},{"Sid":"Sso","Effect":"Allow","Action":["sso:CreateAccountAssignment","sso:ListPermissionSets"],"Resource":["*"]}]}`
+	if got := DraftPolicy(actions).String(); got != wantPolicy {
+		t.Errorf("want policy %s got %s", wantPolicy, got)
+	}
+
+	role := Role{
+		File: "roles/admin.json",
+		Definition: accessrole.FromPolicy(iamp.NewPolicy(
+			iamp.Statement{Effect: iamp.Allow, Action: iamp.Value{"sso:*AccountAssignment", "sso:List*"}, Resource: iamp.Value{"*"}},
+			iamp.Statement{Effect: iamp.Allow, Action: iamp.Value{"s3:Get*", "iam:PassRole"}, Resource: iamp.Value{"*"}},
+		)),
+	}
+
+	diff := Compare(actions, role)
+
+	var missing []string
+	for _, a := range diff.Missing {
+		missing = append(missing, a.Name)
+	}
+	if !reflect.DeepEqual(missing, []string{"s3:ListBucket", "s3:PutObject"}) {
+		t.Errorf("unexpected missing actions %v", missing)
+	}
+	wantUnused := []Unused{{File: "roles/admin.json", Policy: accessrole.DefaultPolicyName, Action: "iam:PassRole"}}
+	if !reflect.DeepEqual(diff.Unused, wantUnused) {
+		t.Errorf("want unused %v got %v", wantUnused, diff.Unused)
+	}
+}
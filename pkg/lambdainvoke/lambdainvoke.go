@@ -0,0 +1,211 @@
+// Package lambdainvoke runs requests against a deployed Provider handler by invoking
+// its Lambda function with the same payloads which Common Fate sends.
+package lambdainvoke
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/common-fate/clio"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/handlerclient"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+)
+
+// InvokeAPI is the subset of the Lambda client used by the Executor.
+type InvokeAPI interface {
+	Invoke(ctx context.Context, params *lambda.InvokeInput, optFns ...func(*lambda.Options)) (*lambda.InvokeOutput, error)
+}
+
+// Executor is a handlerclient.Executor which invokes a Lambda function.
+type Executor struct {
+	Client InvokeAPI
+	// FunctionName is the name or ARN of the Lambda function.
+	FunctionName string
+}
+
+var _ handlerclient.Executor = Executor{}
+
+// New creates an Executor. If roleARN is provided, the role is assumed to invoke the
+// function, in the same way as Common Fate assumes the handler's invoke role.
+func New(cfg aws.Config, functionName string, roleARN string) Executor {
+	if roleARN != "" {
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), roleARN)
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+	return Executor{Client: lambda.NewFromConfig(cfg), FunctionName: functionName}
+}
+
+// NewClient creates a handler client which invokes a Lambda function.
+func NewClient(cfg aws.Config, functionName string, roleARN string) *handlerclient.Client {
+	return &handlerclient.Client{Executor: New(cfg, functionName, roleARN)}
+}
+
+// payload is the request JSON sent to the Lambda function.
+type payload struct {
+	Type msg.RequestType `json:"type"`
+	Data msg.Request     `json:"data"`
+}
+
+// DecodePayload decodes a payload in the format sent to the handler, the
+// inverse of the payload built by Invoke.
+func DecodePayload(b []byte) (msg.Request, error) {
+	var p struct {
+		Type msg.RequestType `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	err := json.Unmarshal(b, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	var req msg.Request
+	switch p.Type {
+	case msg.RequestTypeGrant:
+		var v msg.Grant
+		err = unmarshalData(p.Data, &v)
+		req = v
+	case msg.RequestTypeRevoke:
+		var v msg.Revoke
+		err = unmarshalData(p.Data, &v)
+		req = v
+	case msg.RequestTypeDescribe:
+		req = msg.Describe{}
+	case msg.RequestTypeLoadResources:
+		var v msg.LoadResources
+		err = unmarshalData(p.Data, &v)
+		req = v
+	default:
+		return nil, fmt.Errorf("unknown request type %q, must be one of grant, revoke, describe or load", p.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s request: %w", p.Type, err)
+	}
+	return req, nil
+}
+
+func unmarshalData(data json.RawMessage, v any) error {
+	if len(data) == 0 {
+		return fmt.Errorf("missing 'data' field")
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Invocation is the outcome of invoking the handler.
+type Invocation struct {
+	// Result is the decoded response, if the handler succeeded.
+	Result *msg.Result
+	// FunctionError is the error returned by the handler, if it failed.
+	FunctionError *FunctionError
+	// Logs is the tail of the function logs.
+	Logs string
+}
+
+// FunctionError is an error returned by the handler, such as an unhandled Python exception.
+type FunctionError struct {
+	// Kind is 'Unhandled' for exceptions, or 'Handled' for errors returned by the runtime.
+	Kind       string   `json:"kind"`
+	ErrorType  string   `json:"errorType,omitempty"`
+	Message    string   `json:"errorMessage"`
+	StackTrace []string `json:"stackTrace,omitempty"`
+}
+
+func (e *FunctionError) Error() string {
+	if e.ErrorType == "" {
+		return "the handler returned an error: " + e.Message
+	}
+	return fmt.Sprintf("the handler returned an error: %s: %s", e.ErrorType, e.Message)
+}
+
+// Traceback formats the error like a Python traceback. It's empty if the error has no stack trace.
+func (e *FunctionError) Traceback() string {
+	if len(e.StackTrace) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Traceback (most recent call last):\n")
+	for _, frame := range e.StackTrace {
+		b.WriteString(frame)
+		if !strings.HasSuffix(frame, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString(e.ErrorType + ": " + e.Message)
+	return b.String()
+}
+
+// decodeFunctionError decodes the error payload of the Lambda runtime, which looks like
+// {"errorMessage": "...", "errorType": "...", "stackTrace": ["  File ..."]}.
+func decodeFunctionError(kind string, payload []byte) *FunctionError {
+	fe := FunctionError{Kind: kind}
+	err := json.Unmarshal(payload, &fe)
+	if err != nil || fe.Message == "" {
+		fe.Message = string(payload)
+	}
+	return &fe
+}
+
+// Invoke invokes the handler and returns the result, the function error
+// and the logs. An error is only returned if the function couldn't be invoked.
+func (e Executor) Invoke(ctx context.Context, request msg.Request) (*Invocation, error) {
+	payloadbytes, err := json.Marshal(payload{Type: request.Type(), Data: request})
+	if err != nil {
+		return nil, err
+	}
+	clio.Debugw("invoking handler", "function", e.FunctionName, "payload", string(payloadbytes))
+
+	out, err := e.Client.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName:   &e.FunctionName,
+		InvocationType: types.InvocationTypeRequestResponse,
+		Payload:        payloadbytes,
+		LogType:        types.LogTypeTail,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var inv Invocation
+
+	// the log tail is only included for synchronous invocations.
+	if out.LogResult != nil {
+		logs, err := base64.StdEncoding.DecodeString(*out.LogResult)
+		if err != nil {
+			return nil, fmt.Errorf("decoding function logs: %w", err)
+		}
+		inv.Logs = string(logs)
+	}
+
+	if out.FunctionError != nil {
+		inv.FunctionError = decodeFunctionError(*out.FunctionError, out.Payload)
+		return &inv, nil
+	}
+
+	var res msg.Result
+	err = json.Unmarshal(out.Payload, &res)
+	if err != nil {
+		return nil, fmt.Errorf("decoding handler response: %w", err)
+	}
+	inv.Result = &res
+	return &inv, nil
+}
+
+func (e Executor) Execute(ctx context.Context, request msg.Request) (*msg.Result, error) {
+	inv, err := e.Invoke(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if inv.Logs != "" {
+		clio.Debugf("function logs:\n%s", inv.Logs)
+	}
+	if inv.FunctionError != nil {
+		return nil, inv.FunctionError
+	}
+	return inv.Result, nil
+}
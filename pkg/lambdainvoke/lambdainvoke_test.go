@@ -0,0 +1,104 @@
+package lambdainvoke
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+)
+
+type mockInvoker struct {
+	input *lambda.InvokeInput
+	out   *lambda.InvokeOutput
+}
+
+func (m *mockInvoker) Invoke(ctx context.Context, params *lambda.InvokeInput, optFns ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	m.input = params
+	return m.out, nil
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     msg.Request
+		out         *lambda.InvokeOutput
+		wantPayload string
+		wantResult  string
+		wantErr     string
+	}{
+		{
+			name: "grant",
+			request: msg.Grant{
+				Subject: "alice@example.com",
+				Target:  msg.Target{Kind: "Group", Arguments: map[string]string{"group": "admins"}},
+				Request: msg.AccessRequest{ID: "req_1"},
+			},
+			out:         &lambda.InvokeOutput{Payload: []byte(`{"response":{"state":{"id":1}}}`)},
+			wantPayload: `{"type":"grant","data":{"subject":"alice@example.com","target":{"kind":"Group","arguments":{"group":"admins"}},"request":{"id":"req_1"}}}`,
+			wantResult:  `{"state":{"id":1}}`,
+		},
+		{
+			name:        "load without logs",
+			request:     msg.LoadResources{Task: "fetch_groups", Ctx: map[string]any{}},
+			out:         &lambda.InvokeOutput{Payload: []byte(`{"response":{"resources":[]}}`), LogResult: nil},
+			wantPayload: `{"type":"load","data":{"task":"fetch_groups","ctx":{}}}`,
+			wantResult:  `{"resources":[]}`,
+		},
+		{
+			name:        "function error",
+			request:     msg.Describe{},
+			out:         &lambda.InvokeOutput{FunctionError: aws.String("Unhandled"), Payload: []byte(`{"errorMessage":"boom","errorType":"ValueError"}`)},
+			wantPayload: `{"type":"describe","data":{}}`,
+			wantErr:     `the handler returned an error: ValueError: boom`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockInvoker{out: tt.out}
+			e := Executor{Client: m, FunctionName: "cf-handler-test"}
+
+			res, err := e.Execute(context.Background(), tt.request)
+			if string(m.input.Payload) != tt.wantPayload {
+				t.Errorf("want payload %s got %s", tt.wantPayload, m.input.Payload)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("want error %q got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(res.Response)
+			if string(got) != tt.wantResult {
+				t.Errorf("want result %s got %s", tt.wantResult, got)
+			}
+		})
+	}
+}
+
+func TestInvokeFunctionError(t *testing.T) {
+	m := &mockInvoker{out: &lambda.InvokeOutput{
+		FunctionError: aws.String("Unhandled"),
+		Payload:       []byte(`{"errorMessage":"group not found","errorType":"KeyError","stackTrace":["  File \"/var/task/provider.py\", line 10, in grant\n    groups[name]\n"]}`),
+		LogResult:     aws.String(base64.StdEncoding.EncodeToString([]byte("START RequestId: 1\n"))),
+	}}
+	e := Executor{Client: m, FunctionName: "cf-handler-test"}
+
+	inv, err := e.Invoke(context.Background(), msg.Describe{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.Logs != "START RequestId: 1\n" {
+		t.Errorf("unexpected logs %q", inv.Logs)
+	}
+	want := "Traceback (most recent call last):\n  File \"/var/task/provider.py\", line 10, in grant\n    groups[name]\nKeyError: group not found"
+	if got := inv.FunctionError.Traceback(); got != want {
+		t.Errorf("want traceback\n%s\ngot\n%s", want, got)
+	}
+}
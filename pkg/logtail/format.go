@@ -0,0 +1,69 @@
+package logtail
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Format formats an event for display. Lines logged by structlog with the JSON
+// renderer, like {"event": "granting access", "level": "info", "group": "admins"},
+// are shown as 'info  granting access group=admins'. Other lines are shown as-is.
+func Format(e Event) string {
+	prefix := e.Timestamp.Format("15:04:05.000")
+	if e.RequestID != "" {
+		// the first section of the request ID is enough to tell concurrent requests apart.
+		id, _, _ := strings.Cut(e.RequestID, "-")
+		prefix += " " + id
+	}
+
+	line, ok := formatStructlog(e.Message)
+	if !ok {
+		line = e.Message
+	}
+	return prefix + " " + line
+}
+
+func formatStructlog(message string) (string, bool) {
+	if !strings.HasPrefix(message, "{") {
+		return "", false
+	}
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal([]byte(message), &fields)
+	if err != nil {
+		return "", false
+	}
+	var event string
+	if err := json.Unmarshal(fields["event"], &event); err != nil {
+		return "", false
+	}
+
+	var level string
+	_ = json.Unmarshal(fields["level"], &level)
+	if level == "" {
+		level = "-"
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		// the timestamp is already shown from the log event.
+		if k == "event" || k == "level" || k == "timestamp" {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-5s %s", level, event)
+	for _, k := range keys {
+		var s string
+		if json.Unmarshal(fields[k], &s) == nil && !strings.ContainsAny(s, " \"") {
+			fmt.Fprintf(&b, " %s=%s", k, s)
+		} else {
+			fmt.Fprintf(&b, " %s=%s", k, fields[k])
+		}
+	}
+	return b.String(), true
+}
@@ -0,0 +1,239 @@
+// Package logtail reads the CloudWatch logs of a handler's Lambda function.
+//
+// It only uses logs:DescribeLogStreams and logs:GetLogEvents, which are the
+// log permissions granted to the handler's invoke role.
+package logtail
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// eventTimestampLag is how far behind the lastEventTimestamp of a log stream can be.
+// CloudWatch only updates it eventually, so streams are ordered by it but filtered by
+// their ingestion time instead.
+const eventTimestampLag = time.Hour
+
+// Event is a log event from the Lambda function.
+type Event struct {
+	Stream    string
+	Timestamp time.Time
+	Message   string
+	// RequestID is the Lambda request ID which the event was logged for,
+	// if the event was logged between the START and REPORT lines of an invocation.
+	RequestID string
+}
+
+// Options control which log events are read.
+type Options struct {
+	// Since is the earliest time to read events from.
+	Since time.Time
+	// Follow keeps polling for new events until the context is cancelled.
+	Follow bool
+	// RequestID only returns the events of a single Lambda request, if set.
+	RequestID string
+	// PollInterval is the time between polls when following. Defaults to 2 seconds.
+	PollInterval time.Duration
+}
+
+// LogsAPI is the subset of the CloudWatch Logs client used by the Tailer.
+type LogsAPI interface {
+	DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error)
+	GetLogEvents(ctx context.Context, params *cloudwatchlogs.GetLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetLogEventsOutput, error)
+}
+
+// Tailer reads the events of a log group.
+type Tailer struct {
+	Client LogsAPI
+	// LogGroup is the name of the log group, e.g. '/aws/lambda/my-handler'.
+	LogGroup string
+}
+
+// NewFromConfig creates a Tailer for the log group, e.g. '/aws/lambda/my-handler'.
+func NewFromConfig(cfg aws.Config, logGroup string) *Tailer {
+	return &Tailer{
+		Client:   cloudwatchlogs.NewFromConfig(cfg),
+		LogGroup: logGroup,
+	}
+}
+
+// streamState is the position of the tailer in a log stream.
+type streamState struct {
+	nextToken string
+	// requestID is the request which the Lambda function is currently handling in the stream.
+	// A Lambda instance handles one request at a time, so events in a stream belong to the last START line.
+	requestID string
+}
+
+// Tail calls fn for each event in timestamp order. If opts.Follow is set, it polls
+// for new events until the context is cancelled, in which case it returns nil.
+func (t *Tailer) Tail(ctx context.Context, opts Options, fn func(Event) error) error {
+	if opts.PollInterval == 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+
+	streams := map[string]*streamState{}
+	for {
+		events, err := t.poll(ctx, opts, streams)
+		if ctx.Err() != nil && opts.Follow {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			if opts.RequestID != "" && e.RequestID != opts.RequestID {
+				continue
+			}
+			err = fn(e)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !opts.Follow {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(opts.PollInterval):
+		}
+	}
+}
+
+// poll reads the new events of every active log stream.
+func (t *Tailer) poll(ctx context.Context, opts Options, streams map[string]*streamState) ([]Event, error) {
+	names, err := t.activeStreams(ctx, opts.Since)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	for _, name := range names {
+		state, ok := streams[name]
+		if !ok {
+			state = &streamState{}
+			streams[name] = state
+		}
+		streamEvents, err := t.readStream(ctx, name, opts.Since, state)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, streamEvents...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+	return events, nil
+}
+
+// activeStreams returns the log streams which have received events since the provided time.
+func (t *Tailer) activeStreams(ctx context.Context, since time.Time) ([]string, error) {
+	sinceMS := since.UnixMilli()
+	stopMS := since.Add(-eventTimestampLag).UnixMilli()
+
+	var names []string
+	var nextToken *string
+	for {
+		out, err := t.Client.DescribeLogStreams(ctx, &cloudwatchlogs.DescribeLogStreamsInput{
+			LogGroupName: aws.String(t.LogGroup),
+			OrderBy:      types.OrderByLastEventTime,
+			Descending:   aws.Bool(true),
+			NextToken:    nextToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, s := range out.LogStreams {
+			lastEvent := aws.ToInt64(s.LastEventTimestamp)
+			if lastEvent < stopMS {
+				return names, nil
+			}
+			if aws.ToInt64(s.LastIngestionTime) >= sinceMS || lastEvent >= sinceMS {
+				names = append(names, aws.ToString(s.LogStreamName))
+			}
+		}
+
+		if out.NextToken == nil || aws.ToString(out.NextToken) == aws.ToString(nextToken) {
+			return names, nil
+		}
+		nextToken = out.NextToken
+	}
+}
+
+// readStream reads the new events of a log stream, continuing from the state of the previous read.
+func (t *Tailer) readStream(ctx context.Context, name string, since time.Time, state *streamState) ([]Event, error) {
+	var events []Event
+	for {
+		input := &cloudwatchlogs.GetLogEventsInput{
+			LogGroupName:  aws.String(t.LogGroup),
+			LogStreamName: aws.String(name),
+			StartFromHead: aws.Bool(true),
+		}
+		if state.nextToken != "" {
+			input.NextToken = aws.String(state.nextToken)
+		} else {
+			input.StartTime = aws.Int64(since.UnixMilli())
+		}
+
+		out, err := t.Client.GetLogEvents(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range out.Events {
+			message := aws.ToString(e.Message)
+			events = append(events, Event{
+				Stream:    name,
+				Timestamp: time.UnixMilli(aws.ToInt64(e.Timestamp)).UTC(),
+				Message:   strings.TrimRight(message, "\n"),
+				RequestID: state.track(message),
+			})
+		}
+
+		// the end of the stream has been reached when the same token is returned again.
+		nextForwardToken := aws.ToString(out.NextForwardToken)
+		done := nextForwardToken == "" || nextForwardToken == state.nextToken
+		if nextForwardToken != "" {
+			state.nextToken = nextForwardToken
+		}
+		if done {
+			return events, nil
+		}
+	}
+}
+
+// track updates the current request of the stream from the Lambda
+// runtime's START and REPORT lines, and returns the request of the message.
+func (s *streamState) track(message string) string {
+	if id, ok := lambdaRequestID(message, "START RequestId: "); ok {
+		s.requestID = id
+		return id
+	}
+	if id, ok := lambdaRequestID(message, "REPORT RequestId: "); ok {
+		s.requestID = ""
+		return id
+	}
+	return s.requestID
+}
+
+// lambdaRequestID returns the request ID from a line like 'START RequestId: <id> Version: $LATEST'.
+func lambdaRequestID(message string, prefix string) (string, bool) {
+	if !strings.HasPrefix(message, prefix) {
+		return "", false
+	}
+	id, _, _ := strings.Cut(strings.TrimSpace(message[len(prefix):]), " ")
+	id, _, _ = strings.Cut(id, "\t")
+	return id, true
+}
@@ -0,0 +1,148 @@
+package logtail
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+type fakeEvent struct {
+	Timestamp int64
+	Message   string
+}
+
+// fakeLogs is a stand-in for CloudWatch Logs. GetLogEvents returns
+// one event per page, to exercise the pagination of the tailer.
+type fakeLogs struct {
+	t       *testing.T
+	streams map[string][]fakeEvent
+}
+
+func (f fakeLogs) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	f.checkLogGroup(params.LogGroupName)
+	var out cloudwatchlogs.DescribeLogStreamsOutput
+	for name, events := range f.streams {
+		last := events[len(events)-1].Timestamp
+		out.LogStreams = append(out.LogStreams, types.LogStream{LogStreamName: aws.String(name), LastEventTimestamp: aws.Int64(last), LastIngestionTime: aws.Int64(last)})
+	}
+	return &out, nil
+}
+
+func (f fakeLogs) GetLogEvents(ctx context.Context, params *cloudwatchlogs.GetLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetLogEventsOutput, error) {
+	f.checkLogGroup(params.LogGroupName)
+	events := f.streams[aws.ToString(params.LogStreamName)]
+	pos := 0
+	if params.NextToken != nil {
+		pos = len(aws.ToString(params.NextToken))
+	} else {
+		for pos < len(events) && events[pos].Timestamp < aws.ToInt64(params.StartTime) {
+			pos++
+		}
+	}
+	var out cloudwatchlogs.GetLogEventsOutput
+	if pos < len(events) {
+		out.Events = []types.OutputLogEvent{{Timestamp: aws.Int64(events[pos].Timestamp), Message: aws.String(events[pos].Message)}}
+		pos++
+	}
+	// the token encodes the position in the stream as its length.
+	out.NextForwardToken = aws.String(strings.Repeat("f", pos))
+	return &out, nil
+}
+
+func (f fakeLogs) checkLogGroup(name *string) {
+	if aws.ToString(name) != "/aws/lambda/cf-handler-test" {
+		f.t.Errorf("unexpected log group %s", aws.ToString(name))
+	}
+}
+
+func TestTail(t *testing.T) {
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	ms := func(sec int) int64 { return base.Add(time.Duration(sec) * time.Second).UnixMilli() }
+
+	logs := fakeLogs{t: t, streams: map[string][]fakeEvent{
+		"2023/01/01/[$LATEST]a": {
+			{Timestamp: ms(0), Message: "old event"},
+			{Timestamp: ms(10), Message: "START RequestId: 11111111-aaaa Version: $LATEST\n"},
+			{Timestamp: ms(11), Message: `{"event": "granting access", "level": "info", "group": "admins"}` + "\n"},
+			{Timestamp: ms(12), Message: "REPORT RequestId: 11111111-aaaa\tDuration: 1.00 ms\n"},
+		},
+		"2023/01/01/[$LATEST]b": {
+			{Timestamp: ms(13), Message: "START RequestId: 22222222-bbbb Version: $LATEST\n"},
+			{Timestamp: ms(14), Message: "revoking"},
+		},
+	}}
+	tailer := &Tailer{Client: logs, LogGroup: "/aws/lambda/cf-handler-test"}
+
+	tests := []struct {
+		name      string
+		requestID string
+		want      []string
+	}{
+		{
+			name: "all",
+			want: []string{
+				"00:00:10.000 11111111 START RequestId: 11111111-aaaa Version: $LATEST",
+				"00:00:11.000 11111111 info  granting access group=admins",
+				"00:00:12.000 11111111 REPORT RequestId: 11111111-aaaa\tDuration: 1.00 ms",
+				"00:00:13.000 22222222 START RequestId: 22222222-bbbb Version: $LATEST",
+				"00:00:14.000 22222222 revoking",
+			},
+		},
+		{
+			name:      "request ID",
+			requestID: "11111111-aaaa",
+			want: []string{
+				"00:00:10.000 11111111 START RequestId: 11111111-aaaa Version: $LATEST",
+				"00:00:11.000 11111111 info  granting access group=admins",
+				"00:00:12.000 11111111 REPORT RequestId: 11111111-aaaa\tDuration: 1.00 ms",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			err := tailer.Tail(context.Background(), Options{Since: base.Add(5 * time.Second), RequestID: tt.requestID}, func(e Event) error {
+				got = append(got, Format(e))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("want\n%s\ngot\n%s", strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	ts := time.Date(2023, 1, 1, 12, 30, 0, 0, time.UTC)
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{
+			message: `{"event": "granted", "level": "warning", "timestamp": "2023-01-01T12:30:00Z", "user": "alice@example.com", "args": {"group": "admins"}, "note": "two words"}`,
+			want:    `12:30:00.000 warning granted args={"group": "admins"} note="two words" user=alice@example.com`,
+		},
+		{
+			message: `{"not": "structlog"}`,
+			want:    `12:30:00.000 {"not": "structlog"}`,
+		},
+		{
+			message: "plain text",
+			want:    "12:30:00.000 plain text",
+		},
+	}
+	for _, tt := range tests {
+		got := Format(Event{Timestamp: ts, Message: tt.message})
+		if got != tt.want {
+			t.Errorf("want %s got %s", tt.want, got)
+		}
+	}
+}
@@ -33,6 +33,37 @@ type Config struct {
 	Version   string   `toml:"version"`
 	Language  string   `toml:"language"`
 	Meta      MetaInfo `toml:"meta"`
+	// SecretBackend is the default backend that the Provider reads secrets from,
+	// either 'ssm' or 'secretsmanager'. If empty, SSM is used.
+	SecretBackend string `toml:"secret_backend"`
+	// Lint configures the IAM policy linting run by 'pdk package'.
+	Lint LintConfig `toml:"lint"`
+	// Tags are applied to every resource in the generated templates. Each tag has a
+	// template parameter so that its value can be changed when deploying, and tags
+	// with an empty value must be provided when deploying.
+	Tags map[string]string `toml:"tags"`
+}
+
+// LintConfig configures the IAM policy linting run by 'pdk package'.
+// The rule IDs and severities are validated by 'pdk package' before linting.
+type LintConfig struct {
+	// FailOn is the minimum severity which causes linting to fail. Defaults to 'error'.
+	FailOn string `toml:"fail_on"`
+	// Disable is a list of rule IDs which are not run.
+	Disable []string `toml:"disable"`
+	// Severity overrides the default severity of rules.
+	Severity map[string]string `toml:"severity"`
+	// Suppress ignores findings for specific rules and policies.
+	Suppress []LintSuppression `toml:"suppress"`
+}
+
+// LintSuppression ignores findings matching a rule, and optionally a location and statement ID.
+type LintSuppression struct {
+	Rule string `toml:"rule"`
+	// Location is a glob matching the location of the policy, e.g. 'roles/*'.
+	Location string `toml:"location"`
+	Sid      string `toml:"sid"`
+	Reason   string `toml:"reason"`
 }
 
 func LoadFile(filepath string) (Config, error) {
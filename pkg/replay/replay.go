@@ -0,0 +1,160 @@
+// Package replay runs a batch of handler requests from a JSON lines file and records
+// the outcome of each, so that captured traffic can be replayed against a new version of a Provider.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/common-fate/pdk/pkg/lambdainvoke"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/handlerclient"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+)
+
+// maxLineSize is the longest request line which can be read.
+const maxLineSize = 1024 * 1024
+
+// Request is a line of the requests file. It has the same
+// format as the payload which is sent to the handler Lambda function:
+//
+//	{"type": "grant", "data": {"subject": "alice@example.com", ...}}
+type Request struct {
+	// Line is the line number of the request in the file, starting at 1.
+	Line    int
+	Request msg.Request
+}
+
+// Result is a line of the results file.
+type Result struct {
+	Line       int             `json:"line"`
+	Type       msg.RequestType `json:"type"`
+	Request    msg.Request     `json:"request"`
+	OK         bool            `json:"ok"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	StartedAt  time.Time       `json:"startedAt"`
+	DurationMS int64           `json:"durationMs"`
+}
+
+// Summary counts the outcomes of a replay.
+type Summary struct {
+	Succeeded int
+	Failed    int
+}
+
+// Read parses the requests file. Blank lines are skipped.
+func Read(r io.Reader) ([]Request, error) {
+	var requests []Request
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		req, err := lambdainvoke.DecodePayload(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		requests = append(requests, Request{Line: line, Request: req})
+	}
+	return requests, scanner.Err()
+}
+
+// Run executes the requests and writes a result for each of them to w, in the
+// same order as the requests. Up to concurrency requests are run at the same time;
+// with a concurrency of 1 each request finishes before the next one starts.
+// Failed requests are recorded in the results rather than stopping the replay.
+//
+// Each result is written as soon as it and the results before it have finished, so that
+// progress can be followed and results aren't lost if the replay is stopped. If ctx is
+// cancelled no more requests are started, and the context error is returned once the
+// running requests have been written.
+func Run(ctx context.Context, executor handlerclient.Executor, requests []Request, concurrency int, w io.Writer) (Summary, error) {
+	if concurrency < 1 {
+		return Summary{}, fmt.Errorf("concurrency must be at least 1, got %d", concurrency)
+	}
+
+	// pending receives a channel for each request in order, which receives its result.
+	// It's buffered so that later requests can finish while an earlier one is still running.
+	pending := make(chan chan Result, concurrency)
+	// running limits the number of requests which run at the same time.
+	running := make(chan struct{}, concurrency)
+	// stop is closed if the results can't be written, so that no more requests are started.
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		defer close(pending)
+		for _, req := range requests {
+			select {
+			case running <- struct{}{}:
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			}
+			// the context may have been cancelled while waiting for a running request to finish.
+			if ctx.Err() != nil {
+				return
+			}
+
+			req := req
+			result := make(chan Result, 1)
+			go func() {
+				defer func() { <-running }()
+				result <- execute(ctx, executor, req)
+			}()
+
+			select {
+			case pending <- result:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var summary Summary
+	enc := json.NewEncoder(w)
+	for result := range pending {
+		res := <-result
+		if res.OK {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+		err := enc.Encode(res)
+		if err != nil {
+			return summary, err
+		}
+	}
+	return summary, ctx.Err()
+}
+
+func execute(ctx context.Context, executor handlerclient.Executor, req Request) Result {
+	res := Result{
+		Line:      req.Line,
+		Type:      req.Request.Type(),
+		Request:   req.Request,
+		StartedAt: time.Now().UTC(),
+	}
+
+	out, err := executor.Execute(ctx, req.Request)
+	res.DurationMS = time.Since(res.StartedAt).Milliseconds()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	res.OK = true
+	if out != nil {
+		res.Response = out.Response
+	}
+	return res
+}
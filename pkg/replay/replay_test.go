@@ -0,0 +1,221 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/common-fate/provider-registry-sdk-go/pkg/msg"
+)
+
+type fakeExecutor struct{}
+
+func (fakeExecutor) Execute(ctx context.Context, request msg.Request) (*msg.Result, error) {
+	switch r := request.(type) {
+	case msg.Grant:
+		if r.Subject == "mallory@example.com" {
+			return nil, errors.New("user not found")
+		}
+		return &msg.Result{Response: json.RawMessage(`{"state":{"id":1}}`)}, nil
+	case msg.LoadResources:
+		return &msg.Result{Response: json.RawMessage(`{"resources":[]}`)}, nil
+	}
+	return &msg.Result{Response: json.RawMessage(`{}`)}, nil
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantTypes []msg.RequestType
+		wantLines []int
+		wantErr   string
+	}{
+		{
+			name: "all request types",
+			input: `{"type":"grant","data":{"subject":"alice@example.com","target":{"kind":"Group","arguments":{"group":"admins"}},"request":{"id":"req_1"}}}
+
+{"type":"revoke","data":{"subject":"alice@example.com","target":{"kind":"Group","arguments":{"group":"admins"}},"request":{"id":"req_1"},"state":{"id":1}}}
+{"type":"describe","data":{}}
+{"type":"load","data":{"task":"fetch_groups","ctx":{}}}
+`,
+			wantTypes: []msg.RequestType{msg.RequestTypeGrant, msg.RequestTypeRevoke, msg.RequestTypeDescribe, msg.RequestTypeLoadResources},
+			wantLines: []int{1, 3, 4, 5},
+		},
+		{
+			name:    "unknown type",
+			input:   `{"type":"describe"}` + "\n" + `{"type":"delete","data":{}}`,
+			wantErr: `line 2: unknown request type "delete", must be one of grant, revoke, describe or load`,
+		},
+		{
+			name:    "missing data",
+			input:   `{"type":"grant"}`,
+			wantErr: `line 1: decoding grant request: missing 'data' field`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("want error %q got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.wantTypes) {
+				t.Fatalf("want %d requests got %d", len(tt.wantTypes), len(got))
+			}
+			for i, r := range got {
+				if r.Request.Type() != tt.wantTypes[i] || r.Line != tt.wantLines[i] {
+					t.Errorf("request %d: want %s on line %d got %s on line %d", i, tt.wantTypes[i], tt.wantLines[i], r.Request.Type(), r.Line)
+				}
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	input := `{"type":"grant","data":{"subject":"alice@example.com","target":{"kind":"Group","arguments":{}},"request":{"id":"req_1"}}}
+{"type":"grant","data":{"subject":"mallory@example.com","target":{"kind":"Group","arguments":{}},"request":{"id":"req_2"}}}
+{"type":"load","data":{"task":"fetch_groups","ctx":{}}}
+`
+	requests, err := Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, concurrency := range []int{1, 3} {
+		var out bytes.Buffer
+		summary, err := Run(context.Background(), fakeExecutor{}, requests, concurrency, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary != (Summary{Succeeded: 2, Failed: 1}) {
+			t.Errorf("concurrency %d: unexpected summary %+v", concurrency, summary)
+		}
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("concurrency %d: want 3 results got %d", concurrency, len(lines))
+		}
+		want := []struct {
+			line     int
+			ok       bool
+			response string
+			err      string
+		}{
+			{line: 1, ok: true, response: `{"state":{"id":1}}`},
+			{line: 2, ok: false, err: "user not found"},
+			{line: 3, ok: true, response: `{"resources":[]}`},
+		}
+		for i, l := range lines {
+			var res struct {
+				Line     int             `json:"line"`
+				OK       bool            `json:"ok"`
+				Response json.RawMessage `json:"response"`
+				Error    string          `json:"error"`
+			}
+			err = json.Unmarshal([]byte(l), &res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Line != want[i].line || res.OK != want[i].ok || string(res.Response) != want[i].response || res.Error != want[i].err {
+				t.Errorf("concurrency %d: unexpected result %s", concurrency, l)
+			}
+		}
+	}
+
+	_, err = Run(context.Background(), fakeExecutor{}, requests, 0, &bytes.Buffer{})
+	if err == nil {
+		t.Error("expected an error for a concurrency of 0")
+	}
+}
+
+// funcExecutor calls the function with the request ID of each grant.
+type funcExecutor func(ctx context.Context, requestID string) error
+
+func (f funcExecutor) Execute(ctx context.Context, request msg.Request) (*msg.Result, error) {
+	err := f(ctx, request.(msg.Grant).Request.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &msg.Result{Response: json.RawMessage(`{}`)}, nil
+}
+
+// notifyWriter sends on written after each write.
+type notifyWriter struct {
+	bytes.Buffer
+	written chan struct{}
+}
+
+func (w *notifyWriter) Write(p []byte) (int, error) {
+	n, err := w.Buffer.Write(p)
+	w.written <- struct{}{}
+	return n, err
+}
+
+func grants(n int) []Request {
+	var requests []Request
+	for i := 1; i <= n; i++ {
+		requests = append(requests, Request{Line: i, Request: msg.Grant{Request: msg.AccessRequest{ID: strconv.Itoa(i)}}})
+	}
+	return requests
+}
+
+func TestRunWritesResultsAsTheyFinish(t *testing.T) {
+	w := &notifyWriter{written: make(chan struct{}, 3)}
+
+	executor := funcExecutor(func(ctx context.Context, requestID string) error {
+		if requestID == "1" {
+			return nil
+		}
+		// the result of the first request should be written before the rest of the replay finishes.
+		select {
+		case <-w.written:
+			return nil
+		case <-time.After(5 * time.Second):
+			return errors.New("the first result wasn't written")
+		}
+	})
+
+	summary, err := Run(context.Background(), executor, grants(2), 1, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary != (Summary{Succeeded: 2}) {
+		t.Errorf("unexpected summary %+v: %s", summary, w.String())
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	executor := funcExecutor(func(_ context.Context, requestID string) error {
+		if requestID == "2" {
+			cancel()
+		}
+		return nil
+	})
+
+	var out bytes.Buffer
+	summary, err := Run(ctx, executor, grants(5), 1, &out)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("want context.Canceled got %v", err)
+	}
+	// the running request is recorded, but no more requests are started.
+	if summary != (Summary{Succeeded: 2}) {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if lines := strings.Count(out.String(), "\n"); lines != 2 {
+		t.Errorf("want 2 results got %d: %s", lines, out.String())
+	}
+}
@@ -0,0 +1,156 @@
+// Package secretstore writes Provider secrets to the
+// backend that the deployed Provider reads them from.
+package secretstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// Backend is the AWS service that Provider secrets are stored in.
+type Backend string
+
+const (
+	// SSM stores secrets as SecureString parameters in AWS Systems Manager Parameter Store.
+	SSM Backend = "ssm"
+	// SecretsManager stores secrets in AWS Secrets Manager.
+	SecretsManager Backend = "secretsmanager"
+)
+
+// Default is the backend used if a Provider doesn't specify one.
+const Default = SSM
+
+// Backends is a list of all of the supported secret backends.
+var Backends = []Backend{SSM, SecretsManager}
+
+// Parse parses a backend name. An empty string returns the default backend.
+func Parse(s string) (Backend, error) {
+	if s == "" {
+		return Default, nil
+	}
+	for _, b := range Backends {
+		if string(b) == s {
+			return b, nil
+		}
+	}
+	return "", fmt.Errorf("invalid secret backend %q: must be one of %s", s, strings.Join(backendNames(), ", "))
+}
+
+func backendNames() []string {
+	var names []string
+	for _, b := range Backends {
+		names = append(names, string(b))
+	}
+	return names
+}
+
+// Prefix returns the path prefix that secrets for a Provider are stored under.
+// The deployed Provider is only granted permission to read secrets under this prefix.
+//
+// SSM parameter paths begin with a '/', while Secrets Manager secret names do not.
+func (b Backend) Prefix(publisher, name string) string {
+	prefix := fmt.Sprintf("common-fate/provider/%s/%s/", publisher, name)
+	if b == SSM {
+		return "/" + prefix
+	}
+	return prefix
+}
+
+// Path returns the path of a secret for a particular config key.
+func (b Backend) Path(publisher, name, key string) string {
+	return b.Prefix(publisher, name) + key
+}
+
+// Ref returns the reference to a secret which is passed to
+// the Provider as a CloudFormation parameter, e.g. 'awsssm:///common-fate/provider/example/test/api_key'.
+func (b Backend) Ref(path string) string {
+	if b == SecretsManager {
+		return "awssecretsmanager://" + path
+	}
+	return "awsssm://" + path
+}
+
+// IsRef returns true if s is a reference to a secret, rather than a secret value.
+func IsRef(s string) bool {
+	return strings.HasPrefix(s, "awsssm://") || strings.HasPrefix(s, "awssecretsmanager://")
+}
+
+// SSMAPI is the subset of the SSM client used by the Writer.
+type SSMAPI interface {
+	PutParameter(ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error)
+}
+
+// SecretsManagerAPI is the subset of the Secrets Manager client used by the Writer.
+type SecretsManagerAPI interface {
+	CreateSecret(ctx context.Context, params *secretsmanager.CreateSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error)
+	PutSecretValue(ctx context.Context, params *secretsmanager.PutSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error)
+}
+
+// Writer writes secrets to a backend. Only the client for the backend needs to be set.
+type Writer struct {
+	Backend        Backend
+	SSM            SSMAPI
+	SecretsManager SecretsManagerAPI
+}
+
+// NewFromConfig creates a new Writer for the backend.
+func NewFromConfig(cfg aws.Config, backend Backend) *Writer {
+	w := &Writer{Backend: backend}
+	if backend == SecretsManager {
+		w.SecretsManager = secretsmanager.NewFromConfig(cfg)
+	} else {
+		w.SSM = ssm.NewFromConfig(cfg)
+	}
+	return w
+}
+
+// Put creates or updates a secret and returns the reference to it.
+func (w *Writer) Put(ctx context.Context, path string, value string) (string, error) {
+	var err error
+	switch w.Backend {
+	case SecretsManager:
+		err = w.putSecretsManager(ctx, path, value)
+	default:
+		err = w.putSSM(ctx, path, value)
+	}
+	if err != nil {
+		return "", err
+	}
+	return w.Backend.Ref(path), nil
+}
+
+func (w *Writer) putSSM(ctx context.Context, path string, value string) error {
+	_, err := w.SSM.PutParameter(ctx, &ssm.PutParameterInput{
+		Name:      aws.String(path),
+		Value:     aws.String(value),
+		Type:      ssmtypes.ParameterTypeSecureString,
+		Overwrite: aws.Bool(true),
+	})
+	return err
+}
+
+func (w *Writer) putSecretsManager(ctx context.Context, path string, value string) error {
+	_, err := w.SecretsManager.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+		Name:         aws.String(path),
+		SecretString: aws.String(value),
+	})
+	var exists *smtypes.ResourceExistsException
+	if !errors.As(err, &exists) {
+		return err
+	}
+
+	// the secret already exists, so store a new version of it.
+	_, err = w.SecretsManager.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(path),
+		SecretString: aws.String(value),
+	})
+	return err
+}
@@ -0,0 +1,87 @@
+package secretstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+)
+
+// fakeBackend records the calls made to SSM and Secrets Manager.
+type fakeBackend struct {
+	secretExists bool
+	calls        []string
+}
+
+func (f *fakeBackend) PutParameter(ctx context.Context, params *ssm.PutParameterInput, optFns ...func(*ssm.Options)) (*ssm.PutParameterOutput, error) {
+	f.calls = append(f.calls, "PutParameter "+aws.ToString(params.Name))
+	if params.Type != ssmtypes.ParameterTypeSecureString || !aws.ToBool(params.Overwrite) {
+		f.calls = append(f.calls, "unexpected parameter options")
+	}
+	return &ssm.PutParameterOutput{}, nil
+}
+
+func (f *fakeBackend) CreateSecret(ctx context.Context, params *secretsmanager.CreateSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error) {
+	f.calls = append(f.calls, "CreateSecret "+aws.ToString(params.Name))
+	if f.secretExists {
+		return nil, &smtypes.ResourceExistsException{Message: aws.String("exists")}
+	}
+	return &secretsmanager.CreateSecretOutput{}, nil
+}
+
+func (f *fakeBackend) PutSecretValue(ctx context.Context, params *secretsmanager.PutSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error) {
+	f.calls = append(f.calls, "PutSecretValue "+aws.ToString(params.SecretId))
+	return &secretsmanager.PutSecretValueOutput{}, nil
+}
+
+func TestWriterPut(t *testing.T) {
+	tests := []struct {
+		name         string
+		backend      Backend
+		secretExists bool
+		wantCalls    []string
+		wantRef      string
+	}{
+		{
+			name:      "ssm",
+			backend:   SSM,
+			wantCalls: []string{"PutParameter /common-fate/provider/example-org/test/api_key"},
+			wantRef:   "awsssm:///common-fate/provider/example-org/test/api_key",
+		},
+		{
+			name:      "secrets manager",
+			backend:   SecretsManager,
+			wantCalls: []string{"CreateSecret common-fate/provider/example-org/test/api_key"},
+			wantRef:   "awssecretsmanager://common-fate/provider/example-org/test/api_key",
+		},
+		{
+			name:         "secrets manager existing secret",
+			backend:      SecretsManager,
+			secretExists: true,
+			wantCalls:    []string{"CreateSecret common-fate/provider/example-org/test/api_key", "PutSecretValue common-fate/provider/example-org/test/api_key"},
+			wantRef:      "awssecretsmanager://common-fate/provider/example-org/test/api_key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeBackend{secretExists: tt.secretExists}
+			w := &Writer{Backend: tt.backend, SSM: fake, SecretsManager: fake}
+
+			got, err := w.Put(context.Background(), tt.backend.Path("example-org", "test", "api_key"), "value")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.wantRef {
+				t.Errorf("want ref %s got %s", tt.wantRef, got)
+			}
+			if !reflect.DeepEqual(fake.calls, tt.wantCalls) {
+				t.Errorf("want calls %v got %v", tt.wantCalls, fake.calls)
+			}
+		})
+	}
+}
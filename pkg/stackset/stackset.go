@@ -0,0 +1,189 @@
+// Package stackset deploys CloudFormation templates to the accounts in
+// AWS Organizations OUs using service-managed StackSets.
+package stackset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+	"github.com/common-fate/clio"
+)
+
+// CloudFormationAPI is the subset of the CloudFormation client used by the Deployer.
+type CloudFormationAPI interface {
+	DescribeStackSet(ctx context.Context, params *cloudformation.DescribeStackSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackSetOutput, error)
+	CreateStackSet(ctx context.Context, params *cloudformation.CreateStackSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackSetOutput, error)
+	UpdateStackSet(ctx context.Context, params *cloudformation.UpdateStackSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackSetOutput, error)
+	CreateStackInstances(ctx context.Context, params *cloudformation.CreateStackInstancesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackInstancesOutput, error)
+	DescribeStackSetOperation(ctx context.Context, params *cloudformation.DescribeStackSetOperationInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackSetOperationOutput, error)
+}
+
+type Deployer struct {
+	Client CloudFormationAPI
+	// PollInterval is how often StackSet operations are checked for completion.
+	PollInterval time.Duration
+}
+
+func NewFromConfig(cfg aws.Config) *Deployer {
+	return &Deployer{
+		Client:       cloudformation.NewFromConfig(cfg),
+		PollInterval: 5 * time.Second,
+	}
+}
+
+type DeployOpts struct {
+	StackSetName string
+	Description  string
+	TemplateBody string
+	Parameters   map[string]string
+	// OrganizationalUnitIDs are the OUs to deploy stack instances to.
+	OrganizationalUnitIDs []string
+	Regions               []string
+	// CallAs should be DELEGATED_ADMIN if deploying from a
+	// delegated administrator account. Defaults to SELF.
+	CallAs types.CallAs
+}
+
+// Deploy creates or updates a service-managed StackSet and deploys stack instances
+// to the provided OUs and regions. Accounts which are added to the OUs later on
+// will have the stack deployed automatically.
+func (d *Deployer) Deploy(ctx context.Context, opts DeployOpts) error {
+	if len(opts.OrganizationalUnitIDs) == 0 {
+		return errors.New("at least one organizational unit ID must be provided")
+	}
+	if len(opts.Regions) == 0 {
+		return errors.New("at least one region must be provided")
+	}
+	if opts.CallAs == "" {
+		opts.CallAs = types.CallAsSelf
+	}
+
+	params := parameters(opts.Parameters)
+
+	_, err := d.Client.DescribeStackSet(ctx, &cloudformation.DescribeStackSetInput{
+		StackSetName: &opts.StackSetName,
+		CallAs:       opts.CallAs,
+	})
+	var nfe *types.StackSetNotFoundException
+	if errors.As(err, &nfe) {
+		clio.Infof("creating StackSet %s", opts.StackSetName)
+		_, err = d.Client.CreateStackSet(ctx, &cloudformation.CreateStackSetInput{
+			StackSetName:    &opts.StackSetName,
+			Description:     optionalString(opts.Description),
+			TemplateBody:    &opts.TemplateBody,
+			Parameters:      params,
+			PermissionModel: types.PermissionModelsServiceManaged,
+			AutoDeployment: &types.AutoDeployment{
+				Enabled:                      aws.Bool(true),
+				RetainStacksOnAccountRemoval: aws.Bool(false),
+			},
+			Capabilities:     []types.Capability{types.CapabilityCapabilityNamedIam},
+			ManagedExecution: &types.ManagedExecution{Active: aws.Bool(true)},
+			CallAs:           opts.CallAs,
+		})
+		if err != nil {
+			return fmt.Errorf("creating StackSet %s: %w", opts.StackSetName, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("describing StackSet %s: %w", opts.StackSetName, err)
+	} else {
+		// the StackSet exists, so update the template and parameters of the existing stack instances.
+		clio.Infof("updating StackSet %s", opts.StackSetName)
+		out, err := d.Client.UpdateStackSet(ctx, &cloudformation.UpdateStackSetInput{
+			StackSetName:     &opts.StackSetName,
+			Description:      optionalString(opts.Description),
+			TemplateBody:     &opts.TemplateBody,
+			Parameters:       params,
+			Capabilities:     []types.Capability{types.CapabilityCapabilityNamedIam},
+			ManagedExecution: &types.ManagedExecution{Active: aws.Bool(true)},
+			CallAs:           opts.CallAs,
+		})
+		if err != nil {
+			return fmt.Errorf("updating StackSet %s: %w", opts.StackSetName, err)
+		}
+		err = d.wait(ctx, opts, out.OperationId)
+		if err != nil {
+			return err
+		}
+	}
+
+	// create stack instances for any accounts in the OUs which don't have them yet.
+	clio.Infof("deploying StackSet %s to %v in %v", opts.StackSetName, opts.OrganizationalUnitIDs, opts.Regions)
+	out, err := d.Client.CreateStackInstances(ctx, &cloudformation.CreateStackInstancesInput{
+		StackSetName: &opts.StackSetName,
+		Regions:      opts.Regions,
+		DeploymentTargets: &types.DeploymentTargets{
+			OrganizationalUnitIds: opts.OrganizationalUnitIDs,
+		},
+		CallAs: opts.CallAs,
+	})
+	if err != nil {
+		return fmt.Errorf("creating stack instances for StackSet %s: %w", opts.StackSetName, err)
+	}
+
+	return d.wait(ctx, opts, out.OperationId)
+}
+
+// wait polls a StackSet operation until it completes.
+func (d *Deployer) wait(ctx context.Context, opts DeployOpts, operationID *string) error {
+	if operationID == nil {
+		return nil
+	}
+	for {
+		out, err := d.Client.DescribeStackSetOperation(ctx, &cloudformation.DescribeStackSetOperationInput{
+			StackSetName: &opts.StackSetName,
+			OperationId:  operationID,
+			CallAs:       opts.CallAs,
+		})
+		if err != nil {
+			return fmt.Errorf("describing StackSet operation %s: %w", *operationID, err)
+		}
+		if out.StackSetOperation == nil {
+			return fmt.Errorf("StackSet operation %s was not found", *operationID)
+		}
+
+		switch status := out.StackSetOperation.Status; status {
+		case types.StackSetOperationStatusSucceeded:
+			clio.Debugf("StackSet operation %s succeeded", *operationID)
+			return nil
+		case types.StackSetOperationStatusFailed, types.StackSetOperationStatusStopped:
+			return fmt.Errorf("StackSet operation %s %s, check the stack instances in the CloudFormation console for details", *operationID, status)
+		default:
+			clio.Debugf("StackSet operation %s is %s", *operationID, status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d.PollInterval):
+		}
+	}
+}
+
+// parameters converts a map of parameters into CloudFormation parameters, sorted by key.
+func parameters(m map[string]string) []types.Parameter {
+	var params []types.Parameter
+	for k, v := range m {
+		params = append(params, types.Parameter{
+			ParameterKey:   aws.String(k),
+			ParameterValue: aws.String(v),
+		})
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return *params[i].ParameterKey < *params[j].ParameterKey
+	})
+	return params
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,146 @@
+package stackset
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation"
+	"github.com/aws/aws-sdk-go-v2/service/cloudformation/types"
+)
+
+// fakeCloudFormation is a local stand-in for the CloudFormation StackSet APIs.
+type fakeCloudFormation struct {
+	stackSets  map[string]*cloudformation.CreateStackSetInput
+	operations map[string][]types.StackSetOperationStatus
+	calls      []string
+	instances  []*cloudformation.CreateStackInstancesInput
+	opCount    int
+	// failOperations causes all operations to fail.
+	failOperations bool
+}
+
+func newFake() *fakeCloudFormation {
+	return &fakeCloudFormation{
+		stackSets:  map[string]*cloudformation.CreateStackSetInput{},
+		operations: map[string][]types.StackSetOperationStatus{},
+	}
+}
+
+func (f *fakeCloudFormation) startOperation() *string {
+	f.opCount++
+	id := fmt.Sprintf("op-%d", f.opCount)
+	final := types.StackSetOperationStatusSucceeded
+	if f.failOperations {
+		final = types.StackSetOperationStatusFailed
+	}
+	f.operations[id] = []types.StackSetOperationStatus{types.StackSetOperationStatusRunning, final}
+	return &id
+}
+
+func (f *fakeCloudFormation) DescribeStackSet(ctx context.Context, params *cloudformation.DescribeStackSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackSetOutput, error) {
+	f.calls = append(f.calls, "DescribeStackSet")
+	if _, ok := f.stackSets[*params.StackSetName]; !ok {
+		return nil, &types.StackSetNotFoundException{Message: aws.String("not found")}
+	}
+	return &cloudformation.DescribeStackSetOutput{StackSet: &types.StackSet{StackSetName: params.StackSetName}}, nil
+}
+
+func (f *fakeCloudFormation) CreateStackSet(ctx context.Context, params *cloudformation.CreateStackSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackSetOutput, error) {
+	f.calls = append(f.calls, "CreateStackSet")
+	f.stackSets[*params.StackSetName] = params
+	return &cloudformation.CreateStackSetOutput{StackSetId: params.StackSetName}, nil
+}
+
+func (f *fakeCloudFormation) UpdateStackSet(ctx context.Context, params *cloudformation.UpdateStackSetInput, optFns ...func(*cloudformation.Options)) (*cloudformation.UpdateStackSetOutput, error) {
+	f.calls = append(f.calls, "UpdateStackSet")
+	return &cloudformation.UpdateStackSetOutput{OperationId: f.startOperation()}, nil
+}
+
+func (f *fakeCloudFormation) CreateStackInstances(ctx context.Context, params *cloudformation.CreateStackInstancesInput, optFns ...func(*cloudformation.Options)) (*cloudformation.CreateStackInstancesOutput, error) {
+	f.calls = append(f.calls, "CreateStackInstances")
+	f.instances = append(f.instances, params)
+	return &cloudformation.CreateStackInstancesOutput{OperationId: f.startOperation()}, nil
+}
+
+func (f *fakeCloudFormation) DescribeStackSetOperation(ctx context.Context, params *cloudformation.DescribeStackSetOperationInput, optFns ...func(*cloudformation.Options)) (*cloudformation.DescribeStackSetOperationOutput, error) {
+	statuses := f.operations[*params.OperationId]
+	status := statuses[0]
+	if len(statuses) > 1 {
+		f.operations[*params.OperationId] = statuses[1:]
+	}
+	return &cloudformation.DescribeStackSetOperationOutput{
+		StackSetOperation: &types.StackSetOperation{OperationId: params.OperationId, Status: status},
+	}, nil
+}
+
+func TestDeploy(t *testing.T) {
+	opts := DeployOpts{
+		StackSetName:          "cf-handler-common-fate-test-access-read",
+		TemplateBody:          "{}",
+		Parameters:            map[string]string{"HandlerID": "cf-handler-common-fate-test", "HandlerAccountID": "123456789012"},
+		OrganizationalUnitIDs: []string{"ou-abcd-12345678"},
+		Regions:               []string{"us-east-1"},
+	}
+
+	tests := []struct {
+		name      string
+		existing  bool
+		fail      bool
+		wantCalls []string
+		wantErr   bool
+	}{
+		{
+			name:      "create",
+			wantCalls: []string{"DescribeStackSet", "CreateStackSet", "CreateStackInstances"},
+		},
+		{
+			name:      "update",
+			existing:  true,
+			wantCalls: []string{"DescribeStackSet", "UpdateStackSet", "CreateStackInstances"},
+		},
+		{
+			name:      "operation failed",
+			fail:      true,
+			wantCalls: []string{"DescribeStackSet", "CreateStackSet", "CreateStackInstances"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFake()
+			fake.failOperations = tt.fail
+			if tt.existing {
+				fake.stackSets[opts.StackSetName] = &cloudformation.CreateStackSetInput{}
+			}
+
+			d := Deployer{Client: fake}
+			err := d.Deploy(context.Background(), opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deploy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if fmt.Sprint(fake.calls) != fmt.Sprint(tt.wantCalls) {
+				t.Errorf("want calls %v got %v", tt.wantCalls, fake.calls)
+			}
+
+			if !tt.existing {
+				created := fake.stackSets[opts.StackSetName]
+				if created.PermissionModel != types.PermissionModelsServiceManaged {
+					t.Errorf("want permission model %s got %s", types.PermissionModelsServiceManaged, created.PermissionModel)
+				}
+				if created.AutoDeployment == nil || !*created.AutoDeployment.Enabled {
+					t.Error("want auto deployment to be enabled")
+				}
+				if len(created.Parameters) != 2 || *created.Parameters[0].ParameterKey != "HandlerAccountID" {
+					t.Errorf("unexpected parameters %v", created.Parameters)
+				}
+			}
+
+			if len(fake.instances) != 1 || fake.instances[0].DeploymentTargets.OrganizationalUnitIds[0] != "ou-abcd-12345678" {
+				t.Errorf("stack instances were not created for the OU")
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package tfgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/common-fate/pdk/pkg/cfngen"
+)
+
+// configVariable returns the variable for a Provider config field, matching the
+// parameter created by cfngen, and the expression used to pass it to the Lambda function.
+func configVariable(varName string, f cfngen.ConfigField) (Variable, string, error) {
+	v := Variable{
+		Type:      "string",
+		Sensitive: f.Secret,
+	}
+	if f.Description != nil {
+		v.Description = *f.Description
+	}
+	value := Var(varName)
+
+	// optional fields without a default are empty if they aren't provided.
+	emptyAllowed := f.Optional && f.Default == nil
+
+	var allowed []string
+	switch f.Type {
+	case cfngen.ConfigTypeString, "":
+		if f.Default != nil {
+			v.Default = f.DefaultString()
+		}
+	case cfngen.ConfigTypeNumber:
+		v.Type = "number"
+		v.Default = f.Default
+		if emptyAllowed {
+			// number variables can't be empty strings, so optional numbers are strings as in cfngen.
+			v.Type = "string"
+			v.Validation = append(v.Validation, Validation{
+				Condition:    fmt.Sprintf("${can(regex(%q, var.%s))}", cfngen.OptionalNumberPattern, varName),
+				ErrorMessage: fmt.Sprintf("%s must be a number.", varName),
+			})
+		}
+	case cfngen.ConfigTypeBoolean:
+		v.Type = "bool"
+		v.Default = f.Default
+		if emptyAllowed {
+			v.Type = "string"
+			allowed = []string{"true", "false"}
+		}
+	case cfngen.ConfigTypeList:
+		v.Type = "list(string)"
+		v.Default = f.Default
+		if emptyAllowed {
+			v.Default = []string{}
+		}
+		// environment variables are strings, so lists are passed as comma-separated values.
+		value = fmt.Sprintf(`${join(",", var.%s)}`, varName)
+	default:
+		return v, "", fmt.Errorf("unsupported config type %q", f.Type)
+	}
+
+	if emptyAllowed && v.Default == nil {
+		v.Default = ""
+	}
+
+	if f.IsRequired() && v.Type == "string" {
+		v.Validation = append(v.Validation, Validation{
+			Condition:    fmt.Sprintf("${length(var.%s) > 0}", varName),
+			ErrorMessage: fmt.Sprintf("%s must not be empty.", varName),
+		})
+	}
+
+	for _, e := range f.Enum {
+		allowed = append(allowed, fmt.Sprint(e))
+	}
+	if len(allowed) > 0 {
+		if emptyAllowed {
+			allowed = append([]string{""}, allowed...)
+		}
+		quoted := make([]string, len(allowed))
+		for i, a := range allowed {
+			quoted[i] = fmt.Sprintf("%q", a)
+		}
+		v.Validation = append(v.Validation, Validation{
+			Condition:    fmt.Sprintf("${contains([%s], var.%s)}", strings.Join(quoted, ", "), varName),
+			ErrorMessage: fmt.Sprintf("%s must be one of %s.", varName, strings.Join(quoted, ", ")),
+		})
+	}
+
+	if f.Pattern != "" {
+		condition := fmt.Sprintf("can(regex(%q, var.%s))", f.Pattern, varName)
+		if emptyAllowed {
+			condition = fmt.Sprintf(`var.%s == "" || %s`, varName, condition)
+		}
+		v.Validation = append(v.Validation, Validation{
+			Condition:    "${" + condition + "}",
+			ErrorMessage: fmt.Sprintf("%s must match the pattern %s.", varName, f.Pattern),
+		})
+	}
+
+	return v, value, nil
+}
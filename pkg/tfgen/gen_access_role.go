@@ -0,0 +1,112 @@
+package tfgen
+
+import (
+	"fmt"
+
+	"github.com/common-fate/pdk/pkg/accessrole"
+	"github.com/common-fate/pdk/pkg/iamp"
+	"github.com/common-fate/pdk/pkg/pythonconfig"
+)
+
+// GenerateAccessRole creates a Terraform module for an access role which is
+// equivalent to the CloudFormation template created by cfngen.GenerateAccessRole.
+func GenerateAccessRole(pconfig pythonconfig.Config, roleName string, role accessrole.Definition) ([]byte, error) {
+	err := role.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid access role %s: %w", roleName, err)
+	}
+
+	m := NewModule()
+	addPseudoParameters(m)
+
+	m.Variable["handler_account_id"] = Variable{
+		Type:        "string",
+		Description: fmt.Sprintf("The ID of the AWS account that the %s/%s Provider will be deployed to", pconfig.Publisher, pconfig.Name),
+	}
+
+	m.Variable["handler_id"] = Variable{
+		Type:        "string",
+		Default:     fmt.Sprintf("cf-handler-%s-%s", pconfig.Publisher, pconfig.Name),
+		Description: "The name of the Lambda function deployed for the provider",
+	}
+
+	m.Variable["handler_role_path"] = Variable{
+		Type:        "string",
+		Default:     "/",
+		Description: "The path of the IAM role used by the Lambda function deployed for the provider",
+	}
+
+	addRoleVariables(m)
+
+	providerTags, err := addTagVariables(m, pconfig.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if role.PermissionsBoundary != "" {
+		// use the permissions boundary from the role definition unless it's overridden.
+		v := m.Variable["permissions_boundary_arn"]
+		v.Default = role.PermissionsBoundary
+		m.Variable["permissions_boundary_arn"] = v
+	}
+
+	arpd := iamp.NewPolicy(
+		iamp.Statement{
+			Effect: iamp.Allow,
+			Action: iamp.Value{"sts:AssumeRole"},
+			Principal: iamp.Principal{
+				// only allow the handler function to assume the role
+				"AWS": {"arn:" + partition + ":iam::" + Var("handler_account_id") + ":role" + Var("handler_role_path") + Var("handler_id")},
+			},
+			Condition: role.Trust.TrustCondition(),
+		},
+	)
+
+	var inlinePolicies []map[string]any
+	for _, p := range role.Policies {
+		inlinePolicies = append(inlinePolicies, map[string]any{
+			"name": p.PolicyName,
+			// the policy is provided by the Provider author, so any
+			// IAM policy variables in it must not be interpolated.
+			"policy": escape(p.PolicyDocument.String()),
+		})
+	}
+
+	description := fmt.Sprintf("Common Fate %s/%s Access Role - %s", pconfig.Publisher, pconfig.Name, roleName)
+	if role.Description != "" {
+		description += ": " + role.Description
+	}
+
+	roleTags := map[string]string{
+		"common-fate-abac-role": "access-provider-permissions-role",
+	}
+	for k, v := range role.Tags {
+		roleTags[k] = v
+	}
+
+	r := map[string]any{
+		"name":                 Var("handler_id") + "-access-" + roleName,
+		"description":          description,
+		"path":                 Var("role_path"),
+		"permissions_boundary": optional("permissions_boundary_arn"),
+		"assume_role_policy":   arpd.String(),
+		"inline_policy":        inlinePolicies,
+		"tags":                 roleTags,
+	}
+	// tags in the role definition take precedence over the tags from provider.toml.
+	setTags(r, providerTags)
+	if len(role.ManagedPolicyArns) > 0 {
+		r["managed_policy_arns"] = role.ManagedPolicyArns
+	}
+	if role.MaxSessionDuration != nil {
+		r["max_session_duration"] = *role.MaxSessionDuration
+	}
+
+	m.AddResource("aws_iam_role", "role", r)
+
+	m.Output["role"] = Output{
+		Value: "${aws_iam_role.role.arn}",
+	}
+
+	return m.JSON()
+}
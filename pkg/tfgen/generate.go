@@ -0,0 +1,352 @@
+package tfgen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/common-fate/pdk/pkg/cfngen"
+	"github.com/common-fate/pdk/pkg/iamp"
+	"github.com/common-fate/pdk/pkg/pythonconfig"
+	"github.com/common-fate/pdk/pkg/secretstore"
+)
+
+// reservedVariables can't be used as Provider config keys.
+var reservedVariables = map[string]bool{
+	"asset_path":                    true,
+	"bootstrap_bucket_name":         true,
+	"common_fate_aws_account_id":    true,
+	"handler_id":                    true,
+	"kms_key_arn":                   true,
+	"secret_backend":                true,
+	"permissions_boundary_arn":      true,
+	"role_path":                     true,
+	"log_retention_days":            true,
+	"log_kms_key_arn":               true,
+	"alarm_topic_arn":               true,
+	"resource_load_schedule":        true,
+	"tracing_enabled":               true,
+	"otel_layer_arn":                true,
+	"resource_load_destination_arn": true,
+}
+
+// Generate creates a Terraform module for a Provider which is
+// equivalent to the CloudFormation template created by cfngen.GenerateFromSchema.
+func Generate(pconfig pythonconfig.Config, schema cfngen.Schema) ([]byte, error) {
+	m := NewModule()
+	addPseudoParameters(m)
+
+	m.Variable["asset_path"] = Variable{
+		Type:        "string",
+		Description: "The path of the asset in the bootstrap bucket",
+	}
+
+	m.Variable["bootstrap_bucket_name"] = Variable{
+		Type:        "string",
+		Description: "The name of the bucket used to bootstrap assets from Common Fate Releases into this account",
+	}
+
+	m.Variable["common_fate_aws_account_id"] = Variable{
+		Type:        "string",
+		Description: "The AWS account Id for the account where Common Fate is deployed",
+	}
+
+	m.Variable["handler_id"] = Variable{
+		Type:        "string",
+		Description: "The name of invoke handler lambda function",
+	}
+
+	m.Variable["kms_key_arn"] = Variable{
+		Type:        "string",
+		Default:     "",
+		Description: "(Optional) The ARN of a customer-managed KMS key used to encrypt the Lambda environment variables and to decrypt SecureString SSM parameters",
+	}
+
+	addRoleVariables(m)
+
+	providerTags, err := addTagVariables(m, pconfig.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	addLogGroup(m, providerTags)
+	addAlarms(m, providerTags)
+
+	secretBackend, err := secretstore.Parse(pconfig.SecretBackend)
+	if err != nil {
+		return nil, err
+	}
+
+	envVars := map[string]string{}
+
+	hasSecrets := false
+	for k, v := range schema.Config {
+		// use the same names as the CloudFormation parameters, so that the
+		// two formats can be configured in the same way.
+		cfnKey := cfngen.ConvertToPascalCase(k)
+		envPrefix := "PROVIDER_CONFIG_"
+
+		if v.Secret {
+			cfnKey += "Secret"
+			envPrefix = "PROVIDER_SECRET_"
+			hasSecrets = true
+		}
+
+		varName := VariableName(cfnKey)
+		// prevent users from submitting providers that overwrite our built-in
+		// variable names as it could cause unexpected behaviour.
+		if reservedVariables[varName] {
+			return nil, fmt.Errorf("config %s uses the reserved variable name %s", k, varName)
+		}
+		if _, ok := m.Variable[varName]; ok {
+			return nil, fmt.Errorf("config %s would overwrite the %s variable", k, varName)
+		}
+
+		variable, value, err := configVariable(varName, v)
+		if err != nil {
+			return nil, fmt.Errorf("config %s: %w", k, err)
+		}
+		m.Variable[varName] = variable
+
+		envVars[envPrefix+strings.ToUpper(k)] = value
+	}
+
+	lambdaRolePolicy := iamp.NewPolicy(
+		iamp.Statement{
+			Effect:    iamp.Allow,
+			Action:    iamp.Value{"sts:AssumeRole"},
+			Resource:  iamp.Value{"*"},
+			Condition: iamp.Condition{"StringEquals": {"iam:ResourceTag/common-fate-abac-role": {"access-provider-permissions-role"}}},
+		},
+	)
+
+	// only give secret permissions if the Provider actually needs to read secrets.
+	if hasSecrets {
+		var backends []string
+		for _, b := range secretstore.Backends {
+			backends = append(backends, fmt.Sprintf("%q", b))
+		}
+
+		m.Variable["secret_backend"] = Variable{
+			Type:        "string",
+			Default:     string(secretBackend),
+			Description: "The backend that the Provider reads secrets from",
+			Validation: []Validation{
+				{
+					Condition:    fmt.Sprintf("${contains([%s], var.secret_backend)}", strings.Join(backends, ", ")),
+					ErrorMessage: fmt.Sprintf("secret_backend must be one of %s.", strings.Join(backends, ", ")),
+				},
+			},
+		}
+
+		envVars["PROVIDER_SECRET_BACKEND"] = Var("secret_backend")
+
+		// scope down the secret policies, so that the provider is only allowed to read from
+		// paths which include the publisher and name of the provider.
+		ssmPath := secretstore.SSM.Prefix(pconfig.Publisher, pconfig.Name) + "*"
+		secretsManagerPath := secretstore.SecretsManager.Prefix(pconfig.Publisher, pconfig.Name) + "*"
+
+		m.AddResource("aws_iam_role_policy", "ssm", map[string]any{
+			"count": `${var.secret_backend == "ssm" ? 1 : 0}`,
+			"name":  "handler-ssm-policy",
+			"role":  "${aws_iam_role.lambda_role.id}",
+			"policy": iamp.NewPolicy(iamp.Statement{
+				Effect:   iamp.Allow,
+				Action:   iamp.Value{"ssm:GetParameter"},
+				Resource: iamp.Value{"arn:" + partition + ":ssm:" + region + ":" + accountID + ":parameter" + ssmPath},
+			}).String(),
+		})
+
+		m.AddResource("aws_iam_role_policy", "secretsmanager", map[string]any{
+			"count": `${var.secret_backend == "secretsmanager" ? 1 : 0}`,
+			"name":  "handler-secretsmanager-policy",
+			"role":  "${aws_iam_role.lambda_role.id}",
+			"policy": iamp.NewPolicy(iamp.Statement{
+				Effect:   iamp.Allow,
+				Action:   iamp.Value{"secretsmanager:GetSecretValue"},
+				Resource: iamp.Value{"arn:" + partition + ":secretsmanager:" + region + ":" + accountID + ":secret:" + secretsManagerPath},
+			}).String(),
+		})
+	}
+
+	// allow the customer-managed KMS key to be used to decrypt the environment
+	// variables and any SecureString SSM parameters, if it has been provided.
+	m.AddResource("aws_iam_role_policy", "kms", map[string]any{
+		"count": `${var.kms_key_arn == "" ? 0 : 1}`,
+		"name":  "handler-kms-policy",
+		"role":  "${aws_iam_role.lambda_role.id}",
+		"policy": iamp.NewPolicy(iamp.Statement{
+			Effect:   iamp.Allow,
+			Action:   iamp.Value{"kms:Decrypt"},
+			Resource: iamp.Value{Var("kms_key_arn")},
+		}).String(),
+	})
+
+	lambdaRoleARPD := iamp.NewPolicy(iamp.Statement{
+		Effect:    iamp.Allow,
+		Action:    iamp.Value{"sts:AssumeRole"},
+		Principal: iamp.Principal{"Service": {"lambda.amazonaws.com"}},
+	})
+
+	lambdaRole := map[string]any{
+		"name":                 Var("handler_id"),
+		"path":                 Var("role_path"),
+		"permissions_boundary": optional("permissions_boundary_arn"),
+		"assume_role_policy":   lambdaRoleARPD.String(),
+		"managed_policy_arns": []string{
+			"arn:" + partition + ":iam::aws:policy/service-role/AWSLambdaBasicExecutionRole",
+		},
+	}
+	setTags(lambdaRole, providerTags)
+	m.AddResource("aws_iam_role", "lambda_role", lambdaRole)
+
+	// the policy is a separate resource rather than an inline_policy of the role, as the AWS provider
+	// removes any inline policies which aren't in inline_policy, such as the SSM and KMS policies above.
+	m.AddResource("aws_iam_role_policy", "handler", map[string]any{
+		"name":   "handler-policy",
+		"role":   "${aws_iam_role.lambda_role.id}",
+		"policy": lambdaRolePolicy.String(),
+	})
+
+	lambdaFunction := map[string]any{
+		"function_name": Var("handler_id"),
+		"runtime":       "python3.9",
+		"timeout":       cfngen.LambdaTimeout,
+		"role":          "${aws_iam_role.lambda_role.arn}",
+		"handler":       "provider.runtime.aws_lambda_entrypoint.lambda_handler",
+		"kms_key_arn":   optional("kms_key_arn"),
+		"s3_bucket":     Var("bootstrap_bucket_name"),
+		"s3_key":        Var("asset_path"),
+		"tags": map[string]string{
+			"common-fate-abac-role": "access-provider",
+		},
+	}
+
+	addTracing(m, lambdaFunction, envVars)
+
+	if len(envVars) > 0 {
+		lambdaFunction["environment"] = map[string]any{
+			"variables": envVars,
+		}
+	}
+
+	setTags(lambdaFunction, providerTags)
+	m.AddResource("aws_lambda_function", "lambda_function", lambdaFunction)
+
+	lambdaArn := "${aws_lambda_function.lambda_function.arn}"
+
+	invokeRoleARPD := iamp.NewPolicy(
+		iamp.Statement{
+			Effect: iamp.Allow,
+			Action: iamp.Value{"sts:AssumeRole"},
+			Principal: iamp.Principal{
+				"AWS": {"arn:" + partition + ":iam::" + Var("common_fate_aws_account_id") + ":root"},
+			},
+		},
+	)
+
+	invokePolicy := iamp.NewPolicy(
+		iamp.Statement{
+			Effect:   iamp.Allow,
+			Sid:      "AllowInvokingFunction",
+			Action:   iamp.Value{"lambda:InvokeFunction"},
+			Resource: iamp.Value{lambdaArn},
+		},
+		iamp.Statement{
+			Effect: iamp.Allow,
+			Sid:    "AllowIntrospectingFunction",
+			Action: iamp.Value{
+				"lambda:GetFunction",
+				"lambda:GetFunctionConfiguration",
+			},
+			Resource: iamp.Value{lambdaArn},
+		},
+		iamp.Statement{
+			Effect: iamp.Allow,
+			Sid:    "AllowReadingFunctionLogs",
+			Action: iamp.Value{
+				"logs:DescribeLogStreams",
+				"logs:GetLogEvents",
+			},
+			Resource: iamp.Value{
+				"arn:" + partition + ":logs:" + region + ":" + accountID + ":log-group:/aws/lambda/" + Var("handler_id") + "*",
+			},
+		},
+	)
+
+	// add the invocation role - this is the role that Common Fate assumes in order to invoke the Lambda function
+	invocationRole := map[string]any{
+		"name":                 Var("handler_id") + "-invoke",
+		"description":          "Allows Common Fate to invoke the Lambda Function for the " + Var("handler_id") + " Handler",
+		"path":                 Var("role_path"),
+		"permissions_boundary": optional("permissions_boundary_arn"),
+		"assume_role_policy":   invokeRoleARPD.String(),
+		"inline_policy": []map[string]any{
+			{
+				"name":   "invoke-policy",
+				"policy": invokePolicy.String(),
+			},
+		},
+		"tags": map[string]string{
+			"common-fate-abac-role": "handler-invoke",
+		},
+	}
+	setTags(invocationRole, providerTags)
+	m.AddResource("aws_iam_role", "lambda_invocation_role", invocationRole)
+
+	err = addResourceLoading(m, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	// create the log group first, otherwise Lambda creates it without a retention period.
+	dependsOn := []string{"aws_cloudwatch_log_group.log_group"}
+	// the role policies are attached separately from the role, so wait for them to be attached
+	// before the function is created, otherwise the first invocations may be denied.
+	var policies []string
+	for name := range m.Resource["aws_iam_role_policy"] {
+		policies = append(policies, "aws_iam_role_policy."+name)
+	}
+	sort.Strings(policies)
+	lambdaFunction["depends_on"] = append(dependsOn, policies...)
+
+	m.Output["lambda_function_arn"] = Output{
+		Value:       lambdaArn,
+		Description: "The ARN of the Lambda function",
+	}
+	m.Output["lambda_role_arn"] = Output{
+		Value:       "${aws_iam_role.lambda_role.arn}",
+		Description: "The ARN of the IAM role used by the Lambda function",
+	}
+	m.Output["lambda_invocation_role_arn"] = Output{
+		Value:       "${aws_iam_role.lambda_invocation_role.arn}",
+		Description: "The ARN of the IAM role which Common Fate assumes to invoke the Lambda function",
+	}
+	m.Output["log_group_name"] = Output{
+		Value:       "${aws_cloudwatch_log_group.log_group.name}",
+		Description: "The name of the log group of the Lambda function",
+	}
+
+	return m.JSON()
+}
+
+// addRoleVariables adds the optional permissions boundary and role path variables.
+func addRoleVariables(m *Module) {
+	m.Variable["permissions_boundary_arn"] = Variable{
+		Type:        "string",
+		Default:     "",
+		Description: "(Optional) The ARN of an IAM managed policy to use as a permissions boundary for the IAM roles",
+	}
+
+	m.Variable["role_path"] = Variable{
+		Type:        "string",
+		Default:     "/",
+		Description: "(Optional) The path to create the IAM roles under, e.g. /common-fate/",
+		Validation: []Validation{
+			{
+				Condition:    `${can(regex("^/([\\x21-\\x7E]*/)?$", var.role_path))}`,
+				ErrorMessage: "role_path must begin and end with a '/'.",
+			},
+		},
+	}
+}
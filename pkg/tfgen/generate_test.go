@@ -0,0 +1,345 @@
+package tfgen
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/awslabs/goformation/v7/cloudformation"
+	"github.com/common-fate/pdk/pkg/accessrole"
+	"github.com/common-fate/pdk/pkg/cfngen"
+	"github.com/common-fate/pdk/pkg/iamp"
+	"github.com/common-fate/pdk/pkg/pythonconfig"
+	"github.com/common-fate/provider-registry-sdk-go/pkg/providerregistrysdk"
+)
+
+// cfnResourceTypes maps CloudFormation resource types to their Terraform equivalents.
+var cfnResourceTypes = map[string]string{
+	"AWS::CloudWatch::Alarm":         "aws_cloudwatch_metric_alarm",
+	"AWS::Events::Rule":              "aws_cloudwatch_event_rule",
+	"AWS::Logs::LogGroup":            "aws_cloudwatch_log_group",
+	"AWS::IAM::Policy":               "aws_iam_role_policy",
+	"AWS::IAM::Role":                 "aws_iam_role",
+	"AWS::Lambda::EventInvokeConfig": "aws_lambda_function_event_invoke_config",
+	"AWS::Lambda::Function":          "aws_lambda_function",
+	"AWS::Lambda::Permission":        "aws_lambda_permission",
+}
+
+// cfnOnlyTypes are CloudFormation resources which have no equivalent in Terraform.
+var cfnOnlyTypes = map[string]bool{
+	// Terraform's depends_on can refer to conditional resources, so no handle is needed.
+	"AWS::CloudFormation::WaitConditionHandle": true,
+}
+
+// tfCompanionTypes are Terraform resources which are part of another resource in CloudFormation.
+var tfCompanionTypes = map[string]string{
+	"aws_cloudwatch_event_rule": "aws_cloudwatch_event_target",
+}
+
+type cfnTemplate struct {
+	Parameters map[string]any `json:"Parameters"`
+	Resources  map[string]struct {
+		Type       string         `json:"Type"`
+		Properties map[string]any `json:"Properties"`
+	} `json:"Resources"`
+	Outputs map[string]any `json:"Outputs"`
+}
+
+func TestVariableName(t *testing.T) {
+	tests := map[string]string{
+		"HandlerID":              "handler_id",
+		"CommonFateAWSAccountID": "common_fate_aws_account_id",
+		"ApiKeySecret":           "api_key_secret",
+		"KmsKeyArn":              "kms_key_arn",
+	}
+	for give, want := range tests {
+		if got := VariableName(give); got != want {
+			t.Errorf("VariableName(%s): want %s got %s", give, want, got)
+		}
+	}
+}
+
+func TestGenerateReservedVariables(t *testing.T) {
+	pconfig := pythonconfig.Config{Name: "test", Publisher: "example-org", Tags: map[string]string{"owner": ""}}
+	for _, k := range []string{"kms_key_arn", "log_retention_days", "handler_id", "tag_owner"} {
+		_, err := Generate(pconfig, cfngen.Schema{Config: map[string]cfngen.ConfigField{k: {Type: "string"}}})
+		if err == nil {
+			t.Errorf("expected an error for config %s which collides with a module variable", k)
+		}
+	}
+}
+
+func TestGenerateMatchesCloudFormation(t *testing.T) {
+	pconfig := pythonconfig.Config{Name: "test", Publisher: "example-org", Tags: map[string]string{"cost-centre": "1234", "owner": ""}}
+	schema := cfngen.Schema{
+		Schema: providerregistrysdk.Schema{
+			Resources: &providerregistrysdk.Resources{
+				Loaders: map[string]providerregistrysdk.Loader{
+					"fetch_groups": {Title: "Groups"},
+					"fetch_users":  {Title: "Users"},
+				},
+			},
+		},
+		Config: map[string]cfngen.ConfigField{
+			"api_url": {
+				Type:        "string",
+				Description: cloudformation.String("some usage"),
+			},
+			"api_key": {
+				Type:        "string",
+				Description: cloudformation.String("API key"),
+				Secret:      true,
+			},
+			"page_size": {
+				Type:     "number",
+				Optional: true,
+			},
+			"group_ids": {
+				Type:    "list",
+				Default: []any{"a", "b"},
+			},
+		},
+	}
+
+	cfnJSON, err := cfngen.GenerateFromSchema(pconfig, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tfJSON, err := Generate(pconfig, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfn, tf := parse(t, cfnJSON, tfJSON)
+	assertEquivalent(t, cfn, tf)
+
+	var cfnEnv, tfEnv []string
+	env := cfn.Resources["LambdaFunction"].Properties["Environment"].(map[string]any)["Variables"].(map[string]any)
+	for k := range env {
+		cfnEnv = append(cfnEnv, k)
+	}
+	tfFunc := tf.Resource["aws_lambda_function"]["lambda_function"].(map[string]any)
+	for k := range tfFunc["environment"].(map[string]any)["variables"].(map[string]any) {
+		tfEnv = append(tfEnv, k)
+	}
+	assertSameElements(t, "environment variables", cfnEnv, tfEnv)
+
+	var cfnOutputs, tfOutputs []string
+	for k := range cfn.Outputs {
+		cfnOutputs = append(cfnOutputs, VariableName(k))
+	}
+	for k := range tf.Output {
+		tfOutputs = append(tfOutputs, k)
+	}
+	assertSameElements(t, "outputs", cfnOutputs, tfOutputs)
+}
+
+func TestGenerateAccessRoleMatchesCloudFormation(t *testing.T) {
+	pconfig := pythonconfig.Config{Name: "test-provider", Publisher: "common-fate", Tags: map[string]string{"owner": "platform"}}
+	role := accessrole.Definition{
+		Policies: []accessrole.Policy{
+			{
+				PolicyName: "s3-read",
+				PolicyDocument: iamp.NewPolicy(iamp.Statement{
+					Effect:   iamp.Allow,
+					Action:   iamp.Value{"s3:ListBucket"},
+					Resource: iamp.Value{"arn:aws:s3:::${aws:username}"},
+				}),
+			},
+			{
+				PolicyName: "logs-read",
+				PolicyDocument: iamp.NewPolicy(iamp.Statement{
+					Effect:   iamp.Allow,
+					Action:   iamp.Value{"logs:GetLogEvents"},
+					Resource: iamp.Value{"*"},
+				}),
+			},
+		},
+		ManagedPolicyArns:   []string{"arn:aws:iam::aws:policy/ReadOnlyAccess"},
+		PermissionsBoundary: "arn:aws:iam::123456789012:policy/boundary",
+		Trust:               accessrole.Trust{ExternalId: "example"},
+	}
+
+	cfnJSON, err := cfngen.GenerateAccessRole(pconfig, "s3-read", role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tfJSON, err := GenerateAccessRole(pconfig, "s3-read", role)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfn, tf := parse(t, cfnJSON, tfJSON)
+	assertEquivalent(t, cfn, tf)
+
+	var cfnOutputs, tfOutputs []string
+	for k := range cfn.Outputs {
+		cfnOutputs = append(cfnOutputs, VariableName(k))
+	}
+	for k := range tf.Output {
+		tfOutputs = append(tfOutputs, k)
+	}
+	assertSameElements(t, "outputs", cfnOutputs, tfOutputs)
+
+	if got := tf.Variable["permissions_boundary_arn"].Default; got != role.PermissionsBoundary {
+		t.Errorf("want permissions boundary default %s got %v", role.PermissionsBoundary, got)
+	}
+
+	// IAM policy variables must be escaped so that Terraform doesn't interpolate them.
+	if !strings.Contains(string(tfJSON), "$${aws:username}") {
+		t.Errorf("policy variables were not escaped")
+	}
+}
+
+func parse(t *testing.T, cfnJSON, tfJSON []byte) (cfnTemplate, Module) {
+	t.Helper()
+	var cfn cfnTemplate
+	err := json.Unmarshal(cfnJSON, &cfn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tf Module
+	err = json.Unmarshal(tfJSON, &tf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfn, tf
+}
+
+// assertEquivalent checks that the parameters, resources and IAM actions in the
+// CloudFormation template match the variables, resources and IAM actions in the Terraform module.
+func assertEquivalent(t *testing.T, cfn cfnTemplate, tf Module) {
+	t.Helper()
+
+	var params, vars []string
+	for k := range cfn.Parameters {
+		params = append(params, VariableName(k))
+	}
+	for k := range tf.Variable {
+		vars = append(vars, k)
+	}
+	assertSameElements(t, "variables", params, vars)
+
+	// inline role policies are compared as aws_iam_role_policy resources, as
+	// Terraform roles may use either.
+	var cfnResources, tfResources []string
+	cfnActions := map[string]bool{}
+	for name, r := range cfn.Resources {
+		if cfnOnlyTypes[r.Type] {
+			continue
+		}
+		tfType, ok := cfnResourceTypes[r.Type]
+		if !ok {
+			t.Errorf("resource %s has no Terraform equivalent for type %s", name, r.Type)
+		}
+		cfnResources = append(cfnResources, tfType)
+		if companion, ok := tfCompanionTypes[tfType]; ok {
+			cfnResources = append(cfnResources, companion)
+		}
+		if policies, ok := r.Properties["Policies"].([]any); ok && r.Type == "AWS::IAM::Role" {
+			for range policies {
+				cfnResources = append(cfnResources, "aws_iam_role_policy")
+			}
+		}
+		collectActions(r.Properties, cfnActions)
+	}
+
+	tfActions := map[string]bool{}
+	for resourceType, resources := range tf.Resource {
+		for _, r := range resources {
+			tfResources = append(tfResources, resourceType)
+			if inline, ok := r.(map[string]any)["inline_policy"].([]any); ok && resourceType == "aws_iam_role" {
+				for range inline {
+					tfResources = append(tfResources, "aws_iam_role_policy")
+				}
+			}
+			collectActions(r, tfActions)
+		}
+	}
+	// the resource load destination action is looked up in a local map of actions.
+	if actions, ok := tf.Locals["resource_load_destination_actions"].(map[string]any); ok {
+		for _, action := range actions {
+			tfActions[action.(string)] = true
+		}
+	}
+	assertSameElements(t, "resources", cfnResources, tfResources)
+	assertNoMixedRolePolicies(t, tf)
+
+	if !reflect.DeepEqual(cfnActions, tfActions) {
+		t.Errorf("IAM actions differ: CloudFormation %v, Terraform %v", cfnActions, tfActions)
+	}
+}
+
+// assertNoMixedRolePolicies checks that roles with an inline_policy don't also have aws_iam_role_policy
+// resources. The AWS provider removes inline policies which aren't in inline_policy, so the two would fight.
+func assertNoMixedRolePolicies(t *testing.T, tf Module) {
+	t.Helper()
+	for name, r := range tf.Resource["aws_iam_role_policy"] {
+		role, _ := r.(map[string]any)["role"].(string)
+		for roleName, roleResource := range tf.Resource["aws_iam_role"] {
+			if role != "${aws_iam_role."+roleName+".id}" && role != "${aws_iam_role."+roleName+".name}" {
+				continue
+			}
+			if _, ok := roleResource.(map[string]any)["inline_policy"]; ok {
+				t.Errorf("aws_iam_role.%s has an inline_policy and is also attached to aws_iam_role_policy.%s", roleName, name)
+			}
+		}
+	}
+}
+
+// collectActions finds all IAM actions in a resource, including
+// in policy documents which are encoded as JSON strings.
+func collectActions(v any, actions map[string]bool) {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if k == "Action" {
+				collectAction(child, actions)
+				continue
+			}
+			collectActions(child, actions)
+		}
+	case []any:
+		for _, child := range val {
+			collectActions(child, actions)
+		}
+	case string:
+		if strings.HasPrefix(val, "{") {
+			var doc any
+			if err := json.Unmarshal([]byte(val), &doc); err == nil {
+				collectActions(doc, actions)
+			}
+		}
+	}
+}
+
+// collectAction adds an Action value, which may be a list or include both branches of an Fn::If.
+// Terraform local values are skipped, as they are collected separately.
+func collectAction(v any, actions map[string]bool) {
+	switch a := v.(type) {
+	case string:
+		if !strings.HasPrefix(a, "${local.") {
+			actions[a] = true
+		}
+	case []any:
+		for _, item := range a {
+			collectAction(item, actions)
+		}
+	case map[string]any:
+		if args, ok := a["Fn::If"].([]any); ok && len(args) == 3 {
+			collectAction(args[1], actions)
+			collectAction(args[2], actions)
+		}
+	}
+}
+
+func assertSameElements(t *testing.T, name string, want, got []string) {
+	t.Helper()
+	sort.Strings(want)
+	sort.Strings(got)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("%s differ: CloudFormation %v, Terraform %v", name, want, got)
+	}
+}
@@ -0,0 +1,132 @@
+// Package tfgen generates Terraform modules which are equivalent
+// to the CloudFormation templates generated by the cfngen package.
+//
+// Modules are written using the Terraform JSON configuration syntax,
+// see: https://developer.hashicorp.com/terraform/language/syntax/json
+package tfgen
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// Module is a Terraform module in JSON configuration syntax.
+type Module struct {
+	Terraform map[string]any                       `json:"terraform,omitempty"`
+	Variable  map[string]Variable                  `json:"variable,omitempty"`
+	Locals    map[string]any                       `json:"locals,omitempty"`
+	Data      map[string]map[string]map[string]any `json:"data,omitempty"`
+	Resource  map[string]map[string]any            `json:"resource,omitempty"`
+	Output    map[string]Output                    `json:"output,omitempty"`
+}
+
+type Variable struct {
+	Type        string       `json:"type"`
+	Description string       `json:"description,omitempty"`
+	Default     any          `json:"default,omitempty"`
+	Sensitive   bool         `json:"sensitive,omitempty"`
+	Validation  []Validation `json:"validation,omitempty"`
+}
+
+type Validation struct {
+	Condition    string `json:"condition"`
+	ErrorMessage string `json:"error_message"`
+}
+
+type Output struct {
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+}
+
+// NewModule creates a new module which uses the AWS provider.
+func NewModule() *Module {
+	return &Module{
+		Terraform: map[string]any{
+			"required_providers": map[string]any{
+				"aws": map[string]any{
+					"source":  "hashicorp/aws",
+					"version": ">= 4.0",
+				},
+			},
+		},
+		Variable: map[string]Variable{},
+		Locals:   map[string]any{},
+		Data:     map[string]map[string]map[string]any{},
+		Resource: map[string]map[string]any{},
+		Output:   map[string]Output{},
+	}
+}
+
+// AddResource adds a resource block to the module, e.g. 'aws_iam_role' 'lambda_role'.
+func (m *Module) AddResource(resourceType string, name string, body any) {
+	if _, ok := m.Resource[resourceType]; !ok {
+		m.Resource[resourceType] = map[string]any{}
+	}
+	m.Resource[resourceType][name] = body
+}
+
+// AddData adds a data source block to the module.
+func (m *Module) AddData(dataType string, name string, body map[string]any) {
+	if _, ok := m.Data[dataType]; !ok {
+		m.Data[dataType] = map[string]map[string]any{}
+	}
+	m.Data[dataType][name] = body
+}
+
+// JSON returns the module in Terraform JSON configuration syntax.
+func (m *Module) JSON() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// VariableName converts a CloudFormation parameter name to
+// a Terraform variable name, e.g. 'CommonFateAWSAccountID' -> 'common_fate_aws_account_id'.
+func VariableName(parameter string) string {
+	runes := []rune(parameter)
+	var b strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// start a new word on a lower-to-upper transition, or at the
+			// end of an acronym (e.g. the 'I' in 'AWSId').
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Var returns an expression referencing a variable.
+func Var(name string) string {
+	return "${var." + name + "}"
+}
+
+// optional returns an expression which evaluates to null if
+// the variable is an empty string, so that the argument is omitted.
+func optional(name string) string {
+	return `${var.` + name + ` == "" ? null : var.` + name + `}`
+}
+
+// escape escapes Terraform template sequences in a literal string, such as
+// IAM policy variables like '${aws:username}', so that they aren't interpolated.
+func escape(s string) string {
+	s = strings.ReplaceAll(s, "${", "$${")
+	return strings.ReplaceAll(s, "%{", "%%{")
+}
+
+// Common data sources used in place of CloudFormation pseudo parameters.
+const (
+	partition = "${data.aws_partition.current.partition}"
+	region    = "${data.aws_region.current.name}"
+	accountID = "${data.aws_caller_identity.current.account_id}"
+)
+
+func addPseudoParameters(m *Module) {
+	m.AddData("aws_partition", "current", map[string]any{})
+	m.AddData("aws_region", "current", map[string]any{})
+	m.AddData("aws_caller_identity", "current", map[string]any{})
+}
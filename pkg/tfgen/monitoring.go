@@ -0,0 +1,72 @@
+package tfgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/common-fate/pdk/pkg/cfngen"
+)
+
+// addLogGroup adds the log group for the Lambda function, matching cfngen.
+func addLogGroup(m *Module, providerTags map[string]string) {
+	var retention []string
+	for _, d := range cfngen.LogRetentionDays {
+		retention = append(retention, fmt.Sprint(d))
+	}
+
+	m.Variable["log_retention_days"] = Variable{
+		Type:        "number",
+		Default:     cfngen.DefaultLogRetentionDays,
+		Description: "The number of days to keep the Lambda function logs for",
+		Validation: []Validation{
+			{
+				Condition:    fmt.Sprintf("${contains([%s], var.log_retention_days)}", strings.Join(retention, ", ")),
+				ErrorMessage: "log_retention_days must be a retention period supported by CloudWatch Logs.",
+			},
+		},
+	}
+
+	m.Variable["log_kms_key_arn"] = Variable{
+		Type:        "string",
+		Default:     "",
+		Description: "(Optional) The ARN of a customer-managed KMS key used to encrypt the Lambda function logs. The key policy must allow CloudWatch Logs to use the key",
+	}
+
+	logGroup := map[string]any{
+		"name":              "/aws/lambda/" + Var("handler_id"),
+		"retention_in_days": "${var.log_retention_days}",
+		"kms_key_id":        optional("log_kms_key_arn"),
+	}
+	setTags(logGroup, providerTags)
+	m.AddResource("aws_cloudwatch_log_group", "log_group", logGroup)
+}
+
+// addAlarms adds CloudWatch alarms on the Lambda function which notify an SNS topic, matching cfngen.
+func addAlarms(m *Module, providerTags map[string]string) {
+	m.Variable["alarm_topic_arn"] = Variable{
+		Type:        "string",
+		Default:     "",
+		Description: "(Optional) The ARN of an SNS topic to notify when the Lambda function errors, is throttled or is close to timing out. Alarms are only created if this is provided",
+	}
+
+	for _, a := range cfngen.Alarms {
+		alarm := map[string]any{
+			"count":               `${var.alarm_topic_arn == "" ? 0 : 1}`,
+			"alarm_name":          Var("handler_id") + "-" + a.MetricName,
+			"alarm_description":   a.Description,
+			"namespace":           "AWS/Lambda",
+			"metric_name":         a.MetricName,
+			"statistic":           a.Statistic,
+			"dimensions":          map[string]string{"FunctionName": "${aws_lambda_function.lambda_function.function_name}"},
+			"period":              300,
+			"evaluation_periods":  1,
+			"threshold":           a.Threshold,
+			"comparison_operator": "GreaterThanOrEqualToThreshold",
+			"treat_missing_data":  "notBreaching",
+			"alarm_actions":       []string{Var("alarm_topic_arn")},
+			"ok_actions":          []string{Var("alarm_topic_arn")},
+		}
+		setTags(alarm, providerTags)
+		m.AddResource("aws_cloudwatch_metric_alarm", VariableName(a.MetricName), alarm)
+	}
+}
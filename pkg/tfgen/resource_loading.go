@@ -0,0 +1,109 @@
+package tfgen
+
+import (
+	"fmt"
+
+	"github.com/common-fate/pdk/pkg/cfngen"
+	"github.com/common-fate/pdk/pkg/iamp"
+)
+
+// addResourceLoading adds an optional EventBridge schedule for each top-level
+// resource loader, matching cfngen.
+func addResourceLoading(m *Module, schema cfngen.Schema) error {
+	loaders, err := cfngen.ResourceLoaders(schema)
+	if err != nil {
+		return err
+	}
+	if len(loaders) == 0 {
+		return nil
+	}
+
+	m.Variable["resource_load_schedule"] = Variable{
+		Type:        "string",
+		Default:     "",
+		Description: "(Optional) An EventBridge schedule expression, such as rate(1 hour), to run the Provider's resource loaders on. Resources are only loaded on a schedule if this is provided",
+		Validation: []Validation{
+			{
+				Condition:    fmt.Sprintf("${can(regex(%q, var.resource_load_schedule))}", cfngen.ScheduleExpressionPattern),
+				ErrorMessage: "resource_load_schedule must be an EventBridge schedule expression, such as rate(1 hour).",
+			},
+		},
+	}
+
+	m.Variable["resource_load_destination_arn"] = Variable{
+		Type:        "string",
+		Default:     "",
+		Description: "(Optional) The ARN of an SQS queue, SNS topic, EventBridge event bus or Lambda function to send the results of scheduled resource loads to. S3 buckets aren't supported",
+		Validation: []Validation{
+			{
+				Condition:    fmt.Sprintf("${can(regex(%q, var.resource_load_destination_arn))}", cfngen.ResultDestinationPattern),
+				ErrorMessage: "resource_load_destination_arn must be the ARN of an SQS queue, SNS topic, EventBridge event bus or Lambda function.",
+			},
+		},
+	}
+
+	hasSchedule := `${var.resource_load_schedule == "" ? 0 : 1}`
+	hasDestination := `${var.resource_load_schedule != "" && var.resource_load_destination_arn != "" ? 1 : 0}`
+
+	// only the action for the service in the destination ARN is granted. The action is looked up
+	// in a local value, as the expression can't contain quotes inside the JSON policy document.
+	m.Locals["resource_load_destination_actions"] = cfngen.ResultDestinationActions
+	m.Locals["resource_load_destination_action"] = `${try(local.resource_load_destination_actions[split(":", var.resource_load_destination_arn)[2]], "")}`
+
+	for _, l := range loaders {
+		input, err := cfngen.LoadResourcesInput(l.Task)
+		if err != nil {
+			return err
+		}
+
+		name := "resource_load_" + VariableName(l.Name)
+		m.AddResource("aws_cloudwatch_event_rule", name, map[string]any{
+			"count":               hasSchedule,
+			"description":         "Loads " + escape(l.Title) + " resources for the " + Var("handler_id") + " Handler",
+			"schedule_expression": Var("resource_load_schedule"),
+		})
+
+		// CloudFormation defines the target in the rule, but Terraform has a separate resource.
+		m.AddResource("aws_cloudwatch_event_target", name, map[string]any{
+			"count":     hasSchedule,
+			"rule":      fmt.Sprintf("${aws_cloudwatch_event_rule.%s[0].name}", name),
+			"target_id": "handler",
+			"arn":       "${aws_lambda_function.lambda_function.arn}",
+			"input":     escape(input),
+		})
+
+		m.AddResource("aws_lambda_permission", name, map[string]any{
+			"count":         hasSchedule,
+			"action":        "lambda:InvokeFunction",
+			"function_name": "${aws_lambda_function.lambda_function.function_name}",
+			"principal":     "events.amazonaws.com",
+			"source_arn":    fmt.Sprintf("${aws_cloudwatch_event_rule.%s[0].arn}", name),
+		})
+	}
+
+	m.AddResource("aws_iam_role_policy", "resource_load_destination", map[string]any{
+		"count": hasDestination,
+		"name":  "handler-resource-load-destination-policy",
+		"role":  "${aws_iam_role.lambda_role.id}",
+		"policy": iamp.NewPolicy(iamp.Statement{
+			Effect:   iamp.Allow,
+			Action:   iamp.Value{"${local.resource_load_destination_action}"},
+			Resource: iamp.Value{Var("resource_load_destination_arn")},
+		}).String(),
+	})
+
+	// the destination applies to all asynchronous invocations of the function.
+	m.AddResource("aws_lambda_function_event_invoke_config", "resource_load_destination", map[string]any{
+		"count":         hasDestination,
+		"function_name": "${aws_lambda_function.lambda_function.function_name}",
+		"qualifier":     "$LATEST",
+		"destination_config": map[string]any{
+			"on_success": map[string]any{"destination": Var("resource_load_destination_arn")},
+			"on_failure": map[string]any{"destination": Var("resource_load_destination_arn")},
+		},
+		// Lambda checks that it can send to the destination when the config is created.
+		"depends_on": []string{"aws_iam_role_policy.resource_load_destination"},
+	})
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package tfgen
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/common-fate/pdk/pkg/cfngen"
+)
+
+// addTagVariables adds a variable for each tag in provider.toml, matching the
+// parameters created by cfngen. It returns the tags to apply to each resource.
+func addTagVariables(m *Module, tags map[string]string) (map[string]string, error) {
+	err := cfngen.ValidateTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	resourceTags := map[string]string{}
+	for _, k := range keys {
+		name := VariableName(cfngen.TagParameter(k))
+		v := Variable{
+			Type:        "string",
+			Description: fmt.Sprintf("The value of the '%s' tag applied to all resources", k),
+		}
+		if tags[k] == "" {
+			v.Validation = []Validation{
+				{
+					Condition:    fmt.Sprintf("${length(var.%s) > 0}", name),
+					ErrorMessage: fmt.Sprintf("%s must not be empty.", name),
+				},
+			}
+		} else {
+			v.Default = tags[k]
+		}
+		m.Variable[name] = v
+		resourceTags[k] = Var(name)
+	}
+	return resourceTags, nil
+}
+
+// setTags adds the tags from provider.toml to a resource. Tags which the resource
+// already sets take precedence.
+func setTags(resource map[string]any, providerTags map[string]string) {
+	if len(providerTags) == 0 {
+		return
+	}
+	tags := map[string]string{}
+	for k, v := range providerTags {
+		tags[k] = v
+	}
+	if existing, ok := resource["tags"].(map[string]string); ok {
+		for k, v := range existing {
+			tags[k] = v
+		}
+	}
+	resource["tags"] = tags
+}
@@ -0,0 +1,41 @@
+package tfgen
+
+import (
+	"github.com/common-fate/pdk/pkg/cfngen"
+	"github.com/common-fate/pdk/pkg/iamp"
+)
+
+// addTracing adds the variables which enable X-Ray active tracing and attach an
+// OpenTelemetry layer to the Lambda function, matching cfngen.
+func addTracing(m *Module, lambdaFunction map[string]any, envVars map[string]string) {
+	m.Variable["tracing_enabled"] = Variable{
+		Type:        "bool",
+		Default:     false,
+		Description: "Whether to enable X-Ray active tracing of the Lambda function",
+	}
+
+	m.Variable["otel_layer_arn"] = Variable{
+		Type:        "string",
+		Default:     "",
+		Description: "(Optional) The ARN of an AWS Distro for OpenTelemetry Lambda layer for Python, which instruments the Provider to trace calls to downstream APIs",
+	}
+
+	lambdaFunction["tracing_config"] = map[string]any{
+		"mode": `${var.tracing_enabled ? "Active" : "PassThrough"}`,
+	}
+	lambdaFunction["layers"] = `${var.otel_layer_arn == "" ? [] : [var.otel_layer_arn]}`
+	// environment variables can't be null, and Lambda ignores an empty wrapper.
+	envVars["AWS_LAMBDA_EXEC_WRAPPER"] = `${var.otel_layer_arn == "" ? "" : "` + cfngen.OtelExecWrapper + `"}`
+
+	// these are the permissions which Lambda needs to send traces when active tracing is enabled.
+	m.AddResource("aws_iam_role_policy", "tracing", map[string]any{
+		"count": "${var.tracing_enabled ? 1 : 0}",
+		"name":  "handler-tracing-policy",
+		"role":  "${aws_iam_role.lambda_role.id}",
+		"policy": iamp.NewPolicy(iamp.Statement{
+			Effect:   iamp.Allow,
+			Action:   cfngen.TracingActions,
+			Resource: iamp.Value{"*"},
+		}).String(),
+	})
+}
@@ -0,0 +1,329 @@
+package tracefile
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Span is an OpenTelemetry span, in the format written to the trace file.
+type Span struct {
+	TraceID      string         `json:"traceId"`
+	SpanID       string         `json:"spanId"`
+	ParentSpanID string         `json:"parentSpanId,omitempty"`
+	Name         string         `json:"name"`
+	Kind         string         `json:"kind"`
+	StartTime    time.Time      `json:"startTime"`
+	EndTime      time.Time      `json:"endTime"`
+	DurationMs   float64        `json:"durationMs"`
+	Attributes   map[string]any `json:"attributes,omitempty"`
+	Events       []Event        `json:"events,omitempty"`
+	Status       Status         `json:"status"`
+	Scope        string         `json:"scope,omitempty"`
+	Resource     map[string]any `json:"resource,omitempty"`
+}
+
+// Event is an event recorded during a span, such as an exception.
+type Event struct {
+	Name       string         `json:"name"`
+	Time       time.Time      `json:"time"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+type Status struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+var spanKinds = []string{"SPAN_KIND_UNSPECIFIED", "SPAN_KIND_INTERNAL", "SPAN_KIND_SERVER", "SPAN_KIND_CLIENT", "SPAN_KIND_PRODUCER", "SPAN_KIND_CONSUMER"}
+
+var statusCodes = []string{"STATUS_CODE_UNSET", "STATUS_CODE_OK", "STATUS_CODE_ERROR"}
+
+func enumName(names []string, v uint64) string {
+	if v < uint64(len(names)) {
+		return names[v]
+	}
+	return fmt.Sprint(v)
+}
+
+// protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// field is a decoded protobuf field. Val holds varint and fixed values, Data holds length-delimited values.
+type field struct {
+	Num  int
+	Type int
+	Val  uint64
+	Data []byte
+}
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// parseFields calls fn for each field of a protobuf message.
+func parseFields(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errTruncated
+		}
+		b = b[n:]
+
+		f := field{Num: int(tag >> 3), Type: int(tag & 7)}
+		switch f.Type {
+		case wireVarint:
+			f.Val, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errTruncated
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errTruncated
+			}
+			f.Val = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errTruncated
+			}
+			f.Val = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return errTruncated
+			}
+			f.Data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", f.Type)
+		}
+
+		err := fn(f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeTraces decodes an OTLP ExportTraceServiceRequest protobuf message,
+// as sent by OTLP/HTTP exporters, into spans.
+//
+// See: https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+func DecodeTraces(b []byte) ([]Span, error) {
+	var spans []Span
+	err := parseFields(b, func(f field) error {
+		// ExportTraceServiceRequest.resource_spans
+		if f.Num != 1 || f.Type != wireBytes {
+			return nil
+		}
+		s, err := decodeResourceSpans(f.Data)
+		spans = append(spans, s...)
+		return err
+	})
+	return spans, err
+}
+
+func decodeResourceSpans(b []byte) ([]Span, error) {
+	var resource map[string]any
+	var scopes [][]byte
+	err := parseFields(b, func(f field) error {
+		var err error
+		switch {
+		case f.Num == 1 && f.Type == wireBytes: // resource
+			err = parseFields(f.Data, func(f field) error {
+				if f.Num == 1 && f.Type == wireBytes { // Resource.attributes
+					if resource == nil {
+						resource = map[string]any{}
+					}
+					return decodeKeyValue(f.Data, resource)
+				}
+				return nil
+			})
+		case f.Num == 2 && f.Type == wireBytes: // scope_spans
+			scopes = append(scopes, f.Data)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var spans []Span
+	for _, s := range scopes {
+		scopeSpans, err := decodeScopeSpans(s)
+		if err != nil {
+			return nil, err
+		}
+		for i := range scopeSpans {
+			scopeSpans[i].Resource = resource
+		}
+		spans = append(spans, scopeSpans...)
+	}
+	return spans, nil
+}
+
+func decodeScopeSpans(b []byte) ([]Span, error) {
+	var scope string
+	var spans []Span
+	err := parseFields(b, func(f field) error {
+		if f.Type != wireBytes {
+			return nil
+		}
+		switch f.Num {
+		case 1: // scope
+			return parseFields(f.Data, func(f field) error {
+				if f.Num == 1 && f.Type == wireBytes { // InstrumentationScope.name
+					scope = string(f.Data)
+				}
+				return nil
+			})
+		case 2: // spans
+			s, err := decodeSpan(f.Data)
+			if err != nil {
+				return err
+			}
+			spans = append(spans, s)
+		}
+		return nil
+	})
+	for i := range spans {
+		spans[i].Scope = scope
+	}
+	return spans, err
+}
+
+func decodeSpan(b []byte) (Span, error) {
+	s := Span{Kind: spanKinds[0], Status: Status{Code: statusCodes[0]}}
+	var start, end uint64
+	err := parseFields(b, func(f field) error {
+		switch f.Num {
+		case 1:
+			s.TraceID = hex.EncodeToString(f.Data)
+		case 2:
+			s.SpanID = hex.EncodeToString(f.Data)
+		case 4:
+			s.ParentSpanID = hex.EncodeToString(f.Data)
+		case 5:
+			s.Name = string(f.Data)
+		case 6:
+			s.Kind = enumName(spanKinds, f.Val)
+		case 7:
+			start = f.Val
+		case 8:
+			end = f.Val
+		case 9:
+			if s.Attributes == nil {
+				s.Attributes = map[string]any{}
+			}
+			return decodeKeyValue(f.Data, s.Attributes)
+		case 11:
+			e, err := decodeEvent(f.Data)
+			if err != nil {
+				return err
+			}
+			s.Events = append(s.Events, e)
+		case 15:
+			return parseFields(f.Data, func(f field) error {
+				switch f.Num {
+				case 2:
+					s.Status.Message = string(f.Data)
+				case 3:
+					s.Status.Code = enumName(statusCodes, f.Val)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	s.StartTime = time.Unix(0, int64(start)).UTC()
+	s.EndTime = time.Unix(0, int64(end)).UTC()
+	s.DurationMs = float64(end-start) / float64(time.Millisecond)
+	return s, err
+}
+
+func decodeEvent(b []byte) (Event, error) {
+	var e Event
+	err := parseFields(b, func(f field) error {
+		switch f.Num {
+		case 1:
+			e.Time = time.Unix(0, int64(f.Val)).UTC()
+		case 2:
+			e.Name = string(f.Data)
+		case 3:
+			if e.Attributes == nil {
+				e.Attributes = map[string]any{}
+			}
+			return decodeKeyValue(f.Data, e.Attributes)
+		}
+		return nil
+	})
+	return e, err
+}
+
+// decodeKeyValue decodes a KeyValue message into attrs.
+func decodeKeyValue(b []byte, attrs map[string]any) error {
+	var key string
+	var value any
+	err := parseFields(b, func(f field) error {
+		switch f.Num {
+		case 1:
+			key = string(f.Data)
+		case 2:
+			var err error
+			value, err = decodeAnyValue(f.Data)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	attrs[key] = value
+	return nil
+}
+
+func decodeAnyValue(b []byte) (any, error) {
+	var value any
+	err := parseFields(b, func(f field) error {
+		switch f.Num {
+		case 1:
+			value = string(f.Data)
+		case 2:
+			value = f.Val != 0
+		case 3:
+			value = int64(f.Val)
+		case 4:
+			value = math.Float64frombits(f.Val)
+		case 5: // ArrayValue
+			values := []any{}
+			err := parseFields(f.Data, func(f field) error {
+				v, err := decodeAnyValue(f.Data)
+				values = append(values, v)
+				return err
+			})
+			value = values
+			return err
+		case 6: // KeyValueList
+			kv := map[string]any{}
+			err := parseFields(f.Data, func(f field) error {
+				return decodeKeyValue(f.Data, kv)
+			})
+			value = kv
+			return err
+		case 7:
+			value = hex.EncodeToString(f.Data)
+		}
+		return nil
+	})
+	return value, err
+}
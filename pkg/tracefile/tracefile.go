@@ -0,0 +1,150 @@
+// Package tracefile receives OpenTelemetry spans from a Provider running locally
+// and writes them to a JSON lines file, so that traces can be inspected during development.
+//
+// The Provider exports spans to the Receiver using the OTLP/HTTP protobuf protocol,
+// which is the default protocol of the OpenTelemetry Python SDK.
+package tracefile
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Receiver is an OTLP/HTTP endpoint which appends the spans it receives to a file.
+type Receiver struct {
+	server   *http.Server
+	listener net.Listener
+
+	mu    sync.Mutex
+	file  *os.File
+	enc   *json.Encoder
+	count int
+}
+
+// Listen opens the trace file for appending and starts a receiver on a random local port.
+func Listen(path string) (*Receiver, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	r := &Receiver{
+		listener: l,
+		file:     f,
+		enc:      json.NewEncoder(f),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/traces", r.handleTraces)
+	r.server = &http.Server{Handler: mux}
+
+	go func() {
+		_ = r.server.Serve(l)
+	}()
+
+	return r, nil
+}
+
+// Endpoint is the URL which spans should be exported to.
+func (r *Receiver) Endpoint() string {
+	return "http://" + r.listener.Addr().String() + "/v1/traces"
+}
+
+// Env returns the environment variables which configure the OpenTelemetry SDK
+// to export spans to the receiver.
+func (r *Receiver) Env(serviceName string) []string {
+	return []string{
+		"OTEL_SERVICE_NAME=" + serviceName,
+		"OTEL_TRACES_EXPORTER=otlp",
+		"OTEL_METRICS_EXPORTER=none",
+		"OTEL_LOGS_EXPORTER=none",
+		"OTEL_EXPORTER_OTLP_TRACES_PROTOCOL=http/protobuf",
+		"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT=" + r.Endpoint(),
+	}
+}
+
+// Count returns the number of spans which have been written.
+func (r *Receiver) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.count
+}
+
+// Close stops the receiver, waiting for any exports in progress, and closes the file.
+func (r *Receiver) Close(ctx context.Context) error {
+	err := r.server.Shutdown(ctx)
+	cerr := r.file.Close()
+	if err != nil {
+		return err
+	}
+	return cerr
+}
+
+func (r *Receiver) handleTraces(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "application/x-protobuf" {
+		http.Error(w, fmt.Sprintf("unsupported content type %q: only the http/protobuf OTLP protocol is supported", ct), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var body io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	spans, err := DecodeTraces(b)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = r.write(spans)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// an empty ExportTraceServiceResponse indicates that all spans were accepted.
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *Receiver) write(spans []Span) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range spans {
+		err := r.enc.Encode(s)
+		if err != nil {
+			return err
+		}
+		r.count++
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package tracefile
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// the helpers below encode protobuf messages, so that the tests
+// don't depend on the OpenTelemetry protobuf definitions.
+
+func appendTag(b []byte, num int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(num<<3|wireType))
+}
+
+func appendBytes(b []byte, num int, data []byte) []byte {
+	b = appendTag(b, num, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func appendVarint(b []byte, num int, v uint64) []byte {
+	return binary.AppendUvarint(appendTag(b, num, wireVarint), v)
+}
+
+func appendFixed64(b []byte, num int, v uint64) []byte {
+	return binary.LittleEndian.AppendUint64(appendTag(b, num, wireFixed64), v)
+}
+
+func keyValue(key string, value []byte) []byte {
+	return appendBytes(appendBytes(nil, 1, []byte(key)), 2, value)
+}
+
+func testRequest(start time.Time) []byte {
+	var span []byte
+	span = appendBytes(span, 1, []byte{0x01, 0x02})
+	span = appendBytes(span, 2, []byte{0x03})
+	span = appendBytes(span, 4, []byte{0x04})
+	span = appendBytes(span, 5, []byte("GET /groups"))
+	span = appendVarint(span, 6, 3)
+	span = appendFixed64(span, 7, uint64(start.UnixNano()))
+	span = appendFixed64(span, 8, uint64(start.Add(1500*time.Millisecond).UnixNano()))
+	span = appendBytes(span, 9, keyValue("http.status_code", appendVarint(nil, 3, 200)))
+	span = appendBytes(span, 9, keyValue("http.url", appendBytes(nil, 1, []byte("https://example.com/groups"))))
+	span = appendBytes(span, 9, keyValue("sampled", appendVarint(nil, 2, 1)))
+	span = appendBytes(span, 9, keyValue("ratio", appendFixed64(nil, 4, math.Float64bits(0.5))))
+	span = appendBytes(span, 11, appendBytes(appendFixed64(nil, 1, uint64(start.UnixNano())), 2, []byte("exception")))
+	span = appendBytes(span, 15, appendVarint(appendBytes(nil, 2, []byte("timeout")), 3, 2))
+
+	scope := appendBytes(nil, 1, []byte("opentelemetry.instrumentation.requests"))
+	scopeSpans := appendBytes(appendBytes(nil, 1, scope), 2, span)
+
+	resource := appendBytes(nil, 1, keyValue("service.name", appendBytes(nil, 1, []byte("example"))))
+	resourceSpans := appendBytes(appendBytes(nil, 1, resource), 2, scopeSpans)
+
+	return appendBytes(nil, 1, resourceSpans)
+}
+
+func TestDecodeTraces(t *testing.T) {
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	got, err := DecodeTraces(testRequest(start))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Span{
+		{
+			TraceID:      "0102",
+			SpanID:       "03",
+			ParentSpanID: "04",
+			Name:         "GET /groups",
+			Kind:         "SPAN_KIND_CLIENT",
+			StartTime:    start,
+			EndTime:      start.Add(1500 * time.Millisecond),
+			DurationMs:   1500,
+			Attributes: map[string]any{
+				"http.status_code": int64(200),
+				"http.url":         "https://example.com/groups",
+				"sampled":          true,
+				"ratio":            0.5,
+			},
+			Events: []Event{{Name: "exception", Time: start}},
+			Status: Status{Code: "STATUS_CODE_ERROR", Message: "timeout"},
+			Scope:  "opentelemetry.instrumentation.requests",
+			Resource: map[string]any{
+				"service.name": "example",
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want\n%+v\ngot\n%+v", want, got)
+	}
+
+	_, err = DecodeTraces([]byte{0x0a, 0x05, 0x01})
+	if err != errTruncated {
+		t.Errorf("want truncated error, got %v", err)
+	}
+}
+
+func TestReceiver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	r, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ct := range []string{"application/x-protobuf", "application/json"} {
+		res, err := http.Post(r.Endpoint(), ct, bytes.NewReader(testRequest(time.Now())))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		want := http.StatusOK
+		if ct == "application/json" {
+			want = http.StatusUnsupportedMediaType
+		}
+		if res.StatusCode != want {
+			t.Errorf("%s: want status %d got %d", ct, want, res.StatusCode)
+		}
+	}
+
+	err = r.Close(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Count() != 1 {
+		t.Errorf("want 1 span got %d", r.Count())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var lines int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s Span
+		err = json.Unmarshal(scanner.Bytes(), &s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines++
+	}
+	if lines != 1 {
+		t.Errorf("want 1 line got %d", lines)
+	}
+}